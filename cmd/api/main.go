@@ -1,16 +1,30 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/azin/gdstudio-embed-service/internal/api"
 	"github.com/azin/gdstudio-embed-service/internal/api/handlers"
+	"github.com/azin/gdstudio-embed-service/internal/auth"
+	"github.com/azin/gdstudio-embed-service/internal/buildinfo"
 	"github.com/azin/gdstudio-embed-service/internal/config"
+	"github.com/azin/gdstudio-embed-service/internal/health"
 	"github.com/azin/gdstudio-embed-service/internal/repository"
+	"github.com/azin/gdstudio-embed-service/internal/service/events"
+	"github.com/azin/gdstudio-embed-service/internal/service/gdstudio"
+	"github.com/azin/gdstudio-embed-service/internal/service/maintenance"
+	"github.com/azin/gdstudio-embed-service/internal/service/navidrome"
+	"github.com/azin/gdstudio-embed-service/internal/service/queue"
+	"github.com/azin/gdstudio-embed-service/internal/service/webhook"
+	"github.com/azin/gdstudio-embed-service/internal/tracing"
 	"github.com/azin/gdstudio-embed-service/pkg/logger"
 	"github.com/hibiken/asynq"
 	"go.uber.org/zap"
@@ -39,9 +53,30 @@ func main() {
 
 	log := logger.Get()
 	log.Info("starting embed-service API",
+		zap.String("version", buildinfo.Version),
+		zap.String("commit", buildinfo.CommitSHA),
 		zap.Int("port", cfg.Server.Port),
 		zap.String("mode", cfg.Server.Mode))
 
+	// 初始化链路追踪
+	shutdownTracing, err := tracing.Init(&cfg.Tracing, "embed-service-api")
+	if err != nil {
+		log.Fatal("failed to init tracing", zap.Error(err))
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			log.Warn("failed to flush traces", zap.Error(err))
+		}
+	}()
+
+	// API Key 权限（角色、scope 配置错误时拒绝启动）
+	keys, err := auth.NewKeyring(&cfg.Security)
+	if err != nil {
+		log.Fatal("invalid api key config", zap.Error(err))
+	}
+
 	// 初始化数据库
 	db, err := initDatabase(cfg)
 	if err != nil {
@@ -55,27 +90,76 @@ func main() {
 
 	// 初始化仓库
 	jobRepo := repository.NewJobRepository(db)
+	webhookRepo := repository.NewWebhookRepository(db)
+
+	// 按 API Key 隔离之前创建的任务：补全归属与幂等键前缀（ADMIN_API_KEY 追加的管理员 Key 不参与归属）
+	adopted, prefixed, err := jobRepo.AdoptLegacyJobs(keys.NonAdminNames())
+	if err != nil {
+		log.Fatal("failed to migrate legacy jobs", zap.Error(err))
+	}
+	if adopted > 0 || prefixed > 0 {
+		log.Info("migrated legacy jobs to api keys",
+			zap.Int64("adopted", adopted),
+			zap.Int64("prefixed", prefixed))
+	}
 
 	// 初始化 asynq 客户端
-	asynqClient := asynq.NewClient(asynq.RedisClientOpt{
+	redisOpt := asynq.RedisClientOpt{
 		Addr: cfg.Redis.URL,
 		DB:   cfg.Redis.DB,
-	})
-	defer asynqClient.Close()
+	}
+	asynqClient := asynq.NewClient(redisOpt)
+	inspector := asynq.NewInspector(redisOpt)
 
-	// 初始化 Handler
-	jobHandler := handlers.NewJobHandler(cfg, jobRepo, asynqClient, log)
+	// 任务事件（Redis pub/sub）
+	eventBus := events.NewBus(&cfg.Redis, log)
+
+	// Webhook：同步 API Key 订阅，并在任务事件发生时入队投递
+	dispatcher := webhook.NewDispatcher(&cfg.Webhook, webhookRepo, jobRepo, asynqClient, log)
+	if err := dispatcher.SyncAPIKeys(cfg.Security.APIKeys); err != nil {
+		log.Fatal("failed to sync api key webhooks", zap.Error(err))
+	}
+	eventBus.AddListener(dispatcher.Handle)
+
+	// 初始化 Handler（drainer 在收到退出信号时拒绝新任务并结束长连接）
+	drainer := handlers.NewDrainer()
+	jobHandler := handlers.NewJobHandler(cfg, jobRepo, webhookRepo, asynqClient, inspector, eventBus, drainer, log)
+	eventHandler := handlers.NewEventHandler(jobRepo, eventBus, drainer, log)
+	webhookHandler := handlers.NewWebhookHandler(webhookRepo, log)
+	wsHandler := handlers.NewWSHandler(jobHandler, jobRepo, eventBus, log)
+	adminHandler := handlers.NewAdminHandler(
+		maintenance.NewPurger(&cfg.Retention, jobRepo, webhookRepo, log),
+		queue.NewInspector(inspector, jobRepo, eventBus, log),
+		log,
+	)
+
+	// 健康检查：数据库、Redis 与（按配置）上游服务
+	checker := health.NewStandardChecker(&cfg.Health, db, inspector,
+		gdstudio.NewClient(&cfg.GDStudio, log), navidrome.NewClient(&cfg.Navidrome, log))
+	// 关闭过程中就绪检查失败，负载均衡不再转发新请求
+	checker.Add("server", true, 0, func(context.Context) error {
+		if drainer.Draining() {
+			return errors.New("server is shutting down")
+		}
+		return nil
+	})
 
 	// 设置路由
-	router := api.SetupRouter(cfg, jobHandler)
+	router := api.SetupRouter(cfg, jobHandler, eventHandler, webhookHandler, wsHandler, adminHandler, checker, keys, log)
 
 	// 启动服务器
-	addr := fmt.Sprintf(":%d", cfg.Server.Port)
-	log.Info("server listening", zap.String("addr", addr))
+	srv := &http.Server{
+		Addr:              fmt.Sprintf(":%d", cfg.Server.Port),
+		Handler:           router,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+	}
+	log.Info("server listening", zap.String("addr", srv.Addr))
 
-	// 优雅关闭
 	go func() {
-		if err := router.Run(addr); err != nil {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			log.Fatal("failed to start server", zap.Error(err))
 		}
 	}()
@@ -85,7 +169,30 @@ func main() {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
-	log.Info("shutting down server...")
+	// 优雅关闭：先拒绝新任务并结束 SSE / WebSocket，再等待进行中的请求完成
+	log.Info("shutting down server...", zap.Duration("drain_timeout", cfg.Server.ShutdownTimeout))
+	drainer.Start()
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Warn("drain timeout exceeded, closing remaining connections", zap.Error(err))
+		srv.Close()
+	}
+
+	// 请求处理完毕后再释放依赖
+	eventBus.Close()
+	inspector.Close()
+	if err := asynqClient.Close(); err != nil {
+		log.Warn("failed to close asynq client", zap.Error(err))
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		if err := sqlDB.Close(); err != nil {
+			log.Warn("failed to close database", zap.Error(err))
+		}
+	}
+
+	log.Info("server stopped")
 }
 
 func initDatabase(cfg *config.Config) (*gorm.DB, error) {
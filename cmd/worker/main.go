@@ -1,17 +1,29 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
+	"github.com/azin/gdstudio-embed-service/internal/buildinfo"
 	"github.com/azin/gdstudio-embed-service/internal/config"
+	"github.com/azin/gdstudio-embed-service/internal/health"
 	"github.com/azin/gdstudio-embed-service/internal/repository"
+	"github.com/azin/gdstudio-embed-service/internal/service/events"
+	"github.com/azin/gdstudio-embed-service/internal/service/fsperm"
 	"github.com/azin/gdstudio-embed-service/internal/service/gdstudio"
+	"github.com/azin/gdstudio-embed-service/internal/service/library"
+	"github.com/azin/gdstudio-embed-service/internal/service/maintenance"
 	"github.com/azin/gdstudio-embed-service/internal/service/navidrome"
 	"github.com/azin/gdstudio-embed-service/internal/service/tagger"
+	"github.com/azin/gdstudio-embed-service/internal/service/webhook"
+	"github.com/azin/gdstudio-embed-service/internal/tasks"
+	"github.com/azin/gdstudio-embed-service/internal/tracing"
 	"github.com/azin/gdstudio-embed-service/internal/worker"
 	"github.com/azin/gdstudio-embed-service/pkg/logger"
 	"github.com/hibiken/asynq"
@@ -41,8 +53,23 @@ func main() {
 
 	log := logger.Get()
 	log.Info("starting embed-service Worker",
+		zap.String("version", buildinfo.Version),
+		zap.String("commit", buildinfo.CommitSHA),
 		zap.Int("concurrency", cfg.Worker.MaxConcurrent))
 
+	// 初始化链路追踪
+	shutdownTracing, err := tracing.Init(&cfg.Tracing, "embed-service-worker")
+	if err != nil {
+		log.Fatal("failed to init tracing", zap.Error(err))
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			log.Warn("failed to flush traces", zap.Error(err))
+		}
+	}()
+
 	// 初始化数据库
 	db, err := initDatabase(cfg)
 	if err != nil {
@@ -51,14 +78,25 @@ func main() {
 
 	// 初始化仓库
 	jobRepo := repository.NewJobRepository(db)
+	libraryRepo := repository.NewLibraryRepository(db)
+	webhookRepo := repository.NewWebhookRepository(db)
 
 	// 初始化服务客户端
 	gdClient := gdstudio.NewClient(&cfg.GDStudio, log)
 	naviClient := navidrome.NewClient(&cfg.Navidrome, log)
-	taggerService := tagger.NewTagger(log)
+	// 曲库文件权限与属主，启动时确认可以 chown，避免任务跑完才发现权限不足
+	perms, err := fsperm.NewPolicy(&cfg.Storage)
+	if err != nil {
+		log.Fatal("invalid storage permission config", zap.Error(err))
+	}
+	if err := perms.Check(cfg.Storage.MusicDir); err != nil {
+		log.Fatal("storage ownership check failed", zap.Error(err))
+	}
+
+	taggerService := tagger.NewTagger(&cfg.Tagger, perms, log)
 
 	// 测试 Navidrome 连接
-	if err := naviClient.Ping(); err != nil {
+	if err := naviClient.Ping(context.Background()); err != nil {
 		log.Warn("navidrome ping failed", zap.Error(err))
 	} else {
 		log.Info("navidrome connection successful")
@@ -69,6 +107,29 @@ func main() {
 		log.Fatal("failed to create work dir", zap.Error(err))
 	}
 
+	// 曲库索引（后台周期扫描，用于下载前查重）。索引存在数据库中，多副本时只需一个副本扫描
+	libraryIndex := library.NewIndex(&cfg.Library, cfg.Storage.MusicDir, libraryRepo, log)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if cfg.Worker.PeriodicTasks {
+		go libraryIndex.Run(ctx)
+	}
+
+	redisOpt := asynq.RedisClientOpt{
+		Addr: cfg.Redis.URL,
+		DB:   cfg.Redis.DB,
+	}
+	inspector := asynq.NewInspector(redisOpt)
+	defer inspector.Close()
+
+	// 工作目录清理（失败任务按保留期清理，孤儿目录直接删除）
+	janitor := worker.NewJanitor(cfg, jobRepo, inspector, log)
+	go janitor.Run(ctx)
+
+	// 任务事件（Redis pub/sub，供 API 推送 SSE）
+	eventBus := events.NewBus(&cfg.Redis, log)
+	defer eventBus.Close()
+
 	// 初始化任务处理器
 	downloadTask := worker.NewDownloadTask(
 		cfg,
@@ -76,27 +137,87 @@ func main() {
 		gdClient,
 		naviClient,
 		taggerService,
+		libraryIndex,
+		perms,
+		eventBus,
 		log,
 	)
 
+	// 音质升级任务需要向队列投递子任务
+	client := asynq.NewClient(redisOpt)
+	defer client.Close()
+	upgradeTask := worker.NewUpgradeTaskHandler(downloadTask, client, inspector)
+	maintenanceTask := worker.NewMaintenanceTask(maintenance.NewPurger(&cfg.Retention, jobRepo, webhookRepo, log))
+
+	// Webhook：任务事件发生时入队投递，投递任务由本 worker 执行
+	eventBus.AddListener(webhook.NewDispatcher(&cfg.Webhook, webhookRepo, jobRepo, client, log).Handle)
+	webhookTask := worker.NewWebhookTask(webhook.NewDeliverer(&cfg.Webhook, webhookRepo, jobRepo, log))
+
 	// 初始化 asynq 服务器
 	srv := asynq.NewServer(
-		asynq.RedisClientOpt{
-			Addr: cfg.Redis.URL,
-			DB:   cfg.Redis.DB,
-		},
+		redisOpt,
 		asynq.Config{
 			Concurrency: cfg.Worker.MaxConcurrent,
+			// webhook 投递使用独立队列，订阅方不可达时下载任务仍优先获得并发槽位
 			Queues: map[string]int{
-				"default": 10,
+				"default":     10,
+				webhook.Queue: 3,
 			},
-			Logger: &asynqLogger{log},
+			Logger:         &asynqLogger{log},
+			RetryDelayFunc: webhook.RetryDelayFunc(&cfg.Webhook),
 		},
 	)
 
 	// 注册任务处理器
 	mux := asynq.NewServeMux()
-	mux.HandleFunc(worker.TypeDownload, downloadTask.ProcessTask)
+	mux.HandleFunc(tasks.TypeDownload, downloadTask.ProcessTask)
+	mux.HandleFunc(tasks.TypeUpgrade, upgradeTask.ProcessTask)
+	mux.HandleFunc(tasks.TypeUpgradeSweep, upgradeTask.ProcessSweep)
+	mux.HandleFunc(tasks.TypePurge, maintenanceTask.ProcessPurge)
+	mux.HandleFunc(webhook.TypeDelivery, webhookTask.ProcessDelivery)
+
+	// 定时任务：音质升级扫描、任务记录清理。每个调度器都会入队，多副本时只在开启 periodic_tasks 的副本运行；
+	// Unique 避免上一次尚未执行完时重复排队
+	var scheduler *asynq.Scheduler
+	if cfg.Worker.PeriodicTasks && (cfg.Upgrade.Enabled || cfg.Retention.Enabled) {
+		scheduler = asynq.NewScheduler(redisOpt, &asynq.SchedulerOpts{Logger: &asynqLogger{log}})
+		if cfg.Upgrade.Enabled {
+			spec := "@every " + cfg.Upgrade.SweepInterval.String()
+			if _, err := scheduler.Register(spec, asynq.NewTask(tasks.TypeUpgradeSweep, nil), asynq.Unique(cfg.Upgrade.SweepInterval)); err != nil {
+				log.Fatal("failed to register upgrade sweep", zap.Error(err))
+			}
+			log.Info("upgrade sweep scheduled", zap.Duration("interval", cfg.Upgrade.SweepInterval))
+		}
+		if cfg.Retention.Enabled {
+			spec := "@every " + cfg.Retention.Interval.String()
+			if _, err := scheduler.Register(spec, asynq.NewTask(tasks.TypePurge, nil), asynq.Unique(cfg.Retention.Interval)); err != nil {
+				log.Fatal("failed to register retention purge", zap.Error(err))
+			}
+			log.Info("retention purge scheduled",
+				zap.Duration("interval", cfg.Retention.Interval),
+				zap.Duration("job_retention", cfg.Retention.JobRetention))
+		}
+		if err := scheduler.Start(); err != nil {
+			log.Fatal("failed to start scheduler", zap.Error(err))
+		}
+	}
+
+	// 健康检查端口（worker 没有 API 服务，单独监听）
+	var healthServer *http.Server
+	if cfg.Health.WorkerPort > 0 {
+		checker := health.NewStandardChecker(&cfg.Health, db, inspector, gdClient, naviClient)
+		healthServer = &http.Server{
+			Addr:              fmt.Sprintf(":%d", cfg.Health.WorkerPort),
+			Handler:           checker.Mux(),
+			ReadHeaderTimeout: 5 * time.Second,
+		}
+		go func() {
+			if err := healthServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Error("health server failed", zap.Error(err))
+			}
+		}()
+		log.Info("health server listening", zap.String("addr", healthServer.Addr))
+	}
 
 	log.Info("worker started", zap.Int("concurrency", cfg.Worker.MaxConcurrent))
 
@@ -113,7 +234,16 @@ func main() {
 	<-quit
 
 	log.Info("shutting down worker...")
+	cancel()
+	if scheduler != nil {
+		scheduler.Shutdown()
+	}
 	srv.Shutdown()
+	if healthServer != nil {
+		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer shutdownCancel()
+		healthServer.Shutdown(shutdownCtx)
+	}
 }
 
 func initDatabase(cfg *config.Config) (*gorm.DB, error) {
@@ -0,0 +1,68 @@
+// Package apierror 将 errcode 错误写为统一的 HTTP 错误响应。
+package apierror
+
+import (
+	"net/http"
+
+	"github.com/azin/gdstudio-embed-service/internal/errcode"
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDKey gin.Context 中保存请求 ID 的键
+const RequestIDKey = "request_id"
+
+// Response 统一错误响应
+type Response struct {
+	Code      errcode.Code `json:"code"`
+	Message   string       `json:"message"`
+	Details   interface{}  `json:"details,omitempty"`
+	RequestID string       `json:"request_id,omitempty"`
+}
+
+// statuses 错误码对应的 HTTP 状态码，未列出的按 500 处理
+var statuses = map[errcode.Code]int{
+	errcode.InvalidRequest:    http.StatusBadRequest,
+	errcode.ValidationFailed:  http.StatusBadRequest,
+	errcode.Unauthorized:      http.StatusUnauthorized,
+	errcode.Forbidden:         http.StatusForbidden,
+	errcode.NotFound:          http.StatusNotFound,
+	errcode.MethodNotAllowed:  http.StatusMethodNotAllowed,
+	errcode.JobNotFound:       http.StatusNotFound,
+	errcode.WebhookNotFound:   http.StatusNotFound,
+	errcode.QueueNotFound:     http.StatusNotFound,
+	errcode.TaskNotFound:      http.StatusNotFound,
+	errcode.InvalidState:      http.StatusConflict,
+	errcode.AlreadyQueued:     http.StatusConflict,
+	errcode.QueueUnavailable:  http.StatusServiceUnavailable,
+	errcode.StreamUnavailable: http.StatusServiceUnavailable,
+	errcode.ShuttingDown:      http.StatusServiceUnavailable,
+}
+
+// Status 返回错误码对应的 HTTP 状态码
+func Status(code errcode.Code) int {
+	if status, ok := statuses[code]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// From 将任意错误转换为错误响应；不带错误码的错误视为内部错误，不暴露原因
+func From(err error) Response {
+	if e, ok := errcode.As(err); ok {
+		return Response{Code: e.Code, Message: e.Message, Details: e.Details}
+	}
+	return Response{Code: errcode.Internal, Message: "internal error"}
+}
+
+// Write 写入错误响应
+func Write(c *gin.Context, err error) {
+	resp := From(err)
+	resp.RequestID = c.GetString(RequestIDKey)
+	c.JSON(Status(resp.Code), resp)
+}
+
+// Abort 写入错误响应并终止后续处理
+func Abort(c *gin.Context, err error) {
+	Write(c, err)
+	c.Abort()
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/azin/gdstudio-embed-service/internal/auth"
+	"github.com/azin/gdstudio-embed-service/internal/repository"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// principal 返回 Auth 中间件保存的 API Key 权限；不存在时返回没有任何 scope 的权限
+func principal(c *gin.Context) *auth.Principal {
+	if value, ok := c.Get(auth.ContextKey); ok {
+		if p, ok := value.(*auth.Principal); ok {
+			return p
+		}
+	}
+	return &auth.Principal{}
+}
+
+// jobAccessCacheSize 缓存的任务数上限，超过后清空，避免长时间订阅全部任务时无限增长
+const jobAccessCacheSize = 10000
+
+// jobAccess 判断事件流中的任务是否属于当前 Key，并缓存任务的创建者，避免每个事件都查库（非并发安全）
+type jobAccess struct {
+	repo     *repository.JobRepository
+	p        *auth.Principal
+	creators map[string]*string // job_id -> 创建任务的 API Key；nil 表示任务不存在
+}
+
+func newJobAccess(repo *repository.JobRepository, p *auth.Principal) *jobAccess {
+	return &jobAccess{repo: repo, p: p, creators: make(map[string]*string)}
+}
+
+// allowed 当前 Key 是否可以查看该任务（管理员可查看全部）；任务不存在或查询失败时视为不可访问
+func (a *jobAccess) allowed(jobID string) bool {
+	if a.p.IsAdmin() {
+		return true
+	}
+	return a.createdByKey(jobID)
+}
+
+// createdByKey 任务是否由当前 Key 创建（不考虑管理员权限）
+func (a *jobAccess) createdByKey(jobID string) bool {
+	if a.p.Name == "" {
+		return false
+	}
+	creator, ok := a.creators[jobID]
+	if !ok {
+		job, err := a.repo.FindByID(jobID)
+		switch {
+		case err == nil:
+			creator = &job.APIKeyName
+		case !errors.Is(err, gorm.ErrRecordNotFound):
+			// 数据库暂时不可用时不缓存，下一个事件再查
+			return false
+		}
+		if len(a.creators) >= jobAccessCacheSize {
+			a.creators = make(map[string]*string)
+		}
+		a.creators[jobID] = creator
+	}
+	return creator != nil && *creator == a.p.Name
+}
@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/azin/gdstudio-embed-service/internal/api/apierror"
+	"github.com/azin/gdstudio-embed-service/internal/errcode"
+	"github.com/azin/gdstudio-embed-service/internal/service/maintenance"
+	"github.com/azin/gdstudio-embed-service/internal/service/queue"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// AdminHandler 管理接口处理器（仅管理员 API Key 可访问）
+type AdminHandler struct {
+	purger *maintenance.Purger
+	queues *queue.Inspector
+	logger *zap.Logger
+}
+
+// NewAdminHandler 创建管理接口处理器
+func NewAdminHandler(purger *maintenance.Purger, queues *queue.Inspector, logger *zap.Logger) *AdminHandler {
+	return &AdminHandler{
+		purger: purger,
+		queues: queues,
+		logger: logger,
+	}
+}
+
+// QueuesResponse 队列列表响应
+type QueuesResponse struct {
+	Queues []*queue.QueueInfo `json:"queues"`
+}
+
+// QueueTasksResponse 队列任务列表响应
+type QueueTasksResponse struct {
+	Queue    string            `json:"queue"`
+	State    string            `json:"state"`
+	Page     int               `json:"page"`
+	PageSize int               `json:"page_size"`
+	Tasks    []*queue.TaskInfo `json:"tasks"`
+}
+
+// Purge 手动执行任务记录清理，?dry_run=true 时只返回将被删除的数量
+func (h *AdminHandler) Purge(c *gin.Context) {
+	dryRun := false
+	if value := c.Query("dry_run"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			apierror.Write(c, invalidParam("dry_run", "must be a boolean"))
+			return
+		}
+		dryRun = parsed
+	}
+
+	result, err := h.purger.Purge(dryRun)
+	if err != nil {
+		h.logger.Error("purge failed", zap.Error(err))
+		apierror.Write(c, errcode.Wrap(errcode.Internal, "purge failed", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// Queues 列出全部队列及各状态任务数
+func (h *AdminHandler) Queues(c *gin.Context) {
+	queues, err := h.queues.Queues()
+	if err != nil {
+		apierror.Write(c, h.queueError(err))
+		return
+	}
+	c.JSON(http.StatusOK, QueuesResponse{Queues: queues})
+}
+
+// QueueTasks 按状态分页列出队列中的任务：?state=（默认 pending）&page=&page_size=
+func (h *AdminHandler) QueueTasks(c *gin.Context) {
+	state := c.DefaultQuery("state", queue.StatePending)
+	page, ok := positiveIntQuery(c, "page", 1)
+	if !ok {
+		return
+	}
+	pageSize, ok := positiveIntQuery(c, "page_size", defaultListLimit)
+	if !ok {
+		return
+	}
+	pageSize = min(pageSize, maxListLimit)
+
+	tasks, err := h.queues.Tasks(c.Param("queue"), state, page, pageSize)
+	if err != nil {
+		apierror.Write(c, h.queueError(err))
+		return
+	}
+	c.JSON(http.StatusOK, QueueTasksResponse{
+		Queue:    c.Param("queue"),
+		State:    state,
+		Page:     page,
+		PageSize: pageSize,
+		Tasks:    tasks,
+	})
+}
+
+// PauseQueue 暂停队列，worker 不再从该队列取任务（已在执行的任务不受影响）
+func (h *AdminHandler) PauseQueue(c *gin.Context) {
+	info, err := h.queues.Pause(c.Param("queue"))
+	if err != nil {
+		apierror.Write(c, h.queueError(err))
+		return
+	}
+	c.JSON(http.StatusOK, info)
+}
+
+// ResumeQueue 恢复队列
+func (h *AdminHandler) ResumeQueue(c *gin.Context) {
+	info, err := h.queues.Resume(c.Param("queue"))
+	if err != nil {
+		apierror.Write(c, h.queueError(err))
+		return
+	}
+	c.JSON(http.StatusOK, info)
+}
+
+// DeleteTask 删除未在执行的任务
+func (h *AdminHandler) DeleteTask(c *gin.Context) {
+	if err := h.queues.DeleteTask(c.Param("queue"), c.Param("task_id")); err != nil {
+		apierror.Write(c, h.queueError(err))
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// RunTask 立即执行 scheduled / retry / archived 任务
+func (h *AdminHandler) RunTask(c *gin.Context) {
+	task, err := h.queues.RunTask(c.Param("queue"), c.Param("task_id"))
+	if err != nil {
+		apierror.Write(c, h.queueError(err))
+		return
+	}
+	c.JSON(http.StatusOK, task)
+}
+
+// RunArchived 重新执行队列中全部 archived 任务
+func (h *AdminHandler) RunArchived(c *gin.Context) {
+	result, err := h.queues.RunAllArchived(c.Param("queue"))
+	if err != nil {
+		apierror.Write(c, h.queueError(err))
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// DeleteArchived 删除队列中全部 archived 任务
+func (h *AdminHandler) DeleteArchived(c *gin.Context) {
+	result, err := h.queues.DeleteAllArchived(c.Param("queue"))
+	if err != nil {
+		apierror.Write(c, h.queueError(err))
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// Reconcile 将数据库任务状态与队列对账：?dry_run=true 只返回将要执行的修改，
+// ?stale_after= 只处理超过该时长未更新的任务（默认 10m）
+func (h *AdminHandler) Reconcile(c *gin.Context) {
+	dryRun := false
+	if value := c.Query("dry_run"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			apierror.Write(c, invalidParam("dry_run", "must be a boolean"))
+			return
+		}
+		dryRun = parsed
+	}
+
+	staleAfter := queue.DefaultStaleAfter
+	if value := c.Query("stale_after"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed < 0 {
+			apierror.Write(c, invalidParam("stale_after", "must be a non-negative duration such as 10m"))
+			return
+		}
+		staleAfter = parsed
+	}
+
+	result, err := h.queues.Reconcile(staleAfter, dryRun)
+	if err != nil {
+		apierror.Write(c, h.queueError(err))
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// queueError 保留带错误码的错误（队列或任务不存在、状态不允许），其余视为队列不可用
+func (h *AdminHandler) queueError(err error) error {
+	if _, ok := errcode.As(err); ok {
+		return err
+	}
+	h.logger.Error("queue operation failed", zap.Error(err))
+	return errcode.Wrap(errcode.QueueUnavailable, "queue operation failed", err)
+}
+
+// positiveIntQuery 读取正整数查询参数，非法时写入错误响应并返回 false
+func positiveIntQuery(c *gin.Context, name string, def int) (int, bool) {
+	value := c.Query(name)
+	if value == "" {
+		return def, true
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed <= 0 {
+		apierror.Write(c, invalidParam(name, "must be a positive integer"))
+		return 0, false
+	}
+	return parsed, true
+}
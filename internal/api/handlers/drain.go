@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/azin/gdstudio-embed-service/internal/errcode"
+)
+
+// Drainer 标记 API 进入关闭流程：拒绝提交新任务，并通知 SSE / WebSocket 长连接结束，
+// 让 http.Server.Shutdown 只需等待普通请求完成
+type Drainer struct {
+	draining atomic.Bool
+	done     chan struct{}
+	once     sync.Once
+}
+
+// NewDrainer 创建 Drainer
+func NewDrainer() *Drainer {
+	return &Drainer{done: make(chan struct{})}
+}
+
+// Start 进入关闭流程，可重复调用
+func (d *Drainer) Start() {
+	d.once.Do(func() {
+		d.draining.Store(true)
+		close(d.done)
+	})
+}
+
+// Draining 是否正在关闭
+func (d *Drainer) Draining() bool {
+	return d.draining.Load()
+}
+
+// Done 进入关闭流程时关闭的 channel
+func (d *Drainer) Done() <-chan struct{} {
+	return d.done
+}
+
+// accepting 关闭过程中返回 SHUTTING_DOWN，客户端应重试到其他实例
+func (d *Drainer) accepting() error {
+	if d.Draining() {
+		return errcode.New(errcode.ShuttingDown, "server is shutting down")
+	}
+	return nil
+}
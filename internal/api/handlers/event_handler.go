@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/azin/gdstudio-embed-service/internal/api/apierror"
+	"github.com/azin/gdstudio-embed-service/internal/errcode"
+	"github.com/azin/gdstudio-embed-service/internal/repository"
+	"github.com/azin/gdstudio-embed-service/internal/service/events"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// sseHeartbeatInterval SSE 心跳间隔，防止代理断开空闲连接
+const sseHeartbeatInterval = 15 * time.Second
+
+// maxStreamJobs 多路订阅一次最多订阅的任务数
+const maxStreamJobs = 100
+
+// EventHandler 任务事件流（SSE）处理器
+type EventHandler struct {
+	repo   *repository.JobRepository
+	bus    *events.Bus
+	drain  *Drainer
+	logger *zap.Logger
+}
+
+// NewEventHandler 创建事件流处理器
+func NewEventHandler(repo *repository.JobRepository, bus *events.Bus, drain *Drainer, logger *zap.Logger) *EventHandler {
+	return &EventHandler{
+		repo:   repo,
+		bus:    bus,
+		drain:  drain,
+		logger: logger,
+	}
+}
+
+// JobEvents 推送单个任务的状态与进度，任务结束后关闭流
+func (h *EventHandler) JobEvents(c *gin.Context) {
+	jobID := c.Param("id")
+	if _, err := findJob(h.repo, h.logger, principal(c), jobID); err != nil {
+		apierror.Write(c, err)
+		return
+	}
+
+	h.stream(c, []string{jobID}, true)
+}
+
+// Events 多路推送任务事件：?job_ids=a,b,c；不指定时推送全部任务。
+// 非管理员只会收到自己创建的任务的事件
+func (h *EventHandler) Events(c *gin.Context) {
+	var jobIDs []string
+	for _, value := range c.QueryArray("job_ids") {
+		for _, id := range strings.Split(value, ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				jobIDs = append(jobIDs, id)
+			}
+		}
+	}
+	if len(jobIDs) > maxStreamJobs {
+		apierror.Write(c, invalidParam("job_ids", fmt.Sprintf("must contain at most %d items", maxStreamJobs)))
+		return
+	}
+
+	h.stream(c, jobIDs, false)
+}
+
+// stream 先订阅再推送快照，保证不遗漏订阅期间的事件。
+// closeOnTerminal 为 true 时所有任务结束后关闭连接。
+func (h *EventHandler) stream(c *gin.Context, jobIDs []string, closeOnTerminal bool) {
+	ctx := c.Request.Context()
+	sub, err := h.bus.Subscribe(ctx, jobIDs)
+	if err != nil {
+		h.logger.Error("failed to subscribe job events", zap.Error(err))
+		apierror.Write(c, errcode.Wrap(errcode.StreamUnavailable, "event stream unavailable", err))
+		return
+	}
+	defer sub.Close()
+	access := newJobAccess(h.repo, principal(c))
+
+	// 长连接不受 server.write_timeout 限制
+	http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	pending := make(map[string]bool, len(jobIDs))
+	for _, id := range jobIDs {
+		job, err := h.repo.FindByID(id)
+		if err != nil || !access.p.Owns(job) {
+			continue
+		}
+		snapshot := events.SnapshotEvent(job)
+		writeSSE(c, snapshot)
+		if !snapshot.Terminal() {
+			pending[id] = true
+		}
+	}
+	c.Writer.Flush()
+	if closeOnTerminal && len(pending) == 0 {
+		return
+	}
+
+	heartbeat := time.NewTicker(sseHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-h.drain.Done():
+			// 服务关闭：结束流，EventSource 会自动重连到其他实例
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(c.Writer, ": ping\n\n")
+			c.Writer.Flush()
+		case event, ok := <-sub.Events():
+			if !ok {
+				return
+			}
+			if !access.allowed(event.JobID) {
+				continue
+			}
+			writeSSE(c, event)
+			c.Writer.Flush()
+			if event.Terminal() {
+				delete(pending, event.JobID)
+				if closeOnTerminal && len(pending) == 0 {
+					return
+				}
+			}
+		}
+	}
+}
+
+// writeSSE 写入一条 SSE 消息（event 为事件类型，id 为任务 ID）
+func writeSSE(c *gin.Context, event events.Event) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(c.Writer, "event: %s\nid: %s\ndata: %s\n\n", event.Type, event.JobID, data)
+}
@@ -1,41 +1,73 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/azin/gdstudio-embed-service/internal/api/apierror"
+	"github.com/azin/gdstudio-embed-service/internal/api/openapi"
+	"github.com/azin/gdstudio-embed-service/internal/auth"
 	"github.com/azin/gdstudio-embed-service/internal/config"
+	"github.com/azin/gdstudio-embed-service/internal/errcode"
 	"github.com/azin/gdstudio-embed-service/internal/model"
 	"github.com/azin/gdstudio-embed-service/internal/repository"
-	"github.com/azin/gdstudio-embed-service/internal/worker"
+	"github.com/azin/gdstudio-embed-service/internal/service/events"
+	"github.com/azin/gdstudio-embed-service/internal/service/webhook"
+	"github.com/azin/gdstudio-embed-service/internal/tasks"
+	"github.com/azin/gdstudio-embed-service/internal/tracing"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/hibiken/asynq"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// 任务列表分页大小
+const (
+	defaultListLimit = 50
+	maxListLimit     = 200
 )
 
 // JobHandler 任务处理器
 type JobHandler struct {
-	cfg    *config.Config
-	repo   *repository.JobRepository
-	client *asynq.Client
-	logger *zap.Logger
+	cfg       *config.Config
+	repo      *repository.JobRepository
+	webhooks  *repository.WebhookRepository
+	client    *asynq.Client
+	inspector *asynq.Inspector // 重新发起音质升级时清理已归档的上一次升级
+	events    *events.Bus
+	drain     *Drainer
+	logger    *zap.Logger
 }
 
 // NewJobHandler 创建处理器
 func NewJobHandler(
 	cfg *config.Config,
 	repo *repository.JobRepository,
+	webhooks *repository.WebhookRepository,
 	client *asynq.Client,
+	inspector *asynq.Inspector,
+	eventBus *events.Bus,
+	drain *Drainer,
 	logger *zap.Logger,
 ) *JobHandler {
 	return &JobHandler{
-		cfg:    cfg,
-		repo:   repo,
-		client: client,
-		logger: logger,
+		cfg:       cfg,
+		repo:      repo,
+		webhooks:  webhooks,
+		client:    client,
+		inspector: inspector,
+		events:    eventBus,
+		drain:     drain,
+		logger:    logger,
 	}
 }
 
@@ -50,82 +82,161 @@ type CreateJobRequest struct {
 	IdempotencyKey string                 `json:"idempotency_key"`
 	PathPolicy     map[string]interface{} `json:"path_policy"`
 
+	// 曲库中已有同一首歌时的处理策略：skip / replace_if_better / keep_both，为空时使用服务端配置
+	DuplicatePolicy string `json:"duplicate_policy" binding:"omitempty,oneof=skip replace_if_better keep_both"`
+
+	// 目标文件名已存在时的处理策略：skip / overwrite / suffix / keep_better，为空时使用服务端配置
+	CollisionPolicy string `json:"collision_policy" binding:"omitempty,oneof=skip overwrite suffix keep_better"`
+
+	// 任务生命周期事件的回调地址，使用 webhook.secret 签名
+	CallbackURL string `json:"callback_url" binding:"omitempty,url"`
+
 	// 可选的元数据（如果客户端已知）
 	Title       string `json:"title"`
 	Artist      string `json:"artist"`
 	Album       string `json:"album"`
 	TrackNumber int    `json:"track_number"`
 	Year        int    `json:"year"`
+	ISRC        string `json:"isrc"`
 }
 
 // CreateJobResponse 创建任务响应
 type CreateJobResponse struct {
+	JobID     string `json:"job_id"`
+	Status    string `json:"status"`
+	Message   string `json:"message,omitempty"`
+	WebhookID string `json:"webhook_id,omitempty"` // callback_url 对应的订阅，可用于查询投递记录
+}
+
+// ListJobsResponse 任务列表响应
+type ListJobsResponse struct {
+	Jobs       []*model.Job `json:"jobs"`
+	Count      int          `json:"count"`
+	Total      int64        `json:"total"`
+	NextCursor string       `json:"next_cursor"` // 为空表示没有下一页
+}
+
+// JobActionResponse 重试、取消、升级等任务操作的响应
+type JobActionResponse struct {
 	JobID   string `json:"job_id"`
-	Status  string `json:"status"`
-	Message string `json:"message,omitempty"`
+	Status  string `json:"status,omitempty"`
+	Message string `json:"message"`
 }
 
 // Create 创建任务
 func (h *JobHandler) Create(c *gin.Context) {
 	var req CreateJobRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Write(c, bindingError(&req, err))
+		return
+	}
+
+	resp, err := h.submitJob(c.Request.Context(), &req, principal(c), c.GetString(apierror.RequestIDKey))
+	if err != nil {
+		apierror.Write(c, err)
 		return
 	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// submitJob 创建并入队任务（REST 与 WebSocket 共用），requestID 随任务载荷传给 worker
+func (h *JobHandler) submitJob(ctx context.Context, req *CreateJobRequest, p *auth.Principal, requestID string) (*CreateJobResponse, error) {
+	if err := h.drain.accepting(); err != nil {
+		return nil, err
+	}
+	if !p.AllowsLibrary(req.LibraryID) {
+		return nil, errcode.New(errcode.Forbidden, "api key is not allowed to use this library")
+	}
+	if !p.AllowsSource(req.Source) {
+		return nil, errcode.New(errcode.Forbidden, "api key is not allowed to use this source")
+	}
+
+	// callback_url 由调用方指定、worker 发起请求，需限制在 webhook.allowed_hosts 内
+	if req.CallbackURL != "" {
+		if err := webhook.ValidateCallbackURL(&h.cfg.Webhook, req.CallbackURL); err != nil {
+			return nil, errcode.New(errcode.ValidationFailed, "validation failed").WithDetails([]openapi.FieldError{{
+				Field:   "callback_url",
+				In:      "body",
+				Message: err.Error(),
+			}})
+		}
+	}
 
 	// 默认值
 	if req.Quality == "" {
 		req.Quality = "best"
 	}
 
-	// 生成幂等键
+	// 生成幂等键，按 API Key 隔离，避免返回其他 Key 创建的任务
 	idempotencyKey := req.IdempotencyKey
 	if idempotencyKey == "" {
 		idempotencyKey = fmt.Sprintf("%s:%s:%s", req.Source, req.TrackID, req.LibraryID)
 	}
+	if p.Name != "" {
+		idempotencyKey = p.Name + "/" + idempotencyKey
+	}
 
 	// 检查是否已存在
 	existing, err := h.repo.FindByIdempotencyKey(idempotencyKey)
 	if err != nil {
 		h.logger.Error("failed to check idempotency", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
-		return
+		return nil, errcode.Wrap(errcode.Internal, "failed to check idempotency", err)
 	}
 
 	if existing != nil {
 		h.logger.Info("job already exists", zap.String("job_id", existing.ID))
-		c.JSON(http.StatusOK, CreateJobResponse{
+		return &CreateJobResponse{
 			JobID:   existing.ID,
 			Status:  existing.Status,
 			Message: "job already exists",
-		})
-		return
+		}, nil
 	}
 
 	// 创建新任务
 	job := &model.Job{
-		ID:             uuid.New().String(),
-		IdempotencyKey: idempotencyKey,
-		Source:         req.Source,
-		TrackID:        req.TrackID,
-		PicID:          req.PicID,
-		LyricID:        req.LyricID,
-		LibraryID:      req.LibraryID,
-		Quality:        req.Quality,
-		Title:          req.Title,
-		Artist:         req.Artist,
-		Album:          req.Album,
-		TrackNumber:    req.TrackNumber,
-		Year:           req.Year,
-		Status:         model.JobStatusQueued,
-		CreatedAt:      time.Now(),
-		UpdatedAt:      time.Now(),
+		ID:              uuid.New().String(),
+		IdempotencyKey:  idempotencyKey,
+		Source:          req.Source,
+		TrackID:         req.TrackID,
+		PicID:           req.PicID,
+		LyricID:         req.LyricID,
+		LibraryID:       req.LibraryID,
+		Quality:         req.Quality,
+		ISRC:            req.ISRC,
+		APIKeyName:      p.Name,
+		RequestID:       requestID,
+		Title:           req.Title,
+		Artist:          req.Artist,
+		Album:           req.Album,
+		TrackNumber:     req.TrackNumber,
+		Year:            req.Year,
+		Status:          model.JobStatusQueued,
+		DuplicatePolicy: req.DuplicatePolicy,
+		CollisionPolicy: req.CollisionPolicy,
+		CreatedAt:       time.Now(),
+		UpdatedAt:       time.Now(),
 	}
 
 	if err := h.repo.Create(job); err != nil {
 		h.logger.Error("failed to create job", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create job"})
-		return
+		return nil, errcode.Wrap(errcode.Internal, "failed to create job", err)
+	}
+
+	// 任务级回调，需在入队前创建以免错过首个事件
+	webhookID := ""
+	if req.CallbackURL != "" {
+		hook := &model.Webhook{
+			ID:         uuid.New().String(),
+			URL:        req.CallbackURL,
+			Secret:     h.cfg.Webhook.Secret,
+			APIKeyName: job.APIKeyName,
+			JobID:      job.ID,
+		}
+		if err := h.webhooks.Create(hook); err != nil {
+			h.logger.Error("failed to create webhook", zap.Error(err))
+			return nil, errcode.Wrap(errcode.Internal, "failed to create webhook", err)
+		}
+		webhookID = hook.ID
 	}
 
 	// 创建任务载荷
@@ -138,7 +249,7 @@ func (h *JobHandler) Create(c *gin.Context) {
 		lyricID = req.TrackID
 	}
 
-	payload := worker.DownloadPayload{
+	payload := tasks.DownloadPayload{
 		JobID:     job.ID,
 		Source:    req.Source,
 		TrackID:   req.TrackID,
@@ -146,94 +257,190 @@ func (h *JobHandler) Create(c *gin.Context) {
 		LyricID:   lyricID,
 		LibraryID: req.LibraryID,
 		Quality:   req.Quality,
+		RequestID: requestID,
 	}
 
-	payloadBytes, _ := json.Marshal(payload)
-
 	// 入队
-	task := asynq.NewTask(worker.TypeDownload, payloadBytes)
-	info, err := h.client.Enqueue(task)
+	info, err := h.enqueueDownload(ctx, payload)
 	if err != nil {
 		h.logger.Error("failed to enqueue task", zap.Error(err))
-		h.repo.MarkFailed(job.ID, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to enqueue task"})
-		return
+		queueErr := errcode.Wrap(errcode.QueueUnavailable, "failed to enqueue task", err)
+		h.repo.MarkFailed(job.ID, queueErr)
+		return nil, queueErr
 	}
 
 	h.logger.Info("job created and enqueued",
 		zap.String("job_id", job.ID),
+		zap.String("request_id", requestID),
 		zap.String("task_id", info.ID))
+	h.events.PublishStatus(job.ID, model.JobStatusQueued, "")
 
-	c.JSON(http.StatusOK, CreateJobResponse{
-		JobID:   job.ID,
-		Status:  model.JobStatusQueued,
-		Message: "job created successfully",
-	})
+	return &CreateJobResponse{
+		JobID:     job.ID,
+		Status:    model.JobStatusQueued,
+		Message:   "job created successfully",
+		WebhookID: webhookID,
+	}, nil
+}
+
+// enqueueDownload 入队下载任务，并把当前 trace 上下文写入载荷，worker 端的 span 挂在入队 span 之下
+func (h *JobHandler) enqueueDownload(ctx context.Context, payload tasks.DownloadPayload) (*asynq.TaskInfo, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "asynq.enqueue "+tasks.TypeDownload,
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(attribute.String("job.id", payload.JobID)))
+	payload.TraceContext = tracing.Inject(ctx)
+
+	payloadBytes, _ := json.Marshal(payload)
+	info, err := h.client.EnqueueContext(ctx, asynq.NewTask(tasks.TypeDownload, payloadBytes))
+	tracing.End(span, err)
+	return info, err
 }
 
 // Get 查询任务
 func (h *JobHandler) Get(c *gin.Context) {
-	jobID := c.Param("id")
-
-	job, err := h.repo.FindByID(jobID)
+	job, err := findJob(h.repo, h.logger, principal(c), c.Param("id"))
 	if err != nil {
-		h.logger.Error("failed to find job", zap.String("job_id", jobID), zap.Error(err))
-		c.JSON(http.StatusNotFound, gin.H{"error": "job not found"})
+		apierror.Write(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, job)
 }
 
-// List 列出任务
+// List 列出任务，支持过滤、排序与游标分页
+//
+// 查询参数：status（逗号分隔或重复）、source、library_id、q（标题/艺术家/专辑搜索）、
+// created_after/created_before/updated_after/updated_before（RFC3339）、
+// sort（列名，前缀 "-" 表示降序，默认 -created_at）、limit（默认 50，最大 200）、cursor。
 func (h *JobHandler) List(c *gin.Context) {
-	status := c.Query("status")
-	limit := 50
+	filter := repository.JobFilter{
+		Source:    c.Query("source"),
+		LibraryID: c.Query("library_id"),
+		Query:     c.Query("q"),
+	}
+	// 非管理员只列出自己创建的任务
+	if p := principal(c); !p.IsAdmin() {
+		filter.APIKeyName = &p.Name
+	}
+	for _, value := range c.QueryArray("status") {
+		for _, status := range strings.Split(value, ",") {
+			if status = strings.TrimSpace(status); status != "" {
+				filter.Statuses = append(filter.Statuses, status)
+			}
+		}
+	}
 
-	var jobs []*model.Job
-	var err error
+	timeParams := []struct {
+		name   string
+		target **time.Time
+	}{
+		{"created_after", &filter.CreatedAfter},
+		{"created_before", &filter.CreatedBefore},
+		{"updated_after", &filter.UpdatedAfter},
+		{"updated_before", &filter.UpdatedBefore},
+	}
+	for _, param := range timeParams {
+		value := c.Query(param.name)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			apierror.Write(c, invalidParam(param.name, "must be an RFC3339 timestamp"))
+			return
+		}
+		*param.target = &t
+	}
+
+	sort := repository.JobSort{Field: "created_at", Desc: true}
+	if value := c.Query("sort"); value != "" {
+		sort.Desc = strings.HasPrefix(value, "-")
+		sort.Field = strings.TrimPrefix(value, "-")
+		if !repository.JobSortFields[sort.Field] {
+			apierror.Write(c, invalidParam("sort", "unsupported sort field"))
+			return
+		}
+	}
+
+	limit := defaultListLimit
+	if value := c.Query("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			apierror.Write(c, invalidParam("limit", "must be a positive integer"))
+			return
+		}
+		limit = min(parsed, maxListLimit)
+	}
 
-	if status != "" {
-		jobs, err = h.repo.ListByStatus(status, limit)
-	} else {
-		jobs, err = h.repo.ListRecent(limit)
+	var cursor *repository.JobCursor
+	if value := c.Query("cursor"); value != "" {
+		decoded, err := repository.DecodeCursor(value)
+		if err != nil || decoded.Field != sort.Field {
+			apierror.Write(c, invalidParam("cursor", "invalid cursor"))
+			return
+		}
+		cursor = decoded
 	}
 
+	// 多取一条用于判断是否还有下一页
+	jobs, err := h.repo.List(filter, sort, cursor, limit+1)
 	if err != nil {
 		h.logger.Error("failed to list jobs", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list jobs"})
+		apierror.Write(c, errcode.Wrap(errcode.Internal, "failed to list jobs", err))
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"jobs":  jobs,
-		"count": len(jobs),
+	total, err := h.repo.Count(filter)
+	if err != nil {
+		h.logger.Error("failed to count jobs", zap.Error(err))
+		apierror.Write(c, errcode.Wrap(errcode.Internal, "failed to list jobs", err))
+		return
+	}
+
+	nextCursor := ""
+	if len(jobs) > limit {
+		jobs = jobs[:limit]
+		nextCursor = repository.EncodeCursor(sort.CursorAfter(jobs[len(jobs)-1]))
+	}
+
+	c.JSON(http.StatusOK, ListJobsResponse{
+		Jobs:       jobs,
+		Count:      len(jobs),
+		Total:      total,
+		NextCursor: nextCursor,
 	})
 }
 
 // Retry 重试任务
 func (h *JobHandler) Retry(c *gin.Context) {
-	jobID := c.Param("id")
+	if err := h.drain.accepting(); err != nil {
+		apierror.Write(c, err)
+		return
+	}
 
-	job, err := h.repo.FindByID(jobID)
+	job, err := findJob(h.repo, h.logger, principal(c), c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "job not found"})
+		apierror.Write(c, err)
 		return
 	}
 
 	// 只能重试失败的任务
 	if job.Status != model.JobStatusFailed {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "only failed jobs can be retried"})
+		apierror.Write(c, errcode.New(errcode.InvalidState, "only failed jobs can be retried"))
 		return
 	}
 
-	// 重置状态
+	// 重置状态。查重与文件名冲突结果由重试重新得出，不沿用上一次的结果
+	// （ReservedPath 保留，重试会覆盖上一次写入曲库的文件而不是当作冲突）
 	job.Status = model.JobStatusQueued
 	job.Error = ""
 	job.Message = "retrying"
+	job.DuplicateOf = ""
+	job.CollisionOutcome = ""
 
 	if err := h.repo.Update(job); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update job"})
+		h.logger.Error("failed to update job", zap.String("job_id", job.ID), zap.Error(err))
+		apierror.Write(c, errcode.Wrap(errcode.Internal, "failed to update job", err))
 		return
 	}
 
@@ -247,7 +454,7 @@ func (h *JobHandler) Retry(c *gin.Context) {
 		lyricID = job.TrackID
 	}
 
-	payload := worker.DownloadPayload{
+	payload := tasks.DownloadPayload{
 		JobID:     job.ID,
 		Source:    job.Source,
 		TrackID:   job.TrackID,
@@ -255,78 +462,114 @@ func (h *JobHandler) Retry(c *gin.Context) {
 		LyricID:   lyricID,
 		LibraryID: job.LibraryID,
 		Quality:   job.Quality,
+		RequestID: c.GetString(apierror.RequestIDKey),
 	}
 
-	payloadBytes, _ := json.Marshal(payload)
-	task := asynq.NewTask(worker.TypeDownload, payloadBytes)
-
-	if _, err := h.client.Enqueue(task); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to enqueue task"})
+	if _, err := h.enqueueDownload(c.Request.Context(), payload); err != nil {
+		h.logger.Error("failed to enqueue task", zap.Error(err))
+		queueErr := errcode.Wrap(errcode.QueueUnavailable, "failed to enqueue task", err)
+		h.repo.MarkFailed(job.ID, queueErr)
+		apierror.Write(c, queueErr)
 		return
 	}
 
 	h.repo.IncrementRetry(job.ID)
+	h.events.PublishStatus(job.ID, model.JobStatusQueued, job.Message)
+
+	c.JSON(http.StatusOK, JobActionResponse{
+		JobID:   job.ID,
+		Status:  model.JobStatusQueued,
+		Message: "job queued for retry",
+	})
+}
+
+// Upgrade 为已完成任务发起音质升级：尝试解析更高码率并原地替换曲库文件
+func (h *JobHandler) Upgrade(c *gin.Context) {
+	if err := h.drain.accepting(); err != nil {
+		apierror.Write(c, err)
+		return
+	}
+
+	job, err := findJob(h.repo, h.logger, principal(c), c.Param("id"))
+	if err != nil {
+		apierror.Write(c, err)
+		return
+	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"job_id":  job.ID,
-		"status":  model.JobStatusQueued,
-		"message": "job queued for retry",
+	// 只能升级已完成且已入库的任务
+	if job.Status != model.JobStatusDone || job.FilePath == "" {
+		apierror.Write(c, errcode.New(errcode.InvalidState, "only done jobs can be upgraded"))
+		return
+	}
+
+	if err := tasks.EnqueueUpgrade(c.Request.Context(), h.client, h.inspector, job.ID, c.GetString(apierror.RequestIDKey)); err != nil {
+		if errors.Is(err, asynq.ErrTaskIDConflict) {
+			apierror.Write(c, errcode.New(errcode.AlreadyQueued, "upgrade already queued"))
+			return
+		}
+		h.logger.Error("failed to enqueue task", zap.Error(err))
+		apierror.Write(c, errcode.Wrap(errcode.QueueUnavailable, "failed to enqueue task", err))
+		return
+	}
+
+	c.JSON(http.StatusAccepted, JobActionResponse{
+		JobID:   job.ID,
+		Message: "upgrade queued",
 	})
 }
 
 // Cancel 取消任务
 func (h *JobHandler) Cancel(c *gin.Context) {
-	jobID := c.Param("id")
-
-	job, err := h.repo.FindByID(jobID)
+	job, err := h.cancelJob(principal(c), c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "job not found"})
+		apierror.Write(c, err)
 		return
 	}
 
+	c.JSON(http.StatusOK, JobActionResponse{
+		JobID:   job.ID,
+		Status:  model.JobStatusCancelled,
+		Message: "job cancelled successfully",
+	})
+}
+
+// cancelJob 取消任务（REST 与 WebSocket 共用）
+func (h *JobHandler) cancelJob(p *auth.Principal, jobID string) (*model.Job, error) {
+	job, err := findJob(h.repo, h.logger, p, jobID)
+	if err != nil {
+		return nil, err
+	}
+
 	// 只能取消进行中的任务
 	if job.Status == model.JobStatusDone || job.Status == model.JobStatusFailed {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "cannot cancel completed or failed job"})
-		return
+		return nil, errcode.New(errcode.InvalidState, "cannot cancel completed or failed job")
 	}
 
 	job.Status = model.JobStatusCancelled
 	job.Message = "cancelled by user"
 
 	if err := h.repo.Update(job); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update job"})
-		return
+		h.logger.Error("failed to update job", zap.String("job_id", job.ID), zap.Error(err))
+		return nil, errcode.Wrap(errcode.Internal, "failed to update job", err)
 	}
+	h.events.PublishStatus(job.ID, model.JobStatusCancelled, job.Message)
 
-	c.JSON(http.StatusOK, gin.H{
-		"job_id":  job.ID,
-		"status":  model.JobStatusCancelled,
-		"message": "job cancelled successfully",
-	})
+	return job, nil
 }
 
-// Health 健康检查
-func (h *JobHandler) Health(c *gin.Context) {
-	// 检查数据库
-	count, err := h.repo.CountByStatus(model.JobStatusQueued)
+// findJob 查询任务，区分任务不存在与数据库错误（REST、SSE 与 WebSocket 共用）。
+// 不属于当前 Key 的任务同样返回 JOB_NOT_FOUND，不暴露其存在
+func findJob(repo *repository.JobRepository, logger *zap.Logger, p *auth.Principal, jobID string) (*model.Job, error) {
+	job, err := repo.FindByID(jobID)
 	if err != nil {
-		c.JSON(http.StatusServiceUnavailable, gin.H{
-			"status": "unhealthy",
-			"error":  "database connection failed",
-		})
-		return
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errcode.New(errcode.JobNotFound, "job not found")
+		}
+		logger.Error("failed to find job", zap.String("job_id", jobID), zap.Error(err))
+		return nil, errcode.Wrap(errcode.Internal, "failed to find job", err)
 	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"status":  "healthy",
-		"version": "1.0.0-m1",
-		"uptime":  time.Since(time.Now()).Seconds(),
-		"components": gin.H{
-			"database": "healthy",
-			"queue":    "healthy",
-		},
-		"stats": gin.H{
-			"queued_jobs": count,
-		},
-	})
+	if !p.Owns(job) {
+		return nil, errcode.New(errcode.JobNotFound, "job not found")
+	}
+	return job, nil
 }
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+
+	"github.com/azin/gdstudio-embed-service/internal/api/openapi"
+	"github.com/azin/gdstudio-embed-service/internal/errcode"
+	"github.com/go-playground/validator/v10"
+)
+
+// bindingError 将 gin 绑定错误转换为与校验中间件一致的字段级错误。
+// 正常情况下请求已由 OpenAPI 校验中间件拦截，这里兜底处理 WebSocket 等未经中间件的入口。
+func bindingError(obj interface{}, err error) *errcode.Error {
+	var validationErrors validator.ValidationErrors
+	if errors.As(err, &validationErrors) {
+		t := reflect.TypeOf(obj)
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+
+		fields := make([]openapi.FieldError, 0, len(validationErrors))
+		for _, fe := range validationErrors {
+			fields = append(fields, openapi.FieldError{
+				Field:   jsonFieldName(t, fe.StructField()),
+				In:      "body",
+				Message: validationMessage(fe),
+				Rule:    fe.Tag(),
+			})
+		}
+		return errcode.New(errcode.ValidationFailed, "validation failed").WithDetails(fields)
+	}
+
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &typeErr):
+		return errcode.New(errcode.ValidationFailed, "validation failed").WithDetails([]openapi.FieldError{{
+			Field:   typeErr.Field,
+			In:      "body",
+			Message: "must be a " + typeErr.Type.Kind().String(),
+			Rule:    "type",
+		}})
+	case errors.As(err, &syntaxErr):
+		return errcode.New(errcode.InvalidRequest, "invalid json body")
+	}
+	return errcode.Wrap(errcode.InvalidRequest, "invalid request body", err)
+}
+
+// invalidParam 单个查询参数校验失败
+func invalidParam(name, message string) *errcode.Error {
+	return errcode.New(errcode.ValidationFailed, "validation failed").WithDetails([]openapi.FieldError{{
+		Field:   name,
+		In:      "query",
+		Message: message,
+	}})
+}
+
+// jsonFieldName 返回结构体字段对应的 JSON 名称
+func jsonFieldName(t reflect.Type, structField string) string {
+	if t.Kind() != reflect.Struct {
+		return structField
+	}
+	field, ok := t.FieldByName(structField)
+	if !ok {
+		return structField
+	}
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return structField
+	}
+	return name
+}
+
+func validationMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "oneof":
+		return "must be one of " + strings.Join(strings.Fields(fe.Param()), ", ")
+	case "url", "uri":
+		return "must be an absolute URL"
+	case "min", "gte":
+		return "must be at least " + fe.Param()
+	case "max", "lte":
+		return "must be at most " + fe.Param()
+	}
+	return "failed " + fe.Tag() + " validation"
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/azin/gdstudio-embed-service/internal/api/apierror"
+	"github.com/azin/gdstudio-embed-service/internal/errcode"
+	"github.com/azin/gdstudio-embed-service/internal/model"
+	"github.com/azin/gdstudio-embed-service/internal/repository"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// WebhookHandler Webhook 接口处理器
+type WebhookHandler struct {
+	repo   *repository.WebhookRepository
+	logger *zap.Logger
+}
+
+// NewWebhookHandler 创建 Webhook 接口处理器
+func NewWebhookHandler(repo *repository.WebhookRepository, logger *zap.Logger) *WebhookHandler {
+	return &WebhookHandler{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// DeliveriesResponse 投递记录响应
+type DeliveriesResponse struct {
+	Webhook    *model.Webhook           `json:"webhook"`
+	Deliveries []*model.WebhookDelivery `json:"deliveries"`
+	Count      int                      `json:"count"`
+}
+
+// Deliveries 查询订阅最近的投递记录（?limit=，默认 50，最大 200），只能查看当前 API Key 的订阅（管理员可查看全部）
+func (h *WebhookHandler) Deliveries(c *gin.Context) {
+	hook, err := h.repo.FindByID(c.Param("id"))
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		h.logger.Error("failed to find webhook", zap.Error(err))
+		apierror.Write(c, errcode.Wrap(errcode.Internal, "failed to find webhook", err))
+		return
+	}
+	if p := principal(c); err != nil || (hook.APIKeyName != "" && hook.APIKeyName != p.Name && !p.IsAdmin()) {
+		apierror.Write(c, errcode.New(errcode.WebhookNotFound, "webhook not found"))
+		return
+	}
+
+	limit := defaultListLimit
+	if value := c.Query("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			apierror.Write(c, invalidParam("limit", "must be a positive integer"))
+			return
+		}
+		limit = min(parsed, maxListLimit)
+	}
+
+	deliveries, err := h.repo.ListDeliveries(hook.ID, limit)
+	if err != nil {
+		h.logger.Error("failed to list webhook deliveries", zap.Error(err))
+		apierror.Write(c, errcode.Wrap(errcode.Internal, "failed to list deliveries", err))
+		return
+	}
+
+	// 响应体来自外部地址，只对管理员可见
+	if !principal(c).IsAdmin() {
+		for i := range deliveries {
+			deliveries[i].ResponseBody = ""
+		}
+	}
+
+	c.JSON(http.StatusOK, DeliveriesResponse{
+		Webhook:    hook,
+		Deliveries: deliveries,
+		Count:      len(deliveries),
+	})
+}
@@ -0,0 +1,373 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/azin/gdstudio-embed-service/internal/api/apierror"
+	"github.com/azin/gdstudio-embed-service/internal/api/openapi"
+	"github.com/azin/gdstudio-embed-service/internal/auth"
+	"github.com/azin/gdstudio-embed-service/internal/errcode"
+	"github.com/azin/gdstudio-embed-service/internal/model"
+	"github.com/azin/gdstudio-embed-service/internal/repository"
+	"github.com/azin/gdstudio-embed-service/internal/service/events"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+)
+
+// WSProtocolVersion WebSocket 消息信封版本
+const WSProtocolVersion = 1
+
+// WebSocket 消息类型
+const (
+	// 客户端 -> 服务端
+	WSTypeJobCreate      = "job.create"      // data: CreateJobRequest
+	WSTypeJobSubscribe   = "job.subscribe"   // data: {"job_ids": [...]}
+	WSTypeJobUnsubscribe = "job.unsubscribe" // data: {"job_ids": [...]}
+	WSTypeJobCancel      = "job.cancel"      // data: {"job_id": "..."}
+	WSTypePing           = "ping"
+
+	// 服务端 -> 客户端
+	WSTypeAck          = "ack"          // 对客户端消息的成功响应，id 与请求一致
+	WSTypeError        = "error"        // 对客户端消息的失败响应
+	WSTypeJobEvent     = "job.event"    // 已订阅任务的状态/进度，data: events.Event
+	WSTypeNotification = "notification" // 当前 API Key 创建的任务结束，data: events.Event
+	WSTypePong         = "pong"
+)
+
+const (
+	wsWriteTimeout   = 10 * time.Second
+	wsPongTimeout    = 60 * time.Second
+	wsPingInterval   = 30 * time.Second
+	wsMaxMessageSize = 64 * 1024
+	wsSendBuffer     = 256
+)
+
+// WSMessage 版本化消息信封，客户端通过 id 关联请求与响应，通过 data.job_id 区分多路任务
+type WSMessage struct {
+	V     int                `json:"v"`
+	Type  string             `json:"type"`
+	ID    string             `json:"id,omitempty"`
+	Data  json.RawMessage    `json:"data,omitempty"`
+	Error *apierror.Response `json:"error,omitempty"`
+}
+
+type wsJobIDs struct {
+	JobIDs []string `json:"job_ids"`
+}
+
+type wsJobID struct {
+	JobID string `json:"job_id"`
+}
+
+// WSHandler WebSocket 接口处理器
+type WSHandler struct {
+	jobs     *JobHandler
+	repo     *repository.JobRepository
+	bus      *events.Bus
+	upgrader websocket.Upgrader
+	logger   *zap.Logger
+}
+
+// NewWSHandler 创建 WebSocket 处理器
+func NewWSHandler(jobs *JobHandler, repo *repository.JobRepository, bus *events.Bus, logger *zap.Logger) *WSHandler {
+	return &WSHandler{
+		jobs: jobs,
+		repo: repo,
+		bus:  bus,
+		upgrader: websocket.Upgrader{
+			// 握手已经过 API Key 认证，不限制来源
+			CheckOrigin: func(r *http.Request) bool { return true },
+		},
+		logger: logger,
+	}
+}
+
+// wsConn 单个 WebSocket 连接的状态
+type wsConn struct {
+	h         *WSHandler
+	conn      *websocket.Conn
+	principal *auth.Principal
+	requestID string          // 握手请求的 ID，连接内提交的任务沿用
+	ctx       context.Context // 握手请求的 context（含 trace 上下文），连接关闭时取消
+	send      chan WSMessage
+	done      chan struct{}
+
+	mu         sync.Mutex
+	subscribed map[string]bool
+}
+
+// Serve 升级为 WebSocket 连接（认证由 v1 路由组的 Auth 中间件在握手时完成）
+func (h *WSHandler) Serve(c *gin.Context) {
+	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		h.logger.Warn("websocket upgrade failed", zap.Error(err))
+		return
+	}
+
+	// 订阅全部任务事件，在连接内按订阅集合与 API Key 过滤
+	sub, err := h.bus.Subscribe(c.Request.Context(), nil)
+	if err != nil {
+		h.logger.Error("failed to subscribe job events", zap.Error(err))
+		conn.WriteMessage(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseInternalServerErr, "event stream unavailable"))
+		conn.Close()
+		return
+	}
+	defer sub.Close()
+
+	wc := &wsConn{
+		h:          h,
+		conn:       conn,
+		principal:  principal(c),
+		requestID:  c.GetString(apierror.RequestIDKey),
+		ctx:        c.Request.Context(),
+		send:       make(chan WSMessage, wsSendBuffer),
+		done:       make(chan struct{}),
+		subscribed: make(map[string]bool),
+	}
+
+	go wc.writeLoop()
+	go wc.forwardEvents(sub)
+	wc.readLoop()
+}
+
+// readLoop 读取并处理客户端消息，连接断开时返回
+func (wc *wsConn) readLoop() {
+	defer func() {
+		close(wc.done)
+		wc.conn.Close()
+	}()
+
+	wc.conn.SetReadLimit(wsMaxMessageSize)
+	wc.conn.SetReadDeadline(time.Now().Add(wsPongTimeout))
+	wc.conn.SetPongHandler(func(string) error {
+		return wc.conn.SetReadDeadline(time.Now().Add(wsPongTimeout))
+	})
+
+	for {
+		var msg WSMessage
+		if err := wc.conn.ReadJSON(&msg); err != nil {
+			var syntaxErr *json.SyntaxError
+			if errors.As(err, &syntaxErr) {
+				wc.replyError(&msg, errcode.New(errcode.InvalidRequest, "invalid json"))
+				continue
+			}
+			return
+		}
+		wc.conn.SetReadDeadline(time.Now().Add(wsPongTimeout))
+		wc.handle(&msg)
+	}
+}
+
+// writeLoop 唯一的写协程（gorilla/websocket 不支持并发写），并定时发送 ping
+func (wc *wsConn) writeLoop() {
+	ticker := time.NewTicker(wsPingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-wc.done:
+			return
+		case <-wc.h.jobs.drain.Done():
+			// 服务关闭：通知客户端重连，readLoop 随连接关闭退出
+			wc.conn.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down"),
+				time.Now().Add(wsWriteTimeout))
+			wc.conn.Close()
+			return
+		case msg := <-wc.send:
+			wc.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+			if err := wc.conn.WriteJSON(msg); err != nil {
+				wc.conn.Close()
+				return
+			}
+		case <-ticker.C:
+			if err := wc.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteTimeout)); err != nil {
+				wc.conn.Close()
+				return
+			}
+		}
+	}
+}
+
+// forwardEvents 将已订阅任务的事件与本 API Key 任务的结束通知推送给客户端。
+// 通知只发给创建任务的 Key（管理员也一样），任务创建者按连接缓存
+func (wc *wsConn) forwardEvents(sub *events.Subscription) {
+	access := newJobAccess(wc.h.repo, wc.principal)
+	for {
+		select {
+		case <-wc.done:
+			return
+		case event, ok := <-sub.Events():
+			if !ok {
+				return
+			}
+			wc.mu.Lock()
+			subscribed := wc.subscribed[event.JobID]
+			wc.mu.Unlock()
+
+			if subscribed {
+				wc.push(WSTypeJobEvent, "", event)
+			}
+			if event.Terminal() && access.createdByKey(event.JobID) {
+				wc.push(WSTypeNotification, "", event)
+			}
+		}
+	}
+}
+
+func (wc *wsConn) handle(msg *WSMessage) {
+	if msg.V != WSProtocolVersion {
+		wc.replyError(msg, errcode.New(errcode.InvalidRequest, "unsupported protocol version"))
+		return
+	}
+
+	switch msg.Type {
+	case WSTypePing:
+		wc.push(WSTypePong, msg.ID, nil)
+
+	case WSTypeJobCreate:
+		if !wc.require(msg, auth.ScopeJobsCreate) {
+			return
+		}
+		var req CreateJobRequest
+		if err := json.Unmarshal(msg.Data, &req); err != nil {
+			wc.replyError(msg, errcode.New(errcode.InvalidRequest, "invalid data"))
+			return
+		}
+		if err := binding.Validator.ValidateStruct(&req); err != nil {
+			wc.replyError(msg, bindingError(&req, err))
+			return
+		}
+		resp, err := wc.h.jobs.submitJob(wc.ctx, &req, wc.principal, wc.requestID)
+		if err != nil {
+			wc.replyError(msg, err)
+			return
+		}
+		// 新建的任务自动订阅
+		wc.subscribe(resp.JobID)
+		wc.push(WSTypeAck, msg.ID, resp)
+
+	case WSTypeJobSubscribe:
+		var data wsJobIDs
+		if err := json.Unmarshal(msg.Data, &data); err != nil || len(data.JobIDs) == 0 {
+			wc.replyError(msg, wsFieldError("job_ids"))
+			return
+		}
+		jobs := make([]*model.Job, 0, len(data.JobIDs))
+		for _, id := range data.JobIDs {
+			job, err := findJob(wc.h.repo, wc.h.logger, wc.principal, id)
+			if err != nil {
+				wc.replyError(msg, err)
+				return
+			}
+			jobs = append(jobs, job)
+		}
+		// 先订阅再推送快照，避免遗漏之间的事件
+		for _, job := range jobs {
+			wc.subscribe(job.ID)
+		}
+		wc.push(WSTypeAck, msg.ID, data)
+		for _, job := range jobs {
+			wc.push(WSTypeJobEvent, "", events.SnapshotEvent(job))
+		}
+
+	case WSTypeJobUnsubscribe:
+		var data wsJobIDs
+		if err := json.Unmarshal(msg.Data, &data); err != nil {
+			wc.replyError(msg, wsFieldError("job_ids"))
+			return
+		}
+		wc.mu.Lock()
+		for _, id := range data.JobIDs {
+			delete(wc.subscribed, id)
+		}
+		wc.mu.Unlock()
+		wc.push(WSTypeAck, msg.ID, data)
+
+	case WSTypeJobCancel:
+		if !wc.require(msg, auth.ScopeJobsCancel) {
+			return
+		}
+		var data wsJobID
+		if err := json.Unmarshal(msg.Data, &data); err != nil || data.JobID == "" {
+			wc.replyError(msg, wsFieldError("job_id"))
+			return
+		}
+		job, err := wc.h.jobs.cancelJob(wc.principal, data.JobID)
+		if err != nil {
+			wc.replyError(msg, err)
+			return
+		}
+		wc.push(WSTypeAck, msg.ID, gin.H{"job_id": job.ID, "status": job.Status})
+
+	default:
+		wc.replyError(msg, errcode.New(errcode.InvalidRequest, "unknown message type"))
+	}
+}
+
+// require 检查 API Key 拥有 scope，否则回复 FORBIDDEN（握手时只要求 jobs:read）
+func (wc *wsConn) require(msg *WSMessage, scope auth.Scope) bool {
+	if wc.principal.Has(scope) {
+		return true
+	}
+	wc.replyError(msg, errcode.New(errcode.Forbidden, "api key lacks scope "+string(scope)))
+	return false
+}
+
+func (wc *wsConn) subscribe(jobID string) {
+	wc.mu.Lock()
+	wc.subscribed[jobID] = true
+	wc.mu.Unlock()
+}
+
+// push 发送消息；客户端读取过慢导致缓冲区满时断开连接
+func (wc *wsConn) push(msgType, id string, data interface{}) {
+	msg := WSMessage{V: WSProtocolVersion, Type: msgType, ID: id}
+	if data != nil {
+		raw, err := json.Marshal(data)
+		if err != nil {
+			return
+		}
+		msg.Data = raw
+	}
+	wc.enqueue(msg)
+}
+
+// replyError 回复错误，错误码与 REST 接口一致
+func (wc *wsConn) replyError(req *WSMessage, err error) {
+	resp := apierror.From(err)
+	wc.enqueue(WSMessage{
+		V:     WSProtocolVersion,
+		Type:  WSTypeError,
+		ID:    req.ID,
+		Error: &resp,
+	})
+}
+
+// wsFieldError data 中缺少必填字段
+func wsFieldError(field string) *errcode.Error {
+	return errcode.New(errcode.ValidationFailed, "validation failed").WithDetails([]openapi.FieldError{{
+		Field:   "data." + field,
+		In:      "body",
+		Message: "is required",
+		Rule:    "required",
+	}})
+}
+
+func (wc *wsConn) enqueue(msg WSMessage) {
+	select {
+	case wc.send <- msg:
+	case <-wc.done:
+	default:
+		wc.h.logger.Warn("websocket send buffer full, closing connection")
+		wc.conn.Close()
+	}
+}
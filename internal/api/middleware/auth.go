@@ -1,21 +1,17 @@
 package middleware
 
 import (
+	"fmt"
 	"net/http"
-	"strings"
 
-	"github.com/azin/gdstudio-embed-service/internal/config"
+	"github.com/azin/gdstudio-embed-service/internal/api/apierror"
+	"github.com/azin/gdstudio-embed-service/internal/auth"
+	"github.com/azin/gdstudio-embed-service/internal/errcode"
 	"github.com/gin-gonic/gin"
 )
 
-// Auth API Key 认证中间件
-func Auth(cfg *config.SecurityConfig) gin.HandlerFunc {
-	// 构建 API Key 映射
-	validKeys := make(map[string]string)
-	for _, key := range cfg.APIKeys {
-		validKeys[key.Key] = key.Name
-	}
-
+// Auth API Key 认证中间件，在 context 中保存 api_key_name 与权限（auth.ContextKey）
+func Auth(keys *auth.Keyring) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 从 Header 获取 API Key
 		apiKey := c.GetHeader("X-API-Key")
@@ -25,20 +21,31 @@ func Auth(cfg *config.SecurityConfig) gin.HandlerFunc {
 		}
 
 		if apiKey == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "missing api key"})
-			c.Abort()
+			apierror.Abort(c, errcode.New(errcode.Unauthorized, "missing api key"))
 			return
 		}
 
 		// 验证 API Key
-		if name, ok := validKeys[apiKey]; ok {
-			c.Set("api_key_name", name)
+		if principal, ok := keys.Lookup(apiKey); ok {
+			c.Set("api_key_name", principal.Name)
+			c.Set(auth.ContextKey, principal)
 			c.Next()
 			return
 		}
 
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid api key"})
-		c.Abort()
+		apierror.Abort(c, errcode.New(errcode.Unauthorized, "invalid api key"))
+	}
+}
+
+// RequireScope 要求 API Key 拥有指定 scope（需在 Auth 之后使用）
+func RequireScope(scope auth.Scope) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, _ := c.Get(auth.ContextKey)
+		if p, ok := principal.(*auth.Principal); !ok || !p.Has(scope) {
+			apierror.Abort(c, errcode.New(errcode.Forbidden, fmt.Sprintf("api key lacks scope %s", scope)))
+			return
+		}
+		c.Next()
 	}
 }
 
@@ -47,7 +54,8 @@ func CORS() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
 		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, X-API-Key")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, X-API-Key, X-Request-ID")
+		c.Writer.Header().Set("Access-Control-Expose-Headers", "X-Request-ID")
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE")
 
 		if c.Request.Method == "OPTIONS" {
@@ -58,18 +66,3 @@ func CORS() gin.HandlerFunc {
 		c.Next()
 	}
 }
-
-// RequestLogger 请求日志中间件
-func RequestLogger() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		// 跳过健康检查日志
-		if strings.HasPrefix(c.Request.URL.Path, "/healthz") {
-			c.Next()
-			return
-		}
-
-		c.Next()
-
-		// Gin 自带的日志已经足够
-	}
-}
@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"net/http"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/azin/gdstudio-embed-service/internal/api/apierror"
+	"github.com/azin/gdstudio-embed-service/internal/errcode"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// RequestIDHeader 请求 ID 头
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength 客户端传入的请求 ID 最大长度，超出或含非法字符时重新生成
+const maxRequestIDLength = 128
+
+// RequestID 沿用客户端传入的 X-Request-ID，没有时生成新的，并写入响应头
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = uuid.New().String()
+		}
+
+		c.Set(apierror.RequestIDKey, id)
+		c.Header(RequestIDHeader, id)
+		c.Next()
+	}
+}
+
+// validRequestID 只接受可打印 ASCII，避免日志注入
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// RequestLogger 使用 zap 记录访问日志（替代 gin 自带的日志，保持 JSON 日志格式一致）
+func RequestLogger(logger *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// 跳过健康检查日志
+		if strings.HasPrefix(c.Request.URL.Path, "/healthz") || strings.HasPrefix(c.Request.URL.Path, "/readyz") {
+			c.Next()
+			return
+		}
+
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		status := c.Writer.Status()
+		fields := []zap.Field{
+			zap.String("request_id", c.GetString(apierror.RequestIDKey)),
+			zap.String("method", c.Request.Method),
+			zap.String("route", route),
+			zap.String("path", c.Request.URL.Path),
+			zap.Int("status", status),
+			zap.Duration("latency", time.Since(start)),
+			zap.Int("bytes", max(c.Writer.Size(), 0)),
+			zap.String("client_ip", c.ClientIP()),
+		}
+		if name := c.GetString("api_key_name"); name != "" {
+			fields = append(fields, zap.String("api_key_name", name))
+		}
+		if len(c.Errors) > 0 {
+			fields = append(fields, zap.String("errors", c.Errors.String()))
+		}
+
+		switch {
+		case status >= http.StatusInternalServerError:
+			logger.Error("request completed", fields...)
+		case status >= http.StatusBadRequest:
+			logger.Warn("request completed", fields...)
+		default:
+			logger.Info("request completed", fields...)
+		}
+	}
+}
+
+// Recovery 捕获 handler 中的 panic，记录日志并返回 INTERNAL_ERROR
+func Recovery(logger *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			if r := recover(); r != nil {
+				logger.Error("panic recovered",
+					zap.String("request_id", c.GetString(apierror.RequestIDKey)),
+					zap.String("method", c.Request.Method),
+					zap.String("path", c.Request.URL.Path),
+					zap.Any("panic", r),
+					zap.ByteString("stack", debug.Stack()))
+				if !c.Writer.Written() {
+					apierror.Abort(c, errcode.New(errcode.Internal, "internal error"))
+				} else {
+					c.Abort()
+				}
+			}
+		}()
+		c.Next()
+	}
+}
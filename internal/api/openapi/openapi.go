@@ -0,0 +1,178 @@
+// Package openapi 根据 handler 的请求/响应类型生成 OpenAPI 3 文档，并按文档校验请求。
+package openapi
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Version OpenAPI 规范版本
+const Version = "3.0.3"
+
+// NoAuth 用于 Operation.Security，表示接口无需认证
+var NoAuth = []map[string][]string{{}}
+
+// Document OpenAPI 文档（只包含本服务用到的字段）
+type Document struct {
+	OpenAPI    string                `json:"openapi"`
+	Info       Info                  `json:"info"`
+	Paths      map[string]PathItem   `json:"paths"`
+	Components Components            `json:"components"`
+	Security   []map[string][]string `json:"security,omitempty"`
+
+	types map[reflect.Type]string // 已注册到 components 的类型
+}
+
+// Info 文档信息
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// PathItem 路径下各 HTTP 方法（小写）的操作
+type PathItem map[string]*Operation
+
+// Components 可复用组件
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+// SecurityScheme 认证方式
+type SecurityScheme struct {
+	Type string `json:"type"`
+	Name string `json:"name"`
+	In   string `json:"in"`
+}
+
+// Operation 单个接口
+type Operation struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []*Parameter          `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+// Parameter 路径或查询参数
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"` // path / query
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Explode     *bool   `json:"explode,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// RequestBody 请求体
+type RequestBody struct {
+	Required bool                  `json:"required,omitempty"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+// Response 响应
+type Response struct {
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+// MediaType 内容类型对应的结构
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Schema JSON Schema（OpenAPI 3.0 子集）
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+// New 创建空文档，所有接口默认使用 X-API-Key 认证
+func New(title, version string) *Document {
+	return &Document{
+		OpenAPI: Version,
+		Info:    Info{Title: title, Version: version},
+		Paths:   make(map[string]PathItem),
+		Components: Components{
+			Schemas: make(map[string]*Schema),
+			SecuritySchemes: map[string]*SecurityScheme{
+				"ApiKeyHeader": {Type: "apiKey", Name: "X-API-Key", In: "header"},
+				"ApiKeyQuery":  {Type: "apiKey", Name: "api_key", In: "query"},
+			},
+		},
+		Security: []map[string][]string{{"ApiKeyHeader": {}}, {"ApiKeyQuery": {}}},
+		types:    make(map[reflect.Type]string),
+	}
+}
+
+// Add 注册接口，path 使用 OpenAPI 风格的 {param} 占位符
+func (d *Document) Add(method, path string, op *Operation) {
+	item, ok := d.Paths[path]
+	if !ok {
+		item = make(PathItem)
+		d.Paths[path] = item
+	}
+	item[strings.ToLower(method)] = op
+}
+
+// Operation 查找接口，path 可以是 gin 风格（:param）或 OpenAPI 风格
+func (d *Document) Operation(method, path string) *Operation {
+	item, ok := d.Paths[GinPathToOpenAPI(path)]
+	if !ok {
+		return nil
+	}
+	return item[strings.ToLower(method)]
+}
+
+// Resolve 解析 $ref，非引用时原样返回
+func (d *Document) Resolve(schema *Schema) *Schema {
+	for schema != nil && schema.Ref != "" {
+		schema = d.Components.Schemas[strings.TrimPrefix(schema.Ref, "#/components/schemas/")]
+	}
+	return schema
+}
+
+// JSONBody 以 v 的类型作为必填 JSON 请求体
+func (d *Document) JSONBody(v interface{}) *RequestBody {
+	return &RequestBody{
+		Required: true,
+		Content:  map[string]*MediaType{"application/json": {Schema: d.SchemaOf(v)}},
+	}
+}
+
+// JSONResponse 以 v 的类型作为 JSON 响应体，v 为 nil 时无响应体
+func (d *Document) JSONResponse(description string, v interface{}) *Response {
+	resp := &Response{Description: description}
+	if v != nil {
+		resp.Content = map[string]*MediaType{"application/json": {Schema: d.SchemaOf(v)}}
+	}
+	return resp
+}
+
+// GinPathToOpenAPI 将 /jobs/:id 转换为 /jobs/{id}
+func GinPathToOpenAPI(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,203 @@
+package openapi
+
+import (
+	"encoding/json"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+// SchemaOf 生成 v 的类型对应的 schema。具名结构体注册到 components 并返回 $ref，
+// 因此文档始终与 handler 实际使用的 Go 类型保持一致。
+func (d *Document) SchemaOf(v interface{}) *Schema {
+	return d.schemaFor(reflect.TypeOf(v))
+}
+
+func (d *Document) schemaFor(t reflect.Type) *Schema {
+	if t == nil {
+		return &Schema{}
+	}
+	if t.Kind() == reflect.Ptr {
+		schema := d.schemaFor(t.Elem())
+		if schema.Ref != "" {
+			return schema
+		}
+		schema.Nullable = true
+		return schema
+	}
+
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t == rawMessageType:
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: d.schemaFor(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: d.schemaFor(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return d.structSchema(t)
+		}
+		return d.ref(t)
+	default:
+		// interface{} 等任意值
+		return &Schema{}
+	}
+}
+
+// ref 注册具名结构体并返回引用；同名的不同类型以包名区分
+func (d *Document) ref(t reflect.Type) *Schema {
+	name, ok := d.types[t]
+	if !ok {
+		name = t.Name()
+		if _, taken := d.Components.Schemas[name]; taken {
+			pkg := path.Base(t.PkgPath())
+			name = strings.ToUpper(pkg[:1]) + pkg[1:] + name
+		}
+		d.types[t] = name
+		// 先占位，允许递归引用自身
+		d.Components.Schemas[name] = &Schema{}
+		*d.Components.Schemas[name] = *d.structSchema(t)
+	}
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+func (d *Document) structSchema(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	d.addFields(schema, t)
+	return schema
+}
+
+// addFields 按 encoding/json 的规则展开字段（含匿名嵌入），并读取 binding 标签中的约束
+func (d *Document) addFields(schema *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, opts := parseTag(field.Tag.Get("json"))
+		if name == "-" && opts == "" {
+			continue
+		}
+		if field.Anonymous && name == "" {
+			ft := field.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				d.addFields(schema, ft)
+				continue
+			}
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		prop := d.schemaFor(field.Type)
+		if applyBinding(prop, field.Tag.Get("binding")) {
+			schema.Required = append(schema.Required, name)
+		}
+		schema.Properties[name] = prop
+	}
+}
+
+// applyBinding 将 gin（validator）的 binding 标签转换为 schema 约束，返回字段是否必填
+func applyBinding(schema *Schema, tag string) bool {
+	if tag == "" || schema.Ref != "" {
+		return false
+	}
+
+	required := false
+	for _, rule := range strings.Split(tag, ",") {
+		key, value, _ := strings.Cut(rule, "=")
+		switch key {
+		case "required":
+			required = true
+			// validator 的 required 同时拒绝零值
+			if schema.Type == "string" && schema.MinLength == nil {
+				schema.MinLength = intPtr(1)
+			}
+		case "oneof":
+			for _, option := range strings.Fields(value) {
+				schema.Enum = append(schema.Enum, enumValue(schema.Type, option))
+			}
+		case "url", "uri":
+			schema.Format = "uri"
+		case "email":
+			schema.Format = "email"
+		case "min", "gte":
+			setLowerBound(schema, value)
+		case "max", "lte":
+			setUpperBound(schema, value)
+		}
+	}
+	return required
+}
+
+func setLowerBound(schema *Schema, value string) {
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return
+	}
+	switch schema.Type {
+	case "string":
+		schema.MinLength = intPtr(int(n))
+	case "integer", "number":
+		schema.Minimum = &n
+	}
+}
+
+func setUpperBound(schema *Schema, value string) {
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return
+	}
+	switch schema.Type {
+	case "string":
+		schema.MaxLength = intPtr(int(n))
+	case "array":
+		schema.MaxItems = intPtr(int(n))
+	case "integer", "number":
+		schema.Maximum = &n
+	}
+}
+
+func enumValue(schemaType, value string) interface{} {
+	if schemaType == "integer" {
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return n
+		}
+	}
+	return value
+}
+
+func parseTag(tag string) (string, string) {
+	name, opts, _ := strings.Cut(tag, ",")
+	return name, opts
+}
+
+func intPtr(n int) *int {
+	return &n
+}
@@ -0,0 +1,287 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FieldError 字段级校验错误
+type FieldError struct {
+	Field   string `json:"field"`          // 字段路径，如 track_id、items[0].name
+	In      string `json:"in"`             // body / query / path
+	Message string `json:"message"`        // 可读的错误描述
+	Rule    string `json:"rule,omitempty"` // 未通过的约束：required / type / enum / format / ...
+}
+
+// patternCache 缓存编译后的 pattern
+var patternCache sync.Map
+
+// ValidateBody 按 schema 校验已解码的 JSON 值（需使用 json.Decoder.UseNumber 解码）
+func (d *Document) ValidateBody(schema *Schema, value interface{}) []FieldError {
+	v := validator{doc: d, in: "body"}
+	v.validate(schema, value, "", true)
+	return v.errors
+}
+
+// ValidateParam 校验路径或查询参数，values 为空表示未传
+func (d *Document) ValidateParam(param *Parameter, values []string) []FieldError {
+	v := validator{doc: d, in: param.In}
+	if len(values) == 0 {
+		if param.Required {
+			v.add(param.Name, "required", "is required")
+		}
+		return v.errors
+	}
+
+	schema := d.Resolve(param.Schema)
+	if schema.Type == "array" {
+		if schema.MaxItems != nil && len(values) > *schema.MaxItems {
+			v.add(param.Name, "maxItems", fmt.Sprintf("must contain at most %d items", *schema.MaxItems))
+		}
+		schema = d.Resolve(schema.Items)
+	}
+	for _, raw := range values {
+		value, ok := parseParam(schema.Type, raw)
+		if !ok {
+			v.add(param.Name, "type", "must be "+article(schema.Type))
+			continue
+		}
+		v.validate(schema, value, param.Name, true)
+	}
+	return v.errors
+}
+
+type validator struct {
+	doc    *Document
+	in     string
+	errors []FieldError
+}
+
+func (v *validator) add(field, rule, message string) {
+	v.errors = append(v.errors, FieldError{Field: field, In: v.in, Message: message, Rule: rule})
+}
+
+// validate 校验单个值。required 为 false 的空字符串视为未填写，不检查 enum/format/pattern，
+// 与 binding 标签中 omitempty 的语义一致。
+func (v *validator) validate(schema *Schema, value interface{}, field string, required bool) {
+	schema = v.doc.Resolve(schema)
+	if schema == nil {
+		return
+	}
+	if value == nil {
+		if !schema.Nullable && schema.Type != "" {
+			v.add(field, "type", "must not be null")
+		}
+		return
+	}
+
+	switch schema.Type {
+	case "object":
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			v.add(field, "type", "must be an object")
+			return
+		}
+		v.validateObject(schema, obj, field)
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			v.add(field, "type", "must be an array")
+			return
+		}
+		if schema.MaxItems != nil && len(items) > *schema.MaxItems {
+			v.add(field, "maxItems", fmt.Sprintf("must contain at most %d items", *schema.MaxItems))
+		}
+		for i, item := range items {
+			v.validate(schema.Items, item, fmt.Sprintf("%s[%d]", field, i), true)
+		}
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			v.add(field, "type", "must be a string")
+			return
+		}
+		v.validateString(schema, s, field, required)
+	case "integer", "number":
+		n, ok := value.(json.Number)
+		if !ok {
+			v.add(field, "type", "must be "+article(schema.Type))
+			return
+		}
+		v.validateNumber(schema, n, field)
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			v.add(field, "type", "must be a boolean")
+		}
+	}
+}
+
+func (v *validator) validateObject(schema *Schema, obj map[string]interface{}, field string) {
+	required := make(map[string]bool, len(schema.Required))
+	for _, name := range schema.Required {
+		required[name] = true
+		if _, ok := obj[name]; !ok {
+			v.add(joinField(field, name), "required", "is required")
+		}
+	}
+
+	// 按字段名排序，保证错误顺序稳定
+	names := make([]string, 0, len(obj))
+	for name := range obj {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		value := obj[name]
+		prop, ok := schema.Properties[name]
+		if !ok {
+			if schema.AdditionalProperties != nil {
+				v.validate(schema.AdditionalProperties, value, joinField(field, name), true)
+			}
+			continue
+		}
+		v.validate(prop, value, joinField(field, name), required[name])
+	}
+}
+
+func (v *validator) validateString(schema *Schema, s, field string, required bool) {
+	length := len([]rune(s))
+	if schema.MinLength != nil && length < *schema.MinLength {
+		if *schema.MinLength == 1 {
+			v.add(field, "required", "must not be empty")
+		} else {
+			v.add(field, "minLength", fmt.Sprintf("must be at least %d characters", *schema.MinLength))
+		}
+		return
+	}
+	if schema.MaxLength != nil && length > *schema.MaxLength {
+		v.add(field, "maxLength", fmt.Sprintf("must be at most %d characters", *schema.MaxLength))
+		return
+	}
+	if s == "" && !required {
+		return
+	}
+
+	if len(schema.Enum) > 0 && !inEnum(schema.Enum, s) {
+		v.add(field, "enum", "must be one of "+joinEnum(schema.Enum))
+		return
+	}
+	if schema.Pattern != "" {
+		re, err := compilePattern(schema.Pattern)
+		if err == nil && !re.MatchString(s) {
+			v.add(field, "pattern", "must match "+schema.Pattern)
+			return
+		}
+	}
+
+	switch schema.Format {
+	case "uri":
+		u, err := url.ParseRequestURI(s)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			v.add(field, "format", "must be an absolute URL")
+		}
+	case "date-time":
+		if _, err := time.Parse(time.RFC3339, s); err != nil {
+			v.add(field, "format", "must be an RFC3339 timestamp")
+		}
+	}
+}
+
+func (v *validator) validateNumber(schema *Schema, n json.Number, field string) {
+	f, err := n.Float64()
+	if err != nil {
+		v.add(field, "type", "must be "+article(schema.Type))
+		return
+	}
+	if schema.Type == "integer" {
+		if _, err := n.Int64(); err != nil {
+			v.add(field, "type", "must be an integer")
+			return
+		}
+	}
+	if len(schema.Enum) > 0 && !inEnum(schema.Enum, n) {
+		v.add(field, "enum", "must be one of "+joinEnum(schema.Enum))
+		return
+	}
+	if schema.Minimum != nil && f < *schema.Minimum {
+		v.add(field, "minimum", "must be >= "+formatFloat(*schema.Minimum))
+	}
+	if schema.Maximum != nil && f > *schema.Maximum {
+		v.add(field, "maximum", "must be <= "+formatFloat(*schema.Maximum))
+	}
+}
+
+// parseParam 将参数字符串转换为与 JSON 解码一致的值
+func parseParam(schemaType, raw string) (interface{}, bool) {
+	switch schemaType {
+	case "integer", "number":
+		n := json.Number(raw)
+		if _, err := n.Float64(); err != nil {
+			return nil, false
+		}
+		return n, true
+	case "boolean":
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, false
+		}
+		return b, true
+	default:
+		return raw, true
+	}
+}
+
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	if re, ok := patternCache.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	patternCache.Store(pattern, re)
+	return re, nil
+}
+
+func inEnum(enum []interface{}, value interface{}) bool {
+	for _, option := range enum {
+		if fmt.Sprint(option) == fmt.Sprint(value) {
+			return true
+		}
+	}
+	return false
+}
+
+func joinEnum(enum []interface{}) string {
+	options := make([]string, len(enum))
+	for i, option := range enum {
+		options[i] = fmt.Sprint(option)
+	}
+	return strings.Join(options, ", ")
+}
+
+func joinField(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
+
+func article(schemaType string) string {
+	if schemaType == "integer" {
+		return "an integer"
+	}
+	return "a " + schemaType
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
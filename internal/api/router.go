@@ -1,37 +1,109 @@
 package api
 
 import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/azin/gdstudio-embed-service/internal/api/apierror"
 	"github.com/azin/gdstudio-embed-service/internal/api/handlers"
 	"github.com/azin/gdstudio-embed-service/internal/api/middleware"
+	"github.com/azin/gdstudio-embed-service/internal/auth"
 	"github.com/azin/gdstudio-embed-service/internal/config"
+	"github.com/azin/gdstudio-embed-service/internal/errcode"
+	"github.com/azin/gdstudio-embed-service/internal/health"
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
 )
 
 // SetupRouter 设置路由
-func SetupRouter(cfg *config.Config, jobHandler *handlers.JobHandler) *gin.Engine {
+func SetupRouter(
+	cfg *config.Config,
+	jobHandler *handlers.JobHandler,
+	eventHandler *handlers.EventHandler,
+	webhookHandler *handlers.WebhookHandler,
+	wsHandler *handlers.WSHandler,
+	adminHandler *handlers.AdminHandler,
+	checker *health.Checker,
+	keys *auth.Keyring,
+	logger *zap.Logger,
+) *gin.Engine {
 	// 设置 Gin 模式
 	gin.SetMode(cfg.Server.Mode)
 
-	r := gin.Default()
+	// 不使用 gin.Default()：访问日志与 panic 恢复统一走 zap
+	r := gin.New()
+	r.HandleMethodNotAllowed = true
+	r.NoRoute(func(c *gin.Context) {
+		apierror.Write(c, errcode.New(errcode.NotFound, "route not found"))
+	})
+	r.NoMethod(func(c *gin.Context) {
+		apierror.Write(c, errcode.New(errcode.MethodNotAllowed, "method not allowed"))
+	})
 
 	// 全局中间件
+	r.Use(middleware.RequestID())
+	r.Use(middleware.Tracing())
+	r.Use(middleware.RequestLogger(logger))
+	r.Use(middleware.Recovery(logger))
 	r.Use(middleware.CORS())
-	r.Use(middleware.RequestLogger())
 
 	// 健康检查（无需认证）
-	r.GET("/healthz", jobHandler.Health)
-	r.GET("/readyz", jobHandler.Health)
+	r.GET("/healthz", gin.WrapF(checker.LiveHandler))
+	r.GET("/readyz", gin.WrapF(checker.ReadyHandler))
+
+	// OpenAPI 文档（无需认证，便于客户端生成代码）
+	doc := BuildOpenAPI()
+	r.GET("/v1/openapi.json", func(c *gin.Context) {
+		c.JSON(http.StatusOK, doc)
+	})
 
 	// API v1 路由组
 	v1 := r.Group("/v1")
-	v1.Use(middleware.Auth(&cfg.Security))
+	v1.Use(middleware.Auth(keys))
+	v1.Use(middleware.Validate(doc))
 	{
-		// 任务管理
-		v1.POST("/jobs", jobHandler.Create)
-		v1.GET("/jobs", jobHandler.List)
-		v1.GET("/jobs/:id", jobHandler.Get)
-		v1.POST("/jobs/:id/retry", jobHandler.Retry)
-		v1.POST("/jobs/:id/cancel", jobHandler.Cancel)
+		canCreate := middleware.RequireScope(auth.ScopeJobsCreate)
+		canRead := middleware.RequireScope(auth.ScopeJobsRead)
+		canCancel := middleware.RequireScope(auth.ScopeJobsCancel)
+
+		// 任务管理（非管理员只能看到与操作自己创建的任务）
+		v1.POST("/jobs", canCreate, jobHandler.Create)
+		v1.GET("/jobs", canRead, jobHandler.List)
+		v1.GET("/jobs/:id", canRead, jobHandler.Get)
+		v1.POST("/jobs/:id/retry", canCreate, jobHandler.Retry)
+		v1.POST("/jobs/:id/cancel", canCancel, jobHandler.Cancel)
+		v1.POST("/jobs/:id/upgrade", canCreate, jobHandler.Upgrade)
+
+		// 任务事件流（SSE）
+		v1.GET("/jobs/:id/events", canRead, eventHandler.JobEvents)
+		v1.GET("/events", canRead, eventHandler.Events)
+
+		// WebSocket（握手时使用 X-API-Key 或 ?api_key= 认证；创建与取消按消息检查 scope）
+		v1.GET("/ws", canRead, wsHandler.Serve)
+
+		// Webhook
+		v1.GET("/webhooks/:id/deliveries", canRead, webhookHandler.Deliveries)
+
+		// 管理接口（需要 admin scope）
+		admin := v1.Group("/admin", middleware.RequireScope(auth.ScopeAdmin))
+		admin.POST("/maintenance/purge", adminHandler.Purge)
+		admin.GET("/queues", adminHandler.Queues)
+		admin.GET("/queues/:queue/tasks", adminHandler.QueueTasks)
+		admin.POST("/queues/:queue/pause", adminHandler.PauseQueue)
+		admin.POST("/queues/:queue/resume", adminHandler.ResumeQueue)
+		admin.DELETE("/queues/:queue/tasks/:task_id", adminHandler.DeleteTask)
+		admin.POST("/queues/:queue/tasks/:task_id/run", adminHandler.RunTask)
+		admin.POST("/queues/:queue/archived/run", adminHandler.RunArchived)
+		admin.DELETE("/queues/:queue/archived", adminHandler.DeleteArchived)
+		admin.POST("/reconcile", adminHandler.Reconcile)
+	}
+
+	// 文档与路由必须一致：新增 /v1 路由时需同步更新 BuildOpenAPI
+	for _, route := range r.Routes() {
+		if strings.HasPrefix(route.Path, "/v1/") && doc.Operation(route.Method, route.Path) == nil {
+			panic(fmt.Sprintf("route %s %s is missing from the OpenAPI document", route.Method, route.Path))
+		}
 	}
 
 	return r
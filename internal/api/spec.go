@@ -0,0 +1,380 @@
+package api
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/azin/gdstudio-embed-service/internal/api/apierror"
+	"github.com/azin/gdstudio-embed-service/internal/api/handlers"
+	"github.com/azin/gdstudio-embed-service/internal/api/openapi"
+	"github.com/azin/gdstudio-embed-service/internal/errcode"
+	"github.com/azin/gdstudio-embed-service/internal/model"
+	"github.com/azin/gdstudio-embed-service/internal/repository"
+	"github.com/azin/gdstudio-embed-service/internal/service/events"
+	"github.com/azin/gdstudio-embed-service/internal/service/maintenance"
+	"github.com/azin/gdstudio-embed-service/internal/service/queue"
+)
+
+// APIVersion 对外接口版本（OpenAPI info.version）
+const APIVersion = "1.0.0"
+
+// BuildOpenAPI 生成 /v1 接口的 OpenAPI 文档。请求与响应的 schema 直接由 handler 使用的
+// Go 类型反射生成；SetupRouter 会检查每个 /v1 路由都在文档中有对应条目。
+func BuildOpenAPI() *openapi.Document {
+	doc := openapi.New("gdstudio-embed-service", APIVersion)
+
+	// 错误响应的 code 字段列出全部错误码
+	errorSchema := doc.Resolve(doc.SchemaOf(apierror.Response{}))
+	for _, code := range errcode.All {
+		errorSchema.Properties["code"].Enum = append(errorSchema.Properties["code"].Enum, string(code))
+	}
+	// 每个需要认证的接口都按 API Key 的 scope 授权
+	errorResponses := func(statuses ...int) map[string]*openapi.Response {
+		responses := map[string]*openapi.Response{
+			"401": doc.JSONResponse("Missing or invalid API key (UNAUTHORIZED)", apierror.Response{}),
+			"403": doc.JSONResponse("API key lacks the required scope or access (FORBIDDEN)", apierror.Response{}),
+		}
+		for _, status := range statuses {
+			responses[strconv.Itoa(status)] = doc.JSONResponse(http.StatusText(status), apierror.Response{})
+		}
+		return responses
+	}
+	withResponse := func(responses map[string]*openapi.Response, code int, resp *openapi.Response) map[string]*openapi.Response {
+		responses[strconv.Itoa(code)] = resp
+		return responses
+	}
+	jobIDParam := pathParam("id", "Job ID")
+	limitParam := &openapi.Parameter{
+		Name:        "limit",
+		In:          "query",
+		Description: "Page size (values above 200 are clamped)",
+		Schema:      &openapi.Schema{Type: "integer", Minimum: floatPtr(1)},
+	}
+
+	doc.Add(http.MethodGet, "/v1/openapi.json", &openapi.Operation{
+		OperationID: "getOpenAPI",
+		Summary:     "This OpenAPI document",
+		Tags:        []string{"meta"},
+		Responses: map[string]*openapi.Response{
+			"200": {Description: "OpenAPI 3 document", Content: map[string]*openapi.MediaType{
+				"application/json": {Schema: &openapi.Schema{Type: "object"}},
+			}},
+		},
+		Security: openapi.NoAuth,
+	})
+
+	doc.Add(http.MethodPost, "/v1/jobs", &openapi.Operation{
+		OperationID: "createJob",
+		Summary:     "Create and enqueue a download job",
+		Description: "Requires the jobs:create scope; library_id and source must be allowed for the API key. " +
+			"Idempotent per API key on idempotency_key (defaults to source:track_id:library_id); an existing job is returned as-is.",
+		Tags:        []string{"jobs"},
+		RequestBody: doc.JSONBody(handlers.CreateJobRequest{}),
+		Responses: withResponse(errorResponses(http.StatusBadRequest, http.StatusInternalServerError, http.StatusServiceUnavailable),
+			http.StatusOK, doc.JSONResponse("Job created or already exists", handlers.CreateJobResponse{})),
+	})
+
+	doc.Add(http.MethodGet, "/v1/jobs", &openapi.Operation{
+		OperationID: "listJobs",
+		Summary:     "List jobs with filtering, sorting and cursor pagination",
+		Description: "Only jobs created by the calling API key are listed unless it has the admin scope.",
+		Tags:        []string{"jobs"},
+		Parameters: []*openapi.Parameter{
+			{
+				Name:        "status",
+				In:          "query",
+				Description: "Comma-separated or repeated job statuses",
+				Schema:      &openapi.Schema{Type: "array", Items: &openapi.Schema{Type: "string", Enum: enum(jobStatuses...)}},
+			},
+			{Name: "source", In: "query", Schema: &openapi.Schema{Type: "string"}},
+			{Name: "library_id", In: "query", Schema: &openapi.Schema{Type: "string"}},
+			{Name: "q", In: "query", Description: "Case-insensitive search in title, artist and album", Schema: &openapi.Schema{Type: "string"}},
+			timeParam("created_after"),
+			timeParam("created_before"),
+			timeParam("updated_after"),
+			timeParam("updated_before"),
+			{
+				Name:        "sort",
+				In:          "query",
+				Description: "Sort field, prefix with - for descending (default -created_at)",
+				Schema:      &openapi.Schema{Type: "string", Pattern: sortPattern()},
+			},
+			limitParam,
+			{Name: "cursor", In: "query", Description: "next_cursor from the previous page", Schema: &openapi.Schema{Type: "string"}},
+		},
+		Responses: withResponse(errorResponses(http.StatusBadRequest, http.StatusInternalServerError),
+			http.StatusOK, doc.JSONResponse("Job page", handlers.ListJobsResponse{})),
+	})
+
+	doc.Add(http.MethodGet, "/v1/jobs/{id}", &openapi.Operation{
+		OperationID: "getJob",
+		Summary:     "Get a job",
+		Tags:        []string{"jobs"},
+		Parameters:  []*openapi.Parameter{jobIDParam},
+		Responses: withResponse(errorResponses(http.StatusNotFound, http.StatusInternalServerError),
+			http.StatusOK, doc.JSONResponse("Job", model.Job{})),
+	})
+
+	doc.Add(http.MethodPost, "/v1/jobs/{id}/retry", &openapi.Operation{
+		OperationID: "retryJob",
+		Summary:     "Re-enqueue a failed job",
+		Tags:        []string{"jobs"},
+		Parameters:  []*openapi.Parameter{jobIDParam},
+		Responses: withResponse(errorResponses(http.StatusNotFound, http.StatusConflict, http.StatusInternalServerError, http.StatusServiceUnavailable),
+			http.StatusOK, doc.JSONResponse("Job queued for retry", handlers.JobActionResponse{})),
+	})
+
+	doc.Add(http.MethodPost, "/v1/jobs/{id}/cancel", &openapi.Operation{
+		OperationID: "cancelJob",
+		Summary:     "Cancel a job that has not finished",
+		Tags:        []string{"jobs"},
+		Parameters:  []*openapi.Parameter{jobIDParam},
+		Responses: withResponse(errorResponses(http.StatusNotFound, http.StatusConflict, http.StatusInternalServerError),
+			http.StatusOK, doc.JSONResponse("Job cancelled", handlers.JobActionResponse{})),
+	})
+
+	doc.Add(http.MethodPost, "/v1/jobs/{id}/upgrade", &openapi.Operation{
+		OperationID: "upgradeJob",
+		Summary:     "Queue a quality upgrade for a finished job",
+		Tags:        []string{"jobs"},
+		Parameters:  []*openapi.Parameter{jobIDParam},
+		Responses: withResponse(errorResponses(http.StatusNotFound, http.StatusConflict, http.StatusInternalServerError, http.StatusServiceUnavailable),
+			http.StatusAccepted, doc.JSONResponse("Upgrade queued", handlers.JobActionResponse{})),
+	})
+
+	doc.Add(http.MethodGet, "/v1/jobs/{id}/events", &openapi.Operation{
+		OperationID: "streamJobEvents",
+		Summary:     "Server-sent events for one job; closes when the job finishes",
+		Tags:        []string{"events"},
+		Parameters:  []*openapi.Parameter{jobIDParam},
+		Responses: withResponse(errorResponses(http.StatusNotFound, http.StatusInternalServerError, http.StatusServiceUnavailable),
+			http.StatusOK, eventStream(doc)),
+	})
+
+	doc.Add(http.MethodGet, "/v1/events", &openapi.Operation{
+		OperationID: "streamEvents",
+		Summary:     "Server-sent events for many jobs (all jobs when job_ids is omitted)",
+		Tags:        []string{"events"},
+		Parameters: []*openapi.Parameter{{
+			Name:        "job_ids",
+			In:          "query",
+			Description: "Comma-separated or repeated job IDs",
+			Schema:      &openapi.Schema{Type: "array", Items: &openapi.Schema{Type: "string"}, MaxItems: intPtr(100)},
+		}},
+		Responses: withResponse(errorResponses(http.StatusBadRequest, http.StatusServiceUnavailable),
+			http.StatusOK, eventStream(doc)),
+	})
+
+	// WebSocket 的消息结构无法用 OpenAPI 描述，这里登记信封类型供客户端生成代码
+	doc.SchemaOf(handlers.WSMessage{})
+	doc.Add(http.MethodGet, "/v1/ws", &openapi.Operation{
+		OperationID: "openWebSocket",
+		Summary:     "WebSocket API",
+		Description: "Upgrades to a WebSocket carrying WSMessage envelopes (v=1). " +
+			"Client types: job.create, job.subscribe, job.unsubscribe, job.cancel, ping. " +
+			"Server types: ack, error, job.event, notification, pong. " +
+			"job.create and job.cancel require the jobs:create and jobs:cancel scopes.",
+		Tags: []string{"events"},
+		Responses: withResponse(errorResponses(),
+			http.StatusSwitchingProtocols, &openapi.Response{Description: "Switching to the WebSocket protocol"}),
+	})
+
+	doc.Add(http.MethodGet, "/v1/webhooks/{id}/deliveries", &openapi.Operation{
+		OperationID: "listWebhookDeliveries",
+		Summary:     "Recent delivery attempts of a webhook",
+		Tags:        []string{"webhooks"},
+		Parameters:  []*openapi.Parameter{pathParam("id", "Webhook ID"), limitParam},
+		Responses: withResponse(errorResponses(http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError),
+			http.StatusOK, doc.JSONResponse("Delivery log", handlers.DeliveriesResponse{})),
+	})
+
+	queueParam := pathParam("queue", "Queue name")
+	taskIDParam := pathParam("task_id", "Task ID")
+	dryRunParam := func(description string) *openapi.Parameter {
+		return &openapi.Parameter{
+			Name:        "dry_run",
+			In:          "query",
+			Description: description,
+			Schema:      &openapi.Schema{Type: "boolean"},
+		}
+	}
+
+	doc.Add(http.MethodPost, "/v1/admin/maintenance/purge", &openapi.Operation{
+		OperationID: "purgeJobs",
+		Summary:     "Run the job retention purge now",
+		Tags:        []string{"admin"},
+		Parameters:  []*openapi.Parameter{dryRunParam("Only report what would be deleted")},
+		Responses: withResponse(errorResponses(http.StatusBadRequest, http.StatusInternalServerError),
+			http.StatusOK, doc.JSONResponse("Purge result", maintenance.PurgeResult{})),
+	})
+
+	doc.Add(http.MethodGet, "/v1/admin/queues", &openapi.Operation{
+		OperationID: "listQueues",
+		Summary:     "List task queues with per-state task counts",
+		Tags:        []string{"admin"},
+		Responses: withResponse(errorResponses(http.StatusServiceUnavailable),
+			http.StatusOK, doc.JSONResponse("Queues", handlers.QueuesResponse{})),
+	})
+
+	doc.Add(http.MethodGet, "/v1/admin/queues/{queue}/tasks", &openapi.Operation{
+		OperationID: "listQueueTasks",
+		Summary:     "List tasks in a queue by state",
+		Description: "job_id is read from the task payload for download, upgrade and webhook delivery tasks.",
+		Tags:        []string{"admin"},
+		Parameters: []*openapi.Parameter{
+			queueParam,
+			{
+				Name:        "state",
+				In:          "query",
+				Description: "Task state (default pending)",
+				Schema:      &openapi.Schema{Type: "string", Enum: enum(queue.States...)},
+			},
+			{Name: "page", In: "query", Description: "Page number starting at 1", Schema: &openapi.Schema{Type: "integer", Minimum: floatPtr(1)}},
+			{Name: "page_size", In: "query", Description: "Page size (values above 200 are clamped)", Schema: &openapi.Schema{Type: "integer", Minimum: floatPtr(1)}},
+		},
+		Responses: withResponse(errorResponses(http.StatusBadRequest, http.StatusNotFound, http.StatusServiceUnavailable),
+			http.StatusOK, doc.JSONResponse("Task page", handlers.QueueTasksResponse{})),
+	})
+
+	doc.Add(http.MethodPost, "/v1/admin/queues/{queue}/pause", &openapi.Operation{
+		OperationID: "pauseQueue",
+		Summary:     "Stop workers from taking tasks off a queue",
+		Tags:        []string{"admin"},
+		Parameters:  []*openapi.Parameter{queueParam},
+		Responses: withResponse(errorResponses(http.StatusNotFound, http.StatusServiceUnavailable),
+			http.StatusOK, doc.JSONResponse("Queue", queue.QueueInfo{})),
+	})
+
+	doc.Add(http.MethodPost, "/v1/admin/queues/{queue}/resume", &openapi.Operation{
+		OperationID: "resumeQueue",
+		Summary:     "Resume a paused queue",
+		Tags:        []string{"admin"},
+		Parameters:  []*openapi.Parameter{queueParam},
+		Responses: withResponse(errorResponses(http.StatusNotFound, http.StatusServiceUnavailable),
+			http.StatusOK, doc.JSONResponse("Queue", queue.QueueInfo{})),
+	})
+
+	doc.Add(http.MethodDelete, "/v1/admin/queues/{queue}/tasks/{task_id}", &openapi.Operation{
+		OperationID: "deleteQueueTask",
+		Summary:     "Delete a pending, scheduled, retry or archived task",
+		Tags:        []string{"admin"},
+		Parameters:  []*openapi.Parameter{queueParam, taskIDParam},
+		Responses: withResponse(errorResponses(http.StatusNotFound, http.StatusConflict, http.StatusServiceUnavailable),
+			http.StatusNoContent, &openapi.Response{Description: "Task deleted"}),
+	})
+
+	doc.Add(http.MethodPost, "/v1/admin/queues/{queue}/tasks/{task_id}/run", &openapi.Operation{
+		OperationID: "runQueueTask",
+		Summary:     "Run a scheduled, retry or archived task now",
+		Tags:        []string{"admin"},
+		Parameters:  []*openapi.Parameter{queueParam, taskIDParam},
+		Responses: withResponse(errorResponses(http.StatusNotFound, http.StatusConflict, http.StatusServiceUnavailable),
+			http.StatusOK, doc.JSONResponse("Task", queue.TaskInfo{})),
+	})
+
+	doc.Add(http.MethodPost, "/v1/admin/queues/{queue}/archived/run", &openapi.Operation{
+		OperationID: "runArchivedTasks",
+		Summary:     "Run every archived task in a queue",
+		Tags:        []string{"admin"},
+		Parameters:  []*openapi.Parameter{queueParam},
+		Responses: withResponse(errorResponses(http.StatusNotFound, http.StatusServiceUnavailable),
+			http.StatusOK, doc.JSONResponse("Tasks scheduled to run", queue.BulkResult{})),
+	})
+
+	doc.Add(http.MethodDelete, "/v1/admin/queues/{queue}/archived", &openapi.Operation{
+		OperationID: "deleteArchivedTasks",
+		Summary:     "Delete every archived task in a queue",
+		Tags:        []string{"admin"},
+		Parameters:  []*openapi.Parameter{queueParam},
+		Responses: withResponse(errorResponses(http.StatusNotFound, http.StatusServiceUnavailable),
+			http.StatusOK, doc.JSONResponse("Tasks deleted", queue.BulkResult{})),
+	})
+
+	doc.Add(http.MethodPost, "/v1/admin/reconcile", &openapi.Operation{
+		OperationID: "reconcileQueue",
+		Summary:     "Reconcile job status in the database against the task queue",
+		Description: "Unfinished jobs without a live download task are marked failed with TASK_LOST; " +
+			"queued download tasks of cancelled, done or deleted jobs are removed.",
+		Tags: []string{"admin"},
+		Parameters: []*openapi.Parameter{
+			dryRunParam("Only report what would change"),
+			{
+				Name:        "stale_after",
+				In:          "query",
+				Description: "Only consider jobs not updated for this long, as a Go duration (default 10m)",
+				Schema:      &openapi.Schema{Type: "string"},
+			},
+		},
+		Responses: withResponse(errorResponses(http.StatusBadRequest, http.StatusServiceUnavailable),
+			http.StatusOK, doc.JSONResponse("Reconcile result", queue.ReconcileResult{})),
+	})
+
+	return doc
+}
+
+var jobStatuses = []string{
+	model.JobStatusQueued,
+	model.JobStatusResolving,
+	model.JobStatusDownloading,
+	model.JobStatusTagging,
+	model.JobStatusMoving,
+	model.JobStatusScanning,
+	model.JobStatusDone,
+	model.JobStatusFailed,
+	model.JobStatusCancelled,
+}
+
+func eventStream(doc *openapi.Document) *openapi.Response {
+	return &openapi.Response{
+		Description: "text/event-stream; each data line is an Event",
+		Content: map[string]*openapi.MediaType{
+			"text/event-stream": {Schema: doc.SchemaOf(events.Event{})},
+		},
+	}
+}
+
+func pathParam(name, description string) *openapi.Parameter {
+	return &openapi.Parameter{
+		Name:        name,
+		In:          "path",
+		Description: description,
+		Required:    true,
+		Schema:      &openapi.Schema{Type: "string"},
+	}
+}
+
+func timeParam(name string) *openapi.Parameter {
+	return &openapi.Parameter{
+		Name:   name,
+		In:     "query",
+		Schema: &openapi.Schema{Type: "string", Format: "date-time"},
+	}
+}
+
+// sortPattern 由 repository.JobSortFields 生成 sort 参数的正则
+func sortPattern() string {
+	fields := make([]string, 0, len(repository.JobSortFields))
+	for field := range repository.JobSortFields {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return "^-?(" + strings.Join(fields, "|") + ")$"
+}
+
+func enum(values ...string) []interface{} {
+	out := make([]interface{}, len(values))
+	for i, v := range values {
+		out[i] = v
+	}
+	return out
+}
+
+func floatPtr(f float64) *float64 {
+	return &f
+}
+
+func intPtr(n int) *int {
+	return &n
+}
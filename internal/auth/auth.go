@@ -0,0 +1,201 @@
+// Package auth 定义 API Key 的角色、权限范围（scope）与可访问的曲库 / 音源。
+package auth
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/azin/gdstudio-embed-service/internal/config"
+	"github.com/azin/gdstudio-embed-service/internal/model"
+)
+
+// Scope 接口权限范围
+type Scope string
+
+const (
+	ScopeJobsCreate Scope = "jobs:create" // 创建、重试、升级任务
+	ScopeJobsRead   Scope = "jobs:read"   // 查询任务、订阅事件流
+	ScopeJobsCancel Scope = "jobs:cancel" // 取消任务
+	ScopeSearch     Scope = "search"      // 曲目搜索（预留，当前没有对外的搜索接口）
+	ScopeAdmin      Scope = "admin"       // 管理接口，并可查看与操作所有 Key 的任务
+)
+
+// AllScopes 全部 scope
+var AllScopes = []Scope{ScopeJobsCreate, ScopeJobsRead, ScopeJobsCancel, ScopeSearch, ScopeAdmin}
+
+// 角色
+const (
+	RoleAdmin    = "admin"
+	RoleClient   = "client"
+	RoleReadOnly = "readonly"
+)
+
+// DefaultRole 未配置 role 与 scopes 的 Key 使用的角色（与引入权限控制前的行为一致）
+const DefaultRole = RoleClient
+
+// Roles 角色对应的 scope 集合；Key 的 scopes 会在角色的基础上追加
+var Roles = map[string][]Scope{
+	RoleAdmin:    AllScopes,
+	RoleClient:   {ScopeJobsCreate, ScopeJobsRead, ScopeJobsCancel, ScopeSearch},
+	RoleReadOnly: {ScopeJobsRead},
+}
+
+// ContextKey gin context 中保存 *Principal 的键（api_key_name 仍单独保存，供日志与 webhook 使用）
+const ContextKey = "api_key"
+
+// Principal 一个已认证 API Key 的权限
+type Principal struct {
+	Name      string
+	Role      string
+	scopes    map[Scope]bool
+	libraries map[string]bool // nil 表示不限制
+	sources   map[string]bool // nil 表示不限制
+}
+
+// NewPrincipal 根据配置构造 Key 的权限，角色或 scope 不存在时返回错误
+func NewPrincipal(key config.APIKey) (*Principal, error) {
+	role := strings.ToLower(strings.TrimSpace(key.Role))
+	if role == "" && key.Admin {
+		role = RoleAdmin
+	}
+	if role == "" && len(key.Scopes) == 0 {
+		role = DefaultRole
+	}
+
+	p := &Principal{
+		Name:      key.Name,
+		Role:      role,
+		scopes:    make(map[Scope]bool),
+		libraries: stringSet(key.AllowedLibraries, false),
+		sources:   stringSet(key.AllowedSources, true),
+	}
+
+	if role != "" {
+		scopes, ok := Roles[role]
+		if !ok {
+			return nil, fmt.Errorf("api key %q: unknown role %q", key.Name, key.Role)
+		}
+		for _, scope := range scopes {
+			p.scopes[scope] = true
+		}
+	}
+	if key.Admin {
+		p.scopes[ScopeAdmin] = true
+	}
+	for _, raw := range key.Scopes {
+		scope := Scope(strings.ToLower(strings.TrimSpace(raw)))
+		if !knownScope(scope) {
+			return nil, fmt.Errorf("api key %q: unknown scope %q", key.Name, raw)
+		}
+		p.scopes[scope] = true
+	}
+
+	return p, nil
+}
+
+// Has 是否拥有 scope；admin 拥有全部 scope
+func (p *Principal) Has(scope Scope) bool {
+	return p.scopes[ScopeAdmin] || p.scopes[scope]
+}
+
+// IsAdmin 是否为管理员
+func (p *Principal) IsAdmin() bool {
+	return p.scopes[ScopeAdmin]
+}
+
+// Scopes 返回排序后的 scope 列表
+func (p *Principal) Scopes() []Scope {
+	scopes := make([]Scope, 0, len(p.scopes))
+	for scope := range p.scopes {
+		scopes = append(scopes, scope)
+	}
+	sort.Slice(scopes, func(i, j int) bool { return scopes[i] < scopes[j] })
+	return scopes
+}
+
+// AllowsLibrary 是否可以向该曲库提交任务
+func (p *Principal) AllowsLibrary(libraryID string) bool {
+	return p.libraries == nil || p.libraries[libraryID]
+}
+
+// AllowsSource 是否可以使用该音源（不区分大小写）
+func (p *Principal) AllowsSource(source string) bool {
+	return p.sources == nil || p.sources[strings.ToLower(source)]
+}
+
+// Owns 是否可以查看与操作该任务：管理员可访问全部任务，其余 Key 只能访问自己创建的任务
+func (p *Principal) Owns(job *model.Job) bool {
+	return p.IsAdmin() || (job.APIKeyName != "" && job.APIKeyName == p.Name)
+}
+
+// Keyring 按 Key 查找权限
+type Keyring struct {
+	keys map[string]*Principal
+}
+
+// NewKeyring 根据 security.api_keys 构造 Keyring，配置有误时返回错误。
+// 任务归属按 Key 名称匹配，名称为空或重复时拒绝启动。
+func NewKeyring(cfg *config.SecurityConfig) (*Keyring, error) {
+	keyring := &Keyring{keys: make(map[string]*Principal, len(cfg.APIKeys))}
+	names := make(map[string]bool, len(cfg.APIKeys))
+	for i, key := range cfg.APIKeys {
+		if strings.TrimSpace(key.Name) == "" {
+			return nil, fmt.Errorf("api key #%d: name is required", i+1)
+		}
+		if names[key.Name] {
+			return nil, fmt.Errorf("api key %q: duplicate name", key.Name)
+		}
+		names[key.Name] = true
+
+		principal, err := NewPrincipal(key)
+		if err != nil {
+			return nil, err
+		}
+		keyring.keys[key.Key] = principal
+	}
+	return keyring, nil
+}
+
+// NonAdminNames 返回非管理员 Key 的名称（已排序）
+func (k *Keyring) NonAdminNames() []string {
+	var names []string
+	for _, p := range k.keys {
+		if !p.IsAdmin() {
+			names = append(names, p.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Lookup 查找 Key 对应的权限
+func (k *Keyring) Lookup(key string) (*Principal, bool) {
+	principal, ok := k.keys[key]
+	return principal, ok
+}
+
+func knownScope(scope Scope) bool {
+	for _, s := range AllScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// stringSet 空列表返回 nil（不限制）
+func stringSet(values []string, lower bool) map[string]bool {
+	if len(values) == 0 {
+		return nil
+	}
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if lower {
+			v = strings.ToLower(v)
+		}
+		set[v] = true
+	}
+	return set
+}
@@ -0,0 +1,45 @@
+package auth
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/azin/gdstudio-embed-service/internal/config"
+)
+
+func TestNewKeyringNames(t *testing.T) {
+	tests := []struct {
+		name    string
+		keys    []config.APIKey
+		wantErr bool
+	}{
+		{name: "unique names", keys: []config.APIKey{{Key: "a", Name: "app"}, {Key: "b", Name: "admin", Role: RoleAdmin}}},
+		{name: "empty name", keys: []config.APIKey{{Key: "a", Name: ""}}, wantErr: true},
+		{name: "blank name", keys: []config.APIKey{{Key: "a", Name: "  "}}, wantErr: true},
+		{name: "duplicate name", keys: []config.APIKey{{Key: "a", Name: "app"}, {Key: "b", Name: "app"}}, wantErr: true},
+		{name: "unknown role", keys: []config.APIKey{{Key: "a", Name: "app", Role: "root"}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewKeyring(&config.SecurityConfig{APIKeys: tt.keys})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewKeyring() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestKeyringNonAdminNames(t *testing.T) {
+	keyring, err := NewKeyring(&config.SecurityConfig{APIKeys: []config.APIKey{
+		{Key: "1", Name: "web"},
+		{Key: "2", Name: "admin", Role: RoleAdmin},
+		{Key: "3", Name: "legacy-admin", Admin: true},
+		{Key: "4", Name: "dashboard", Role: RoleReadOnly},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := keyring.NonAdminNames(), []string{"dashboard", "web"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("NonAdminNames() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,26 @@
+// Package buildinfo 保存构建时通过 -ldflags 注入的版本信息与进程启动时间。
+package buildinfo
+
+import "time"
+
+// 构建时注入，例如：
+//
+//	go build -ldflags "-X github.com/azin/gdstudio-embed-service/internal/buildinfo.Version=v1.2.0"
+var (
+	Version   = "dev"
+	CommitSHA = "unknown"
+	BuildDate = "unknown"
+)
+
+// startTime 进程启动时间（包初始化时记录）
+var startTime = time.Now()
+
+// StartTime 返回进程启动时间
+func StartTime() time.Time {
+	return startTime
+}
+
+// Uptime 返回进程已运行时长
+func Uptime() time.Duration {
+	return time.Since(startTime)
+}
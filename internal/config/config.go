@@ -16,17 +16,29 @@ type Config struct {
 	GDStudio  GDStudioConfig  `mapstructure:"gdstudio"`
 	Navidrome NavidromeConfig `mapstructure:"navidrome"`
 	Storage   StorageConfig   `mapstructure:"storage"`
+	Tagger    TaggerConfig    `mapstructure:"tagger"`
+	Library   LibraryConfig   `mapstructure:"library"`
+	Upgrade   UpgradeConfig   `mapstructure:"upgrade"`
+	Retention RetentionConfig `mapstructure:"retention"`
+	Webhook   WebhookConfig   `mapstructure:"webhook"`
 	Worker    WorkerConfig    `mapstructure:"worker"`
 	Database  DatabaseConfig  `mapstructure:"database"`
 	Redis     RedisConfig     `mapstructure:"redis"`
 	Security  SecurityConfig  `mapstructure:"security"`
 	Logging   LoggingConfig   `mapstructure:"logging"`
 	Metrics   MetricsConfig   `mapstructure:"metrics"`
+	Tracing   TracingConfig   `mapstructure:"tracing"`
+	Health    HealthConfig    `mapstructure:"health"`
 }
 
 type ServerConfig struct {
-	Port int    `mapstructure:"port"`
-	Mode string `mapstructure:"mode"` // debug / release
+	Port              int           `mapstructure:"port"`
+	Mode              string        `mapstructure:"mode"` // debug / release
+	ReadTimeout       time.Duration `mapstructure:"read_timeout"`
+	ReadHeaderTimeout time.Duration `mapstructure:"read_header_timeout"`
+	WriteTimeout      time.Duration `mapstructure:"write_timeout"` // SSE 与 WebSocket 长连接不受此限制
+	IdleTimeout       time.Duration `mapstructure:"idle_timeout"`
+	ShutdownTimeout   time.Duration `mapstructure:"shutdown_timeout"` // 收到退出信号后等待进行中请求完成的时长
 }
 
 type GDStudioConfig struct {
@@ -49,6 +61,81 @@ type StorageConfig struct {
 	MusicDir          string   `mapstructure:"music_dir"`
 	PathTemplate      string   `mapstructure:"path_template"`
 	AllowedExtensions []string `mapstructure:"allowed_extensions"`
+	CollisionPolicy   string   `mapstructure:"collision_policy"` // skip / overwrite / suffix / keep_better
+	SanitizeProfile   string   `mapstructure:"sanitize_profile"` // posix / windows-safe / ascii
+
+	// 曲库输出文件的权限与属主（uid/gid 为 -1 时不修改）
+	FileMode     string `mapstructure:"file_mode"` // 八进制，例如 "0644"
+	DirMode      string `mapstructure:"dir_mode"`  // 八进制，例如 "0755"
+	UID          int    `mapstructure:"uid"`
+	GID          int    `mapstructure:"gid"`
+	InheritGroup bool   `mapstructure:"inherit_group"` // 继承父目录属组并为新目录设置 setgid
+}
+
+type TaggerConfig struct {
+	Lyrics LyricsConfig `mapstructure:"lyrics"`
+	LRC    LRCConfig    `mapstructure:"lrc"`
+	Cover  CoverConfig  `mapstructure:"cover"`
+}
+
+// CoverConfig 嵌入封面处理配置
+type CoverConfig struct {
+	MaxDimension int `mapstructure:"max_dimension"` // 嵌入封面最长边（像素）
+	MaxBytes     int `mapstructure:"max_bytes"`     // 嵌入封面字节预算
+	JPEGQuality  int `mapstructure:"jpeg_quality"`
+
+	// 专辑目录封面：按顺序检查已有文件，缺失时以首个名称写入
+	FolderNames     []string `mapstructure:"folder_names"`
+	FolderOverwrite bool     `mapstructure:"folder_overwrite"` // 允许替换用户放置的封面（仅在新封面更大时）
+}
+
+type LyricsConfig struct {
+	EmbedSynced      bool `mapstructure:"embed_synced"`      // 写入 SYLT 同步歌词
+	EmbedTranslation bool `mapstructure:"embed_translation"` // 写入翻译歌词（独立 USLT/SYLT）
+	MergeTranslation bool `mapstructure:"merge_translation"` // 原文与翻译合并为双语 LRC 写入同一帧
+}
+
+// LRCConfig .lrc 歌词文件生成配置
+type LRCConfig struct {
+	Mode         string                      `mapstructure:"mode"`          // original / translation / merged / separate
+	StripCredits bool                        `mapstructure:"strip_credits"` // 去掉 "作词/作曲" 等署名行
+	Libraries    map[string]LRCLibraryConfig `mapstructure:"libraries"`     // 按 library_id 覆盖（键不区分大小写）
+}
+
+// LRCLibraryConfig 单个曲库的 LRC 配置，未设置的字段沿用全局配置
+type LRCLibraryConfig struct {
+	Mode         string `mapstructure:"mode"`
+	StripCredits *bool  `mapstructure:"strip_credits"`
+}
+
+// LRCModes 支持的 LRC 输出模式
+var LRCModes = []string{"original", "translation", "merged", "separate"}
+
+// SanitizeProfiles 支持的文件名清理策略（见 filename 包）
+var SanitizeProfiles = []string{"posix", "windows-safe", "ascii"}
+
+// LibraryConfig 曲库索引与下载前查重配置
+type LibraryConfig struct {
+	IndexEnabled      bool          `mapstructure:"index_enabled"`
+	IndexInterval     time.Duration `mapstructure:"index_interval"`
+	DuplicatePolicy   string        `mapstructure:"duplicate_policy"`   // skip / replace_if_better / keep_both，可被任务参数覆盖
+	DurationTolerance time.Duration `mapstructure:"duration_tolerance"` // 时长匹配容差
+}
+
+// UpgradeConfig 低码率曲目的音质升级配置
+type UpgradeConfig struct {
+	Enabled       bool          `mapstructure:"enabled"`        // 启用周期扫描
+	SweepInterval time.Duration `mapstructure:"sweep_interval"` // 扫描间隔
+	RecheckAfter  time.Duration `mapstructure:"recheck_after"`  // 同一任务两次尝试的最小间隔
+	BatchSize     int           `mapstructure:"batch_size"`     // 每次扫描最多入队的任务数
+}
+
+// RetentionConfig 任务记录保留与清理配置
+type RetentionConfig struct {
+	Enabled         bool          `mapstructure:"enabled"`           // 启用周期清理
+	Interval        time.Duration `mapstructure:"interval"`          // 清理间隔
+	JobRetention    time.Duration `mapstructure:"job_retention"`     // 已结束任务（done/failed/cancelled）保留时长，超过后软删除
+	HardDeleteAfter time.Duration `mapstructure:"hard_delete_after"` // 软删除后再过多久从数据库中彻底删除
 }
 
 type WorkerConfig struct {
@@ -59,6 +146,13 @@ type WorkerConfig struct {
 	ScanTimeout      time.Duration `mapstructure:"scan_timeout"`
 	RetryMaxAttempts int           `mapstructure:"retry_max_attempts"`
 	RetryDelay       time.Duration `mapstructure:"retry_delay"`
+
+	// 是否在本副本运行周期任务（曲库索引扫描、音质升级扫描与记录清理的调度）。多个 worker 副本时只在一个副本开启，避免重复执行
+	PeriodicTasks bool `mapstructure:"periodic_tasks"`
+
+	// 工作目录清理
+	WorkDirGCInterval   time.Duration `mapstructure:"workdir_gc_interval"`
+	FailedWorkRetention time.Duration `mapstructure:"failed_work_retention"` // 失败任务的工作目录保留时长，便于排查
 }
 
 type DatabaseConfig struct {
@@ -84,6 +178,31 @@ type SecurityConfig struct {
 type APIKey struct {
 	Key  string `mapstructure:"key"`
 	Name string `mapstructure:"name"`
+
+	// 角色（admin / client / readonly）与额外的 scope；都未配置时为 client
+	Role   string   `mapstructure:"role"`
+	Scopes []string `mapstructure:"scopes"` // jobs:create / jobs:read / jobs:cancel / search / admin
+
+	// Deprecated: 使用 role: admin。为兼容旧配置保留，为 true 时等同于授予 admin scope
+	Admin bool `mapstructure:"admin"`
+
+	// 允许提交任务的曲库与音源，为空时不限制
+	AllowedLibraries []string `mapstructure:"allowed_libraries"`
+	AllowedSources   []string `mapstructure:"allowed_sources"`
+
+	// 该 Key 创建的任务的生命周期事件推送地址与签名密钥（可选）
+	WebhookURL    string `mapstructure:"webhook_url"`
+	WebhookSecret string `mapstructure:"webhook_secret"`
+}
+
+// WebhookConfig Webhook 投递配置
+type WebhookConfig struct {
+	Secret       string        `mapstructure:"secret"`        // 任务 callback_url 使用的签名密钥，为空时不接受 callback_url
+	AllowedHosts []string      `mapstructure:"allowed_hosts"` // callback_url 允许的主机（"*.example.com" 匹配子域名），为空时不接受 callback_url
+	Timeout      time.Duration `mapstructure:"timeout"`       // 单次请求超时
+	MaxRetries   int           `mapstructure:"max_retries"`   // 失败后的最大重试次数，0 表示不重试
+	BackoffBase  time.Duration `mapstructure:"backoff_base"`  // 首次重试间隔，之后指数增长
+	BackoffMax   time.Duration `mapstructure:"backoff_max"`   // 重试间隔上限
 }
 
 type RateLimit struct {
@@ -104,6 +223,26 @@ type MetricsConfig struct {
 	Path    string `mapstructure:"path"`
 }
 
+// TracingConfig OpenTelemetry 链路追踪配置
+type TracingConfig struct {
+	Enabled     bool    `mapstructure:"enabled"`
+	Exporter    string  `mapstructure:"exporter"`     // otlp / stdout / file
+	Endpoint    string  `mapstructure:"endpoint"`     // OTLP/HTTP 地址（host:port），为空时读取 OTEL_EXPORTER_OTLP_ENDPOINT
+	Insecure    bool    `mapstructure:"insecure"`     // OTLP 使用 HTTP 而非 HTTPS
+	FilePath    string  `mapstructure:"file_path"`    // exporter 为 file 时写入的文件（每行一个 JSON span）
+	ServiceName string  `mapstructure:"service_name"` // 为空时 API 与 worker 分别使用 embed-service-api / embed-service-worker
+	SampleRatio float64 `mapstructure:"sample_ratio"` // 采样比例 0-1，默认 1
+}
+
+// HealthConfig 健康检查配置
+type HealthConfig struct {
+	Timeout        time.Duration `mapstructure:"timeout"`         // 单项检查超时
+	CacheTTL       time.Duration `mapstructure:"cache_ttl"`       // 外部依赖（GDStudio / Navidrome）检查结果缓存时长
+	CheckGDStudio  bool          `mapstructure:"check_gdstudio"`  // 就绪检查是否探测 GDStudio
+	CheckNavidrome bool          `mapstructure:"check_navidrome"` // 就绪检查是否探测 Navidrome
+	WorkerPort     int           `mapstructure:"worker_port"`     // worker 健康检查端口，0 表示不监听
+}
+
 // Load 加载配置
 func Load(configPath string) (*Config, error) {
 	v := viper.New()
@@ -131,8 +270,24 @@ func Load(configPath string) (*Config, error) {
 	v.BindEnv("redis.url", "REDIS_URL")
 	v.BindEnv("worker.max_concurrent", "MAX_CONCURRENT_JOBS")
 	v.BindEnv("worker.download_timeout", "DOWNLOAD_TIMEOUT")
+	v.BindEnv("worker.periodic_tasks", "WORKER_PERIODIC_TASKS")
 	v.BindEnv("logging.level", "LOG_LEVEL")
 
+	// 布尔开关无法在 setDefaults 中区分"未配置"与 false，在此设置默认值。
+	v.SetDefault("tagger.lyrics.embed_synced", true)
+	v.SetDefault("tagger.lyrics.embed_translation", true)
+	v.SetDefault("library.index_enabled", true)
+	v.SetDefault("worker.periodic_tasks", true)
+	// uid/gid 为 0 是合法值（root），用 -1 表示"不修改"。
+	v.SetDefault("storage.uid", -1)
+	v.SetDefault("storage.gid", -1)
+	// 采样比例 0 表示不采样，同样需要区分"未配置"。
+	v.SetDefault("tracing.sample_ratio", 1.0)
+	v.SetDefault("health.check_navidrome", true)
+	v.SetDefault("health.worker_port", 8081)
+	// max_retries 为 0 表示不重试
+	v.SetDefault("webhook.max_retries", 8)
+
 	// 读取配置文件
 	if err := v.ReadInConfig(); err != nil {
 		return nil, fmt.Errorf("failed to read config: %w", err)
@@ -140,14 +295,33 @@ func Load(configPath string) (*Config, error) {
 
 	// 兼容纯数字秒值（例如 DOWNLOAD_TIMEOUT=600）
 	normalizeDurationValues(v, []string{
+		"server.read_timeout",
+		"server.read_header_timeout",
+		"server.write_timeout",
+		"server.idle_timeout",
+		"server.shutdown_timeout",
 		"gdstudio.timeout",
 		"navidrome.scan_timeout",
+		"library.index_interval",
+		"library.duration_tolerance",
+		"upgrade.sweep_interval",
+		"upgrade.recheck_after",
+		"retention.interval",
+		"retention.job_retention",
+		"retention.hard_delete_after",
+		"webhook.timeout",
+		"webhook.backoff_base",
+		"webhook.backoff_max",
 		"worker.download_timeout",
 		"worker.tag_write_timeout",
 		"worker.move_timeout",
 		"worker.scan_timeout",
 		"worker.retry_delay",
+		"worker.workdir_gc_interval",
+		"worker.failed_work_retention",
 		"database.conn_max_lifetime",
+		"health.timeout",
+		"health.cache_ttl",
 	})
 
 	// 解析配置
@@ -159,8 +333,16 @@ func Load(configPath string) (*Config, error) {
 	// 应用默认值
 	setDefaults(&cfg)
 
-	// 从环境变量覆盖首个 API Key，便于 Docker Compose 从 .env 注入。
+	// 从环境变量覆盖首个 API Key、追加管理员 Key，便于 Docker Compose 从 .env 注入。
 	applyAPIKeyOverride(v, &cfg)
+	applyAdminKeyOverride(v, &cfg)
+
+	if err := validateLRC(&cfg.Tagger.LRC); err != nil {
+		return nil, err
+	}
+	if err := validateSanitizeProfile(&cfg.Storage); err != nil {
+		return nil, err
+	}
 
 	// 兼容 REDIS_URL 同时支持 host:port 与 redis://host:port/db
 	if err := normalizeRedisAddress(&cfg.Redis); err != nil {
@@ -170,6 +352,38 @@ func Load(configPath string) (*Config, error) {
 	return &cfg, nil
 }
 
+// validateLRC 检查全局与各曲库的 LRC 模式，未知模式拒绝启动而不是静默退回 original
+func validateLRC(cfg *LRCConfig) error {
+	valid := func(mode string) bool {
+		for _, m := range LRCModes {
+			if strings.EqualFold(mode, m) {
+				return true
+			}
+		}
+		return false
+	}
+	if !valid(cfg.Mode) {
+		return fmt.Errorf("invalid tagger.lrc.mode %q (expected one of %s)", cfg.Mode, strings.Join(LRCModes, ", "))
+	}
+	for id, lib := range cfg.Libraries {
+		if lib.Mode != "" && !valid(lib.Mode) {
+			return fmt.Errorf("invalid tagger.lrc.libraries.%s.mode %q (expected one of %s)", id, lib.Mode, strings.Join(LRCModes, ", "))
+		}
+	}
+	return nil
+}
+
+// validateSanitizeProfile 检查文件名清理策略，拼写错误时拒绝启动而不是静默按 windows-safe 处理
+func validateSanitizeProfile(cfg *StorageConfig) error {
+	cfg.SanitizeProfile = strings.ToLower(strings.TrimSpace(cfg.SanitizeProfile))
+	for _, profile := range SanitizeProfiles {
+		if cfg.SanitizeProfile == profile {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid storage.sanitize_profile %q (expected one of %s)", cfg.SanitizeProfile, strings.Join(SanitizeProfiles, ", "))
+}
+
 func setDefaults(cfg *Config) {
 	if cfg.Server.Port == 0 {
 		cfg.Server.Port = 8080
@@ -177,18 +391,114 @@ func setDefaults(cfg *Config) {
 	if cfg.Server.Mode == "" {
 		cfg.Server.Mode = "release"
 	}
+	if cfg.Server.ReadTimeout == 0 {
+		cfg.Server.ReadTimeout = 30 * time.Second
+	}
+	if cfg.Server.ReadHeaderTimeout == 0 {
+		cfg.Server.ReadHeaderTimeout = 10 * time.Second
+	}
+	if cfg.Server.WriteTimeout == 0 {
+		cfg.Server.WriteTimeout = 60 * time.Second
+	}
+	if cfg.Server.IdleTimeout == 0 {
+		cfg.Server.IdleTimeout = 120 * time.Second
+	}
+	if cfg.Server.ShutdownTimeout == 0 {
+		cfg.Server.ShutdownTimeout = 30 * time.Second
+	}
 	if cfg.GDStudio.Timeout == 0 {
 		cfg.GDStudio.Timeout = 15 * time.Second
 	}
 	if cfg.Navidrome.APIVersion == "" {
 		cfg.Navidrome.APIVersion = "1.16.1"
 	}
+	if cfg.Storage.CollisionPolicy == "" {
+		cfg.Storage.CollisionPolicy = "suffix"
+	}
+	if cfg.Storage.FileMode == "" {
+		cfg.Storage.FileMode = "0644"
+	}
+	if cfg.Storage.DirMode == "" {
+		cfg.Storage.DirMode = "0755"
+	}
+	if cfg.Storage.SanitizeProfile == "" {
+		cfg.Storage.SanitizeProfile = "windows-safe"
+	}
+	if cfg.Tagger.LRC.Mode == "" {
+		cfg.Tagger.LRC.Mode = "original"
+	}
+	if cfg.Tagger.Cover.MaxDimension == 0 {
+		cfg.Tagger.Cover.MaxDimension = 1000
+	}
+	if cfg.Tagger.Cover.MaxBytes == 0 {
+		cfg.Tagger.Cover.MaxBytes = 500 * 1024
+	}
+	if cfg.Tagger.Cover.JPEGQuality == 0 {
+		cfg.Tagger.Cover.JPEGQuality = 90
+	}
+	if len(cfg.Tagger.Cover.FolderNames) == 0 {
+		cfg.Tagger.Cover.FolderNames = []string{"cover.jpg", "folder.jpg"}
+	}
+	if cfg.Library.IndexInterval == 0 {
+		cfg.Library.IndexInterval = 6 * time.Hour
+	}
+	if cfg.Library.DuplicatePolicy == "" {
+		cfg.Library.DuplicatePolicy = "skip"
+	}
+	if cfg.Library.DurationTolerance == 0 {
+		cfg.Library.DurationTolerance = 3 * time.Second
+	}
+	if cfg.Upgrade.SweepInterval == 0 {
+		cfg.Upgrade.SweepInterval = 24 * time.Hour
+	}
+	if cfg.Upgrade.RecheckAfter == 0 {
+		cfg.Upgrade.RecheckAfter = 7 * 24 * time.Hour
+	}
+	if cfg.Upgrade.BatchSize == 0 {
+		cfg.Upgrade.BatchSize = 20
+	}
+	if cfg.Retention.Interval == 0 {
+		cfg.Retention.Interval = 24 * time.Hour
+	}
+	if cfg.Retention.JobRetention == 0 {
+		cfg.Retention.JobRetention = 30 * 24 * time.Hour
+	}
+	if cfg.Retention.HardDeleteAfter == 0 {
+		cfg.Retention.HardDeleteAfter = 7 * 24 * time.Hour
+	}
+	if cfg.Webhook.Timeout == 0 {
+		cfg.Webhook.Timeout = 10 * time.Second
+	}
+	if cfg.Webhook.BackoffBase == 0 {
+		cfg.Webhook.BackoffBase = 10 * time.Second
+	}
+	if cfg.Webhook.BackoffMax == 0 {
+		cfg.Webhook.BackoffMax = time.Hour
+	}
+	if cfg.Tracing.Exporter == "" {
+		cfg.Tracing.Exporter = "otlp"
+	}
+	if cfg.Tracing.FilePath == "" {
+		cfg.Tracing.FilePath = "/var/log/embed-service-traces.jsonl"
+	}
+	if cfg.Health.Timeout == 0 {
+		cfg.Health.Timeout = 3 * time.Second
+	}
+	if cfg.Health.CacheTTL == 0 {
+		cfg.Health.CacheTTL = 30 * time.Second
+	}
 	if cfg.Worker.MaxConcurrent == 0 {
 		cfg.Worker.MaxConcurrent = 3
 	}
 	if cfg.Worker.DownloadTimeout == 0 {
 		cfg.Worker.DownloadTimeout = 600 * time.Second
 	}
+	if cfg.Worker.WorkDirGCInterval == 0 {
+		cfg.Worker.WorkDirGCInterval = time.Hour
+	}
+	if cfg.Worker.FailedWorkRetention == 0 {
+		cfg.Worker.FailedWorkRetention = 72 * time.Hour
+	}
 	if cfg.Logging.Level == "" {
 		cfg.Logging.Level = "info"
 	}
@@ -228,6 +538,15 @@ func applyAPIKeyOverride(v *viper.Viper, cfg *Config) {
 	}
 }
 
+// applyAdminKeyOverride 从 ADMIN_API_KEY 追加一个管理员 Key
+func applyAdminKeyOverride(v *viper.Viper, cfg *Config) {
+	adminKey := strings.TrimSpace(v.GetString("ADMIN_API_KEY"))
+	if adminKey == "" {
+		return
+	}
+	cfg.Security.APIKeys = append(cfg.Security.APIKeys, APIKey{Key: adminKey, Name: "admin", Role: "admin"})
+}
+
 func normalizeDurationValues(v *viper.Viper, keys []string) {
 	for _, key := range keys {
 		raw := strings.TrimSpace(v.GetString(key))
@@ -0,0 +1,118 @@
+// Package errcode 定义对外稳定的错误码。API 错误响应与任务失败信息（Job.Error）使用同一套错误码。
+package errcode
+
+import (
+	"errors"
+	"strings"
+)
+
+// Code 机器可读的错误码，一经发布不再修改
+type Code string
+
+// 请求错误
+const (
+	InvalidRequest   Code = "INVALID_REQUEST"   // 请求格式错误（如 JSON 无法解析）
+	ValidationFailed Code = "VALIDATION_FAILED" // 参数校验失败，details 为字段级错误
+	Unauthorized     Code = "UNAUTHORIZED"      // 缺少或无效的 API Key
+	Forbidden        Code = "FORBIDDEN"         // API Key 无权访问该接口
+	NotFound         Code = "NOT_FOUND"         // 路由不存在
+	MethodNotAllowed Code = "METHOD_NOT_ALLOWED"
+	JobNotFound      Code = "JOB_NOT_FOUND"
+	WebhookNotFound  Code = "WEBHOOK_NOT_FOUND"
+	QueueNotFound    Code = "QUEUE_NOT_FOUND"
+	TaskNotFound     Code = "TASK_NOT_FOUND" // 队列中不存在该任务
+	InvalidState     Code = "INVALID_STATE"  // 任务当前状态不允许该操作
+	AlreadyQueued    Code = "ALREADY_QUEUED" // 相同的后台任务已在队列中
+)
+
+// 服务端错误
+const (
+	Internal          Code = "INTERNAL_ERROR"
+	QueueUnavailable  Code = "QUEUE_UNAVAILABLE"  // 无法写入任务队列
+	StreamUnavailable Code = "STREAM_UNAVAILABLE" // 无法订阅事件流
+	ShuttingDown      Code = "SHUTTING_DOWN"      // 服务正在关闭，不再接收新任务
+)
+
+// 任务失败（写入 Job.Error）
+const (
+	ResolveFailed  Code = "RESOLVE_FAILED"  // 解析下载地址失败
+	DownloadFailed Code = "DOWNLOAD_FAILED" // 下载音频失败
+	TaggingFailed  Code = "TAGGING_FAILED"  // 写入标签失败
+	MoveFailed     Code = "MOVE_FAILED"     // 移动到曲库失败
+	ScanFailed     Code = "SCAN_FAILED"     // 触发曲库扫描失败
+	TaskLost       Code = "TASK_LOST"       // 队列中已没有对应的存活任务（由队列对账标记）
+)
+
+// All 全部错误码，用于生成 OpenAPI 文档
+var All = []Code{
+	InvalidRequest, ValidationFailed, Unauthorized, Forbidden, NotFound, MethodNotAllowed,
+	JobNotFound, WebhookNotFound, QueueNotFound, TaskNotFound, InvalidState, AlreadyQueued,
+	Internal, QueueUnavailable, StreamUnavailable, ShuttingDown,
+	ResolveFailed, DownloadFailed, TaggingFailed, MoveFailed, ScanFailed, TaskLost,
+}
+
+// Error 带错误码的错误。Message 面向调用方，Err 为内部原因（不返回给 API 调用方）。
+type Error struct {
+	Code    Code
+	Message string
+	Details interface{}
+	Err     error
+}
+
+// New 创建错误
+func New(code Code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+// Wrap 以 code 包装内部错误，err 为 nil 时等同于 New；message 可为空
+func Wrap(code Code, message string, err error) *Error {
+	return &Error{Code: code, Message: message, Err: err}
+}
+
+// WithDetails 附加 details
+func (e *Error) WithDetails(details interface{}) *Error {
+	e.Details = details
+	return e
+}
+
+// Error 格式为 "CODE: message[: cause]"，写入 Job.Error 时客户端可用 Split 解析
+func (e *Error) Error() string {
+	s := string(e.Code)
+	if e.Message != "" {
+		s += ": " + e.Message
+	}
+	if e.Err != nil {
+		s += ": " + e.Err.Error()
+	}
+	return s
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// As 从错误链中取出 *Error
+func As(err error) (*Error, bool) {
+	var e *Error
+	if errors.As(err, &e) {
+		return e, true
+	}
+	return nil, false
+}
+
+// Of 返回错误链中的错误码，没有时返回 fallback
+func Of(err error, fallback Code) Code {
+	if e, ok := As(err); ok {
+		return e.Code
+	}
+	return fallback
+}
+
+// Split 将 Error() 格式的字符串拆分为错误码与描述；不含错误码时 code 为空
+func Split(s string) (Code, string) {
+	prefix, rest, ok := strings.Cut(s, ": ")
+	if !ok || prefix == "" || strings.ToUpper(prefix) != prefix || strings.ContainsAny(prefix, " .") {
+		return "", s
+	}
+	return Code(prefix), rest
+}
@@ -0,0 +1,56 @@
+package health
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/azin/gdstudio-embed-service/internal/config"
+	"github.com/azin/gdstudio-embed-service/internal/service/gdstudio"
+	"github.com/azin/gdstudio-embed-service/internal/service/navidrome"
+	"github.com/hibiken/asynq"
+	"gorm.io/gorm"
+)
+
+// NewStandardChecker 创建 API 与 worker 共用的就绪检查：
+// 数据库与 Redis 为关键依赖；GDStudio / Navidrome 按配置探测，结果缓存，失败只标记 degraded
+func NewStandardChecker(cfg *config.HealthConfig, db *gorm.DB, inspector *asynq.Inspector, gdClient *gdstudio.Client, naviClient *navidrome.Client) *Checker {
+	checker := NewChecker(cfg.Timeout)
+	checker.Add("database", true, 0, Database(db))
+	checker.Add("redis", true, 0, Redis(inspector))
+	if cfg.CheckGDStudio {
+		checker.Add("gdstudio", false, cfg.CacheTTL, gdClient.Ping)
+	}
+	if cfg.CheckNavidrome {
+		checker.Add("navidrome", false, cfg.CacheTTL, naviClient.Ping)
+	}
+	return checker
+}
+
+// Database 检查数据库连接
+func Database(db *gorm.DB) CheckFunc {
+	return func(ctx context.Context) error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return fmt.Errorf("failed to get sql.DB: %w", err)
+		}
+		return sqlDB.PingContext(ctx)
+	}
+}
+
+// Redis 通过 asynq inspector 检查队列所在的 Redis。
+// inspector 不接受 context，超时由 Redis 客户端自身的读写超时兜底
+func Redis(inspector *asynq.Inspector) CheckFunc {
+	return func(ctx context.Context) error {
+		errCh := make(chan error, 1)
+		go func() {
+			_, err := inspector.Queues()
+			errCh <- err
+		}()
+		select {
+		case err := <-errCh:
+			return err
+		case <-ctx.Done():
+			return fmt.Errorf("redis check timed out: %w", ctx.Err())
+		}
+	}
+}
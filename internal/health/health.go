@@ -0,0 +1,181 @@
+// Package health 实现存活（liveness）与就绪（readiness）检查，API 与 worker 共用。
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/azin/gdstudio-embed-service/internal/buildinfo"
+)
+
+// 检查状态
+const (
+	StatusHealthy   = "healthy"
+	StatusDegraded  = "degraded"  // 非关键依赖不可用，仍然接收流量
+	StatusUnhealthy = "unhealthy" // 关键依赖不可用，就绪检查返回 503
+)
+
+// CheckFunc 单项检查，返回 nil 表示正常
+type CheckFunc func(ctx context.Context) error
+
+// ComponentStatus 单项检查结果
+type ComponentStatus struct {
+	Status    string    `json:"status"`
+	Critical  bool      `json:"critical"`
+	Error     string    `json:"error,omitempty"`
+	LatencyMS int64     `json:"latency_ms"`
+	CheckedAt time.Time `json:"checked_at"`
+	Cached    bool      `json:"cached,omitempty"`
+}
+
+// Report 健康检查响应
+type Report struct {
+	Status     string                     `json:"status"`
+	Version    string                     `json:"version"`
+	Commit     string                     `json:"commit"`
+	BuildDate  string                     `json:"build_date"`
+	StartedAt  time.Time                  `json:"started_at"`
+	Uptime     float64                    `json:"uptime"` // 秒
+	Components map[string]ComponentStatus `json:"components,omitempty"`
+}
+
+type check struct {
+	name     string
+	critical bool
+	ttl      time.Duration
+	fn       CheckFunc
+
+	mu        sync.Mutex
+	last      ComponentStatus
+	hasResult bool
+}
+
+// Checker 就绪检查集合
+type Checker struct {
+	timeout time.Duration
+	checks  []*check
+}
+
+// NewChecker 创建检查器，timeout 为单项检查超时
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{timeout: timeout}
+}
+
+// Add 注册检查项。critical 为 true 时失败会让就绪检查返回 503；
+// ttl 大于 0 时在有效期内复用上次结果，避免探针频繁访问外部服务
+func (c *Checker) Add(name string, critical bool, ttl time.Duration, fn CheckFunc) {
+	c.checks = append(c.checks, &check{name: name, critical: critical, ttl: ttl, fn: fn})
+}
+
+// Live 存活检查：只说明进程在运行，不访问任何依赖
+func (c *Checker) Live() Report {
+	return newReport(StatusHealthy)
+}
+
+// Ready 并发执行所有检查项并汇总
+func (c *Checker) Ready(ctx context.Context) Report {
+	components := make(map[string]ComponentStatus, len(c.checks))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, chk := range c.checks {
+		wg.Add(1)
+		go func(chk *check) {
+			defer wg.Done()
+			result := chk.run(ctx, c.timeout)
+			mu.Lock()
+			components[chk.name] = result
+			mu.Unlock()
+		}(chk)
+	}
+	wg.Wait()
+
+	status := StatusHealthy
+	for _, result := range components {
+		if result.Status == StatusHealthy {
+			continue
+		}
+		if result.Critical {
+			status = StatusUnhealthy
+			break
+		}
+		status = StatusDegraded
+	}
+
+	report := newReport(status)
+	report.Components = components
+	return report
+}
+
+func (chk *check) run(ctx context.Context, timeout time.Duration) ComponentStatus {
+	chk.mu.Lock()
+	defer chk.mu.Unlock()
+
+	if chk.hasResult && chk.ttl > 0 && time.Since(chk.last.CheckedAt) < chk.ttl {
+		cached := chk.last
+		cached.Cached = true
+		return cached
+	}
+
+	checkCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := chk.fn(checkCtx)
+	result := ComponentStatus{
+		Status:    StatusHealthy,
+		Critical:  chk.critical,
+		LatencyMS: time.Since(start).Milliseconds(),
+		CheckedAt: start,
+	}
+	if err != nil {
+		result.Status = StatusUnhealthy
+		result.Error = err.Error()
+	}
+
+	chk.last = result
+	chk.hasResult = true
+	return result
+}
+
+func newReport(status string) Report {
+	return Report{
+		Status:    status,
+		Version:   buildinfo.Version,
+		Commit:    buildinfo.CommitSHA,
+		BuildDate: buildinfo.BuildDate,
+		StartedAt: buildinfo.StartTime(),
+		Uptime:    buildinfo.Uptime().Seconds(),
+	}
+}
+
+// LiveHandler /healthz 处理函数
+func (c *Checker) LiveHandler(w http.ResponseWriter, r *http.Request) {
+	writeReport(w, c.Live())
+}
+
+// ReadyHandler /readyz 处理函数，关键依赖不可用时返回 503
+func (c *Checker) ReadyHandler(w http.ResponseWriter, r *http.Request) {
+	writeReport(w, c.Ready(r.Context()))
+}
+
+func writeReport(w http.ResponseWriter, report Report) {
+	status := http.StatusOK
+	if report.Status == StatusUnhealthy {
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(report)
+}
+
+// Mux 返回仅包含 /healthz 与 /readyz 的 HTTP 处理器（worker 使用）
+func (c *Checker) Mux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", c.LiveHandler)
+	mux.HandleFunc("/readyz", c.ReadyHandler)
+	return mux
+}
@@ -16,6 +16,18 @@ type Job struct {
 	LyricID        string `gorm:"size:64" json:"lyric_id"`
 	LibraryID      string `gorm:"size:64;not null" json:"library_id"`
 	Quality        string `gorm:"size:16" json:"quality"`
+	ISRC           string `gorm:"size:32" json:"isrc,omitempty"`
+	APIKeyName     string `gorm:"size:64;index" json:"api_key_name,omitempty"` // 创建任务的 API Key
+	RequestID      string `gorm:"size:128" json:"request_id,omitempty"`        // 创建任务的 API 请求 ID（X-Request-ID）
+
+	// 曲库重复处理
+	DuplicatePolicy string `gorm:"size:32" json:"duplicate_policy"`
+	DuplicateOf     string `gorm:"size:1024" json:"duplicate_of,omitempty"` // 匹配到的曲库文件路径
+
+	// 目标文件名冲突处理
+	CollisionPolicy  string `gorm:"size:32" json:"collision_policy"`
+	CollisionOutcome string `gorm:"size:32" json:"collision_outcome,omitempty"`
+	ReservedPath     string `gorm:"size:1024" json:"-"` // 本任务在曲库中占用的文件路径，重试时覆盖而不是当作冲突
 
 	// 元数据
 	Title       string `gorm:"size:255" json:"title"`
@@ -40,10 +52,13 @@ type Job struct {
 	Bitrate  int    `json:"bitrate"`  // kbps
 
 	// 错误信息
-	Error       string     `gorm:"size:1024" json:"error"`
+	Error       string     `gorm:"size:1024" json:"error"` // 格式为 "错误码: 描述"，错误码见 errcode 包
 	RetryCount  int        `json:"retry_count"`
 	LastRetryAt *time.Time `json:"last_retry_at"`
 
+	// 音质升级
+	UpgradeCheckedAt *time.Time `json:"upgrade_checked_at,omitempty"`
+
 	// 时间戳
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
@@ -55,6 +70,23 @@ func (Job) TableName() string {
 	return "jobs"
 }
 
+// CollisionPolicy 目标路径已存在同名文件时的处理策略
+const (
+	CollisionPolicySkip       = "skip"        // 保留已有文件，不移动新文件
+	CollisionPolicyOverwrite  = "overwrite"   // 覆盖已有文件
+	CollisionPolicySuffix     = "suffix"      // 新文件追加 " (2)"、" (3)" 等后缀
+	CollisionPolicyKeepBetter = "keep_better" // 比较音质，保留较好的一个
+)
+
+// CollisionOutcome 文件名冲突的处理结果
+const (
+	CollisionOutcomeSkipped      = "skipped"       // 已有文件保留，新文件丢弃
+	CollisionOutcomeOverwritten  = "overwritten"   // 已有文件被覆盖
+	CollisionOutcomeSuffixed     = "suffixed"      // 新文件使用了带后缀的文件名
+	CollisionOutcomeKeptExisting = "kept_existing" // 已有文件音质不低于新文件，保留已有文件
+	CollisionOutcomeReplaced     = "replaced"      // 新文件音质更高，替换已有文件
+)
+
 // TrackMetadata 曲目元数据
 type TrackMetadata struct {
 	Title       string
@@ -62,8 +94,10 @@ type TrackMetadata struct {
 	Album       string
 	TrackNumber int
 	Year        int
+	Duration    int // 秒
 	CoverURL    string
 	CoverData   []byte
+	CoverMIME   string
 	Lyrics      string
 	Translation string // 翻译歌词
 }
@@ -0,0 +1,43 @@
+package model
+
+import "time"
+
+// LibraryTrack 曲库索引（扫描 MusicDir 得到的已有曲目）
+type LibraryTrack struct {
+	ID     uint   `gorm:"primaryKey" json:"id"`
+	Path   string `gorm:"uniqueIndex;size:1024;not null" json:"path"`
+	Format string `gorm:"size:16" json:"format"`
+
+	// 标签
+	Title  string `gorm:"size:255" json:"title"`
+	Artist string `gorm:"size:255" json:"artist"`
+	Album  string `gorm:"size:255" json:"album"`
+	ISRC   string `gorm:"size:32;index" json:"isrc"`
+
+	// 归一化后的匹配键
+	NormArtist string `gorm:"size:255;index:idx_library_tracks_match" json:"-"`
+	NormTitle  string `gorm:"size:255;index:idx_library_tracks_match" json:"-"`
+	NormAlbum  string `gorm:"size:255" json:"-"`
+
+	Duration int   `json:"duration"` // 秒
+	Bitrate  int   `json:"bitrate"`  // kbps
+	Size     int64 `json:"size"`
+
+	ModTime   time.Time `json:"mod_time"`
+	JobID     string    `gorm:"size:64" json:"job_id,omitempty"` // 由本服务下载时记录来源任务
+	IndexedAt time.Time `gorm:"index" json:"indexed_at"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName 表名
+func (LibraryTrack) TableName() string {
+	return "library_tracks"
+}
+
+// DuplicatePolicy 曲库重复处理策略
+const (
+	DuplicatePolicySkip            = "skip"              // 已存在则跳过下载
+	DuplicatePolicyReplaceIfBetter = "replace_if_better" // 新版本音质更高时替换
+	DuplicatePolicyKeepBoth        = "keep_both"         // 照常下载
+)
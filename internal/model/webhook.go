@@ -0,0 +1,43 @@
+package model
+
+import "time"
+
+// Webhook 任务生命周期事件订阅
+//
+// 两种来源：API Key 配置中的 webhook_url（JobID 为空，接收该 Key 创建的全部任务的事件），
+// 以及创建任务时传入的 callback_url（只接收该任务的事件）。
+type Webhook struct {
+	ID         string    `gorm:"primaryKey;size:64" json:"id"`
+	URL        string    `gorm:"size:1024;not null" json:"url"`
+	Secret     string    `gorm:"size:255" json:"-"`
+	APIKeyName string    `gorm:"size:64;index" json:"api_key_name,omitempty"`
+	JobID      string    `gorm:"size:64;index" json:"job_id,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// TableName 表名
+func (Webhook) TableName() string {
+	return "webhooks"
+}
+
+// WebhookDelivery Webhook 投递记录（每次尝试一条）
+type WebhookDelivery struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	DeliveryID   string    `gorm:"size:64;index" json:"delivery_id"` // 同一事件的多次重试共享
+	WebhookID    string    `gorm:"size:64;index:idx_webhook_deliveries_webhook" json:"webhook_id"`
+	JobID        string    `gorm:"size:64" json:"job_id"`
+	Event        string    `gorm:"size:32" json:"event"`
+	URL          string    `gorm:"size:1024" json:"url"`
+	Attempt      int       `json:"attempt"`
+	StatusCode   int       `json:"status_code"`
+	Error        string    `gorm:"size:1024" json:"error,omitempty"`
+	ResponseBody string    `gorm:"size:1024" json:"response_body,omitempty"`
+	DurationMs   int64     `json:"duration_ms"`
+	CreatedAt    time.Time `gorm:"index:idx_webhook_deliveries_webhook" json:"created_at"`
+}
+
+// TableName 表名
+func (WebhookDelivery) TableName() string {
+	return "webhook_deliveries"
+}
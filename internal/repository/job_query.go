@@ -0,0 +1,178 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/azin/gdstudio-embed-service/internal/model"
+	"gorm.io/gorm"
+)
+
+// JobFilter 任务列表过滤条件，零值字段不参与过滤
+type JobFilter struct {
+	Statuses      []string
+	Source        string
+	LibraryID     string
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	UpdatedAfter  *time.Time
+	UpdatedBefore *time.Time
+	Query         string  // 在标题、艺术家、专辑中模糊搜索（不区分大小写）
+	APIKeyName    *string // 只返回该 API Key 创建的任务（为空字符串时只匹配未记录创建者的任务）
+}
+
+// JobSort 任务列表排序，Field 为 JobSortFields 中的列名
+type JobSort struct {
+	Field string
+	Desc  bool
+}
+
+// JobSortFields 允许排序的列
+var JobSortFields = map[string]bool{
+	"created_at": true,
+	"updated_at": true,
+	"title":      true,
+	"artist":     true,
+	"album":      true,
+	"status":     true,
+}
+
+// JobCursor 游标分页位置：上一页最后一条记录的排序值与 ID
+type JobCursor struct {
+	Field string `json:"f"`
+	Value string `json:"v"`
+	ID    string `json:"id"`
+}
+
+// EncodeCursor 将游标编码为不透明字符串
+func EncodeCursor(cursor JobCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor 解析 EncodeCursor 生成的游标
+func DecodeCursor(value string) (*JobCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor: %w", err)
+	}
+	var cursor JobCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.ID == "" {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	return &cursor, nil
+}
+
+// CursorAfter 返回指向 job 之后的游标
+func (s JobSort) CursorAfter(job *model.Job) JobCursor {
+	cursor := JobCursor{Field: s.Field, ID: job.ID}
+	switch s.Field {
+	case "created_at":
+		cursor.Value = job.CreatedAt.UTC().Format(time.RFC3339Nano)
+	case "updated_at":
+		cursor.Value = job.UpdatedAt.UTC().Format(time.RFC3339Nano)
+	case "title":
+		cursor.Value = job.Title
+	case "artist":
+		cursor.Value = job.Artist
+	case "album":
+		cursor.Value = job.Album
+	case "status":
+		cursor.Value = job.Status
+	}
+	return cursor
+}
+
+// List 按过滤条件、排序与游标分页查询任务
+func (r *JobRepository) List(filter JobFilter, sort JobSort, cursor *JobCursor, limit int) ([]*model.Job, error) {
+	if !JobSortFields[sort.Field] {
+		return nil, fmt.Errorf("unsupported sort field: %s", sort.Field)
+	}
+
+	query := applyJobFilter(r.db.Model(&model.Job{}), filter)
+
+	if cursor != nil {
+		if cursor.Field != sort.Field {
+			return nil, fmt.Errorf("cursor does not match sort field")
+		}
+		var value interface{} = cursor.Value
+		if sort.Field == "created_at" || sort.Field == "updated_at" {
+			t, err := time.Parse(time.RFC3339Nano, cursor.Value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid cursor: %w", err)
+			}
+			// 与写入时使用的本地时区一致（sqlite 以字符串比较时间）
+			value = t.Local()
+		}
+
+		// 排序列可能重复，用 (列, id) 组合作为游标键。
+		op := ">"
+		if sort.Desc {
+			op = "<"
+		}
+		query = query.Where(
+			fmt.Sprintf("(%s %s ?) OR (%s = ? AND id %s ?)", sort.Field, op, sort.Field, op),
+			value, value, cursor.ID)
+	}
+
+	direction := "ASC"
+	if sort.Desc {
+		direction = "DESC"
+	}
+
+	var jobs []*model.Job
+	err := query.
+		Order(fmt.Sprintf("%s %s, id %s", sort.Field, direction, direction)).
+		Limit(limit).
+		Find(&jobs).Error
+	return jobs, err
+}
+
+// Count 统计满足过滤条件的任务总数
+func (r *JobRepository) Count(filter JobFilter) (int64, error) {
+	var count int64
+	err := applyJobFilter(r.db.Model(&model.Job{}), filter).Count(&count).Error
+	return count, err
+}
+
+func applyJobFilter(query *gorm.DB, filter JobFilter) *gorm.DB {
+	if len(filter.Statuses) > 0 {
+		query = query.Where("status IN ?", filter.Statuses)
+	}
+	if filter.Source != "" {
+		query = query.Where("source = ?", filter.Source)
+	}
+	if filter.LibraryID != "" {
+		query = query.Where("library_id = ?", filter.LibraryID)
+	}
+	if filter.APIKeyName != nil {
+		query = query.Where("api_key_name = ?", *filter.APIKeyName)
+	}
+	if filter.CreatedAfter != nil {
+		query = query.Where("created_at >= ?", *filter.CreatedAfter)
+	}
+	if filter.CreatedBefore != nil {
+		query = query.Where("created_at < ?", *filter.CreatedBefore)
+	}
+	if filter.UpdatedAfter != nil {
+		query = query.Where("updated_at >= ?", *filter.UpdatedAfter)
+	}
+	if filter.UpdatedBefore != nil {
+		query = query.Where("updated_at < ?", *filter.UpdatedBefore)
+	}
+	if q := strings.TrimSpace(filter.Query); q != "" {
+		pattern := "%" + escapeLike(strings.ToLower(q)) + "%"
+		query = query.Where(
+			`LOWER(title) LIKE ? ESCAPE '\' OR LOWER(artist) LIKE ? ESCAPE '\' OR LOWER(album) LIKE ? ESCAPE '\'`,
+			pattern, pattern, pattern)
+	}
+	return query
+}
+
+// escapeLike 转义 LIKE 通配符
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
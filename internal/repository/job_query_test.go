@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"encoding/base64"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/azin/gdstudio-embed-service/internal/model"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	cursors := []JobCursor{
+		{Field: "created_at", Value: "2026-01-02T03:04:05.123456789Z", ID: "job-1"},
+		{Field: "title", Value: "晴天 / \"Live\" ?&=", ID: "job-2"},
+		{Field: "status", Value: "", ID: "job-3"},
+	}
+	for _, want := range cursors {
+		encoded := EncodeCursor(want)
+		got, err := DecodeCursor(encoded)
+		if err != nil {
+			t.Fatalf("DecodeCursor(%q): %v", encoded, err)
+		}
+		if *got != want {
+			t.Errorf("round trip = %+v, want %+v", *got, want)
+		}
+	}
+}
+
+func TestDecodeCursorInvalid(t *testing.T) {
+	tests := map[string]string{
+		"not base64":   "!!!",
+		"padded":       "eyJpZCI6ImEifQ==",
+		"not json":     encodeCursorRaw("not json"),
+		"missing id":   encodeCursorRaw(`{"f":"title","v":"a"}`),
+		"wrong type":   encodeCursorRaw(`{"f":"title","v":1,"id":"a"}`),
+		"empty string": "",
+	}
+	for name, value := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := DecodeCursor(value); err == nil {
+				t.Errorf("DecodeCursor(%q) should fail", value)
+			}
+		})
+	}
+}
+
+// encodeCursorRaw 以游标相同的编码方式编码任意内容
+func encodeCursorRaw(s string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(s))
+}
+
+func TestListCursorPagination(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := InitDB(db); err != nil {
+		t.Fatal(err)
+	}
+	repo := NewJobRepository(db)
+
+	// 排序列有大量重复值，翻页必须依靠 id 打破平局
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	statuses := []string{model.JobStatusDone, model.JobStatusFailed, model.JobStatusQueued}
+	const total = 11
+	for i := 0; i < total; i++ {
+		job := &model.Job{
+			ID:             fmt.Sprintf("job-%02d", i),
+			IdempotencyKey: fmt.Sprintf("key-%02d", i),
+			Source:         "netease",
+			TrackID:        fmt.Sprint(i),
+			LibraryID:      "default",
+			Status:         statuses[i%len(statuses)],
+			CreatedAt:      base.Add(time.Duration(i/2) * time.Minute),
+		}
+		if err := repo.Create(job); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, sort := range []JobSort{
+		{Field: "status"}, {Field: "status", Desc: true},
+		{Field: "created_at"}, {Field: "created_at", Desc: true},
+	} {
+		t.Run(fmt.Sprintf("%s desc=%v", sort.Field, sort.Desc), func(t *testing.T) {
+			all, err := repo.List(JobFilter{}, sort, nil, total)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var paged []*model.Job
+			var cursor *JobCursor
+			for page := 0; page < total; page++ {
+				jobs, err := repo.List(JobFilter{}, sort, cursor, 3)
+				if err != nil {
+					t.Fatalf("page %d: %v", page, err)
+				}
+				if len(jobs) == 0 {
+					break
+				}
+				paged = append(paged, jobs...)
+
+				// 经过编码与解码，与 API 返回给客户端的游标一致
+				next, err := DecodeCursor(EncodeCursor(sort.CursorAfter(jobs[len(jobs)-1])))
+				if err != nil {
+					t.Fatal(err)
+				}
+				cursor = next
+			}
+
+			if len(paged) != total {
+				t.Fatalf("paged through %d jobs, want %d", len(paged), total)
+			}
+			for i := range all {
+				if paged[i].ID != all[i].ID {
+					t.Fatalf("position %d: paged %s, unpaged %s", i, paged[i].ID, all[i].ID)
+				}
+			}
+		})
+	}
+
+	t.Run("cursor for another sort field", func(t *testing.T) {
+		cursor := JobCursor{Field: "title", ID: "job-00"}
+		if _, err := repo.List(JobFilter{}, JobSort{Field: "status"}, &cursor, 3); err == nil {
+			t.Error("expected an error")
+		}
+	})
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/azin/gdstudio-embed-service/internal/model"
@@ -103,6 +104,19 @@ func (r *JobRepository) MarkDone(id, filePath string, fileSize int64) error {
 		}).Error
 }
 
+// MarkSkipped 标记任务完成但未实际下载（例如曲库中已存在）
+func (r *JobRepository) MarkSkipped(id, filePath, message string) error {
+	return r.db.Model(&model.Job{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"status":     model.JobStatusDone,
+			"file_path":  filePath,
+			"message":    message,
+			"progress":   100,
+			"updated_at": time.Now(),
+		}).Error
+}
+
 // ListByStatus 根据状态查询任务列表
 func (r *JobRepository) ListByStatus(status string, limit int) ([]*model.Job, error) {
 	var jobs []*model.Job
@@ -122,6 +136,26 @@ func (r *JobRepository) ListRecent(limit int) ([]*model.Job, error) {
 	return jobs, err
 }
 
+// ListUpgradeCandidates 查询码率可能低于期望音质的已完成任务（跳过 checkedBefore 之后检查过的）
+func (r *JobRepository) ListUpgradeCandidates(checkedBefore time.Time, limit int) ([]*model.Job, error) {
+	var jobs []*model.Job
+	err := r.db.Where("status = ? AND file_path <> '' AND bitrate > 0", model.JobStatusDone).
+		Where("bitrate < ? OR (quality IN ? AND file_path NOT LIKE ?)", 320, []string{"best", "lossless"}, "%.flac").
+		Where("upgrade_checked_at IS NULL OR upgrade_checked_at < ?", checkedBefore).
+		Order("created_at ASC").
+		Limit(limit).
+		Find(&jobs).Error
+	return jobs, err
+}
+
+// MarkUpgradeChecked 记录最近一次音质升级检查时间
+func (r *JobRepository) MarkUpgradeChecked(id string) error {
+	now := time.Now()
+	return r.db.Model(&model.Job{}).
+		Where("id = ?", id).
+		Update("upgrade_checked_at", &now).Error
+}
+
 // IncrementRetry 增加重试次数
 func (r *JobRepository) IncrementRetry(id string) error {
 	now := time.Now()
@@ -141,20 +175,94 @@ func (r *JobRepository) CountByStatus(status string) (int64, error) {
 	return count, err
 }
 
-// DeleteOldJobs 删除旧任务（已完成或失败超过指定天数）
-func (r *JobRepository) DeleteOldJobs(days int) error {
-	cutoff := time.Now().AddDate(0, 0, -days)
-	return r.db.Where("status IN (?, ?) AND updated_at < ?",
-		model.JobStatusDone,
-		model.JobStatusFailed,
-		cutoff).
-		Delete(&model.Job{}).Error
+// oldJobs 已结束且最后更新早于 cutoff 的任务
+func (r *JobRepository) oldJobs(cutoff time.Time) *gorm.DB {
+	return r.db.Model(&model.Job{}).
+		Where("status IN ? AND updated_at < ?", []string{
+			model.JobStatusDone,
+			model.JobStatusFailed,
+			model.JobStatusCancelled,
+		}, cutoff)
+}
+
+// CountOldJobs 统计可清理的旧任务数量
+func (r *JobRepository) CountOldJobs(cutoff time.Time) (int64, error) {
+	var count int64
+	err := r.oldJobs(cutoff).Count(&count).Error
+	return count, err
+}
+
+// deletedIdempotencyKey 软删除时改写幂等键，释放唯一索引，使同一曲目可以重新提交
+var deletedIdempotencyKey = gorm.Expr("'deleted:' || id")
+
+// DeleteOldJobs 软删除旧任务（已完成、失败或取消且最后更新早于 cutoff），返回删除数量
+func (r *JobRepository) DeleteOldJobs(cutoff time.Time) (int64, error) {
+	result := r.oldJobs(cutoff).Updates(map[string]interface{}{
+		"deleted_at":      time.Now(),
+		"idempotency_key": deletedIdempotencyKey,
+	})
+	return result.RowsAffected, result.Error
+}
+
+// softDeleted 软删除时间早于 cutoff 的任务
+func (r *JobRepository) softDeleted(cutoff time.Time) *gorm.DB {
+	return r.db.Unscoped().Model(&model.Job{}).
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff)
+}
+
+// CountSoftDeleted 统计可彻底删除的软删除任务数量
+func (r *JobRepository) CountSoftDeleted(cutoff time.Time) (int64, error) {
+	var count int64
+	err := r.softDeleted(cutoff).Count(&count).Error
+	return count, err
+}
+
+// PurgeSoftDeleted 彻底删除软删除时间早于 cutoff 的任务，返回删除数量
+func (r *JobRepository) PurgeSoftDeleted(cutoff time.Time) (int64, error) {
+	result := r.softDeleted(cutoff).Delete(&model.Job{})
+	return result.RowsAffected, result.Error
+}
+
+// AdoptLegacyJobs 迁移按 API Key 隔离之前创建的任务：只配置了一个非管理员 Key 时，将没有 api_key_name 的任务归属于它
+// （管理员本就可以访问全部任务）；配置了多个非管理员 Key 且仍有无归属的任务时无法判断归属，返回错误。
+// 再为已有归属的任务补上 "<key>/" 幂等键前缀，避免升级后重复下载。返回归属与补前缀的任务数。
+func (r *JobRepository) AdoptLegacyJobs(ownerNames []string) (adopted, prefixed int64, err error) {
+	legacy := r.db.Model(&model.Job{}).Where("api_key_name IS NULL OR api_key_name = ''")
+	switch len(ownerNames) {
+	case 0:
+	case 1:
+		result := legacy.Update("api_key_name", ownerNames[0])
+		if result.Error != nil {
+			return 0, 0, fmt.Errorf("failed to assign legacy jobs: %w", result.Error)
+		}
+		adopted = result.RowsAffected
+	default:
+		var count int64
+		if err := legacy.Count(&count).Error; err != nil {
+			return 0, 0, fmt.Errorf("failed to count legacy jobs: %w", err)
+		}
+		if count > 0 {
+			return 0, 0, fmt.Errorf("%d jobs have no api key and %d non-admin api keys are configured (%s): set jobs.api_key_name manually",
+				count, len(ownerNames), strings.Join(ownerNames, ", "))
+		}
+	}
+
+	// 已带前缀、已软删除或前缀键已被新任务占用的行保持不变
+	result := r.db.Model(&model.Job{}).
+		Where("api_key_name <> '' AND idempotency_key NOT LIKE ?", "deleted:%").
+		Where("substr(idempotency_key, 1, length(api_key_name) + 1) <> api_key_name || '/'").
+		Where("NOT EXISTS (SELECT 1 FROM jobs AS other WHERE other.idempotency_key = jobs.api_key_name || '/' || jobs.idempotency_key)").
+		Update("idempotency_key", gorm.Expr("api_key_name || '/' || idempotency_key"))
+	if result.Error != nil {
+		return adopted, 0, fmt.Errorf("failed to prefix legacy idempotency keys: %w", result.Error)
+	}
+	return adopted, result.RowsAffected, nil
 }
 
 // InitDB 初始化数据库
 func InitDB(db *gorm.DB) error {
 	// 自动迁移表结构
-	if err := db.AutoMigrate(&model.Job{}); err != nil {
+	if err := db.AutoMigrate(&model.Job{}, &model.LibraryTrack{}, &model.Webhook{}, &model.WebhookDelivery{}); err != nil {
 		return fmt.Errorf("failed to migrate database: %w", err)
 	}
 
@@ -163,5 +271,12 @@ func InitDB(db *gorm.DB) error {
 		return fmt.Errorf("failed to create index: %w", err)
 	}
 
+	// 早期版本软删除时保留了幂等键，改写以释放唯一索引
+	if err := db.Unscoped().Model(&model.Job{}).
+		Where("deleted_at IS NOT NULL AND idempotency_key NOT LIKE ?", "deleted:%").
+		Update("idempotency_key", deletedIdempotencyKey).Error; err != nil {
+		return fmt.Errorf("failed to release idempotency keys of deleted jobs: %w", err)
+	}
+
 	return nil
 }
@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/azin/gdstudio-embed-service/internal/model"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func newTestJobRepository(t *testing.T, jobs ...*model.Job) *JobRepository {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := InitDB(db); err != nil {
+		t.Fatal(err)
+	}
+	repo := NewJobRepository(db)
+	for _, job := range jobs {
+		if err := repo.Create(job); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return repo
+}
+
+func TestAdoptLegacyJobs(t *testing.T) {
+	legacyJobs := func() []*model.Job {
+		return []*model.Job{
+			{ID: "legacy", IdempotencyKey: "netease:1:default:best", Source: "netease", TrackID: "1", LibraryID: "default"},
+			{ID: "owned", IdempotencyKey: "netease:2:default:best", Source: "netease", TrackID: "2", LibraryID: "default", APIKeyName: "web"},
+		}
+	}
+
+	t.Run("single owner adopts and prefixes", func(t *testing.T) {
+		repo := newTestJobRepository(t, legacyJobs()...)
+		adopted, prefixed, err := repo.AdoptLegacyJobs([]string{"web"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if adopted != 1 || prefixed != 2 {
+			t.Errorf("adopted %d, prefixed %d, want 1, 2", adopted, prefixed)
+		}
+		job, err := repo.FindByID("legacy")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if job.APIKeyName != "web" || job.IdempotencyKey != "web/netease:1:default:best" {
+			t.Errorf("legacy job = %q %q", job.APIKeyName, job.IdempotencyKey)
+		}
+
+		// 再次启动不重复处理
+		adopted, prefixed, err = repo.AdoptLegacyJobs([]string{"web"})
+		if err != nil || adopted != 0 || prefixed != 0 {
+			t.Errorf("second run: adopted %d, prefixed %d, err %v", adopted, prefixed, err)
+		}
+	})
+
+	t.Run("ambiguous owners", func(t *testing.T) {
+		repo := newTestJobRepository(t, legacyJobs()...)
+		if _, _, err := repo.AdoptLegacyJobs([]string{"app", "web"}); err == nil {
+			t.Fatal("expected an error")
+		}
+		job, err := repo.FindByID("legacy")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if job.APIKeyName != "" {
+			t.Errorf("legacy job assigned to %q", job.APIKeyName)
+		}
+	})
+
+	t.Run("multiple owners without legacy jobs", func(t *testing.T) {
+		repo := newTestJobRepository(t, legacyJobs()[1])
+		if _, _, err := repo.AdoptLegacyJobs([]string{"app", "web"}); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("admin only keeps legacy jobs unowned", func(t *testing.T) {
+		repo := newTestJobRepository(t, legacyJobs()...)
+		adopted, _, err := repo.AdoptLegacyJobs(nil)
+		if err != nil || adopted != 0 {
+			t.Errorf("adopted %d, err %v", adopted, err)
+		}
+	})
+}
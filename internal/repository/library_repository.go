@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/azin/gdstudio-embed-service/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// LibraryRepository 曲库索引仓库
+type LibraryRepository struct {
+	db *gorm.DB
+}
+
+// NewLibraryRepository 创建曲库索引仓库
+func NewLibraryRepository(db *gorm.DB) *LibraryRepository {
+	return &LibraryRepository{db: db}
+}
+
+// FindByPath 根据路径查询索引。扫描时大部分文件首次出现，用 Find 避免 GORM 记录 not found 日志。
+func (r *LibraryRepository) FindByPath(path string) (*model.LibraryTrack, error) {
+	var tracks []*model.LibraryTrack
+	if err := r.db.Where("path = ?", path).Limit(1).Find(&tracks).Error; err != nil {
+		return nil, err
+	}
+	if len(tracks) == 0 {
+		return nil, nil
+	}
+	return tracks[0], nil
+}
+
+// Upsert 按路径插入或更新索引
+func (r *LibraryRepository) Upsert(track *model.LibraryTrack) error {
+	now := time.Now()
+	track.UpdatedAt = now
+	if track.CreatedAt.IsZero() {
+		track.CreatedAt = now
+	}
+
+	return r.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "path"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"format", "title", "artist", "album", "isrc",
+			"norm_artist", "norm_title", "norm_album",
+			"duration", "bitrate", "size", "mod_time", "job_id",
+			"indexed_at", "updated_at",
+		}),
+	}).Create(track).Error
+}
+
+// Touch 标记索引在本轮扫描中仍然存在
+func (r *LibraryRepository) Touch(id uint, indexedAt time.Time) error {
+	return r.db.Model(&model.LibraryTrack{}).
+		Where("id = ?", id).
+		Update("indexed_at", indexedAt).Error
+}
+
+// FindByISRC 根据 ISRC 查询
+func (r *LibraryRepository) FindByISRC(isrc string) ([]*model.LibraryTrack, error) {
+	var tracks []*model.LibraryTrack
+	err := r.db.Where("isrc = ?", isrc).Find(&tracks).Error
+	return tracks, err
+}
+
+// FindByNormalized 根据归一化的艺术家与标题查询候选
+func (r *LibraryRepository) FindByNormalized(artist, title string) ([]*model.LibraryTrack, error) {
+	var tracks []*model.LibraryTrack
+	err := r.db.Where("norm_artist = ? AND norm_title = ?", artist, title).Find(&tracks).Error
+	return tracks, err
+}
+
+// DeletePath 删除指定路径的索引
+func (r *LibraryRepository) DeletePath(path string) error {
+	return r.db.Where("path = ?", path).Delete(&model.LibraryTrack{}).Error
+}
+
+// DeleteStale 删除本轮扫描未再出现的索引（文件已被删除或移动）
+func (r *LibraryRepository) DeleteStale(before time.Time) (int64, error) {
+	result := r.db.Where("indexed_at < ?", before).Delete(&model.LibraryTrack{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/azin/gdstudio-embed-service/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// WebhookRepository Webhook 订阅与投递记录仓库
+type WebhookRepository struct {
+	db *gorm.DB
+}
+
+// NewWebhookRepository 创建 Webhook 仓库
+func NewWebhookRepository(db *gorm.DB) *WebhookRepository {
+	return &WebhookRepository{db: db}
+}
+
+// Create 创建订阅
+func (r *WebhookRepository) Create(webhook *model.Webhook) error {
+	return r.db.Create(webhook).Error
+}
+
+// Upsert 按 ID 插入或更新订阅（用于同步 API Key 配置中的 webhook）
+func (r *WebhookRepository) Upsert(webhook *model.Webhook) error {
+	now := time.Now()
+	webhook.UpdatedAt = now
+	if webhook.CreatedAt.IsZero() {
+		webhook.CreatedAt = now
+	}
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"url", "secret", "api_key_name", "updated_at"}),
+	}).Create(webhook).Error
+}
+
+// FindByID 根据 ID 查询订阅
+func (r *WebhookRepository) FindByID(id string) (*model.Webhook, error) {
+	var webhook model.Webhook
+	if err := r.db.Where("id = ?", id).First(&webhook).Error; err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+// ListForJob 返回任务相关的订阅：任务自身的 callback 与创建该任务的 API Key 的订阅
+func (r *WebhookRepository) ListForJob(jobID, apiKeyName string) ([]*model.Webhook, error) {
+	var webhooks []*model.Webhook
+	query := r.db.Where("job_id = ?", jobID)
+	if apiKeyName != "" {
+		query = query.Or("job_id = '' AND api_key_name = ?", apiKeyName)
+	}
+	err := query.Find(&webhooks).Error
+	return webhooks, err
+}
+
+// CreateDelivery 记录一次投递
+func (r *WebhookRepository) CreateDelivery(delivery *model.WebhookDelivery) error {
+	if delivery.CreatedAt.IsZero() {
+		delivery.CreatedAt = time.Now()
+	}
+	return r.db.Create(delivery).Error
+}
+
+// ListDeliveries 查询订阅最近的投递记录
+func (r *WebhookRepository) ListDeliveries(webhookID string, limit int) ([]*model.WebhookDelivery, error) {
+	var deliveries []*model.WebhookDelivery
+	err := r.db.Where("webhook_id = ?", webhookID).
+		Order("created_at DESC, id DESC").
+		Limit(limit).
+		Find(&deliveries).Error
+	return deliveries, err
+}
+
+// DeleteDeliveriesBefore 删除早于 cutoff 的投递记录，返回删除数量
+func (r *WebhookRepository) DeleteDeliveriesBefore(cutoff time.Time) (int64, error) {
+	result := r.db.Where("created_at < ?", cutoff).Delete(&model.WebhookDelivery{})
+	return result.RowsAffected, result.Error
+}
+
+// CountDeliveriesBefore 统计早于 cutoff 的投递记录数量
+func (r *WebhookRepository) CountDeliveriesBefore(cutoff time.Time) (int64, error) {
+	var count int64
+	err := r.db.Model(&model.WebhookDelivery{}).Where("created_at < ?", cutoff).Count(&count).Error
+	return count, err
+}
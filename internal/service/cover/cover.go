@@ -0,0 +1,190 @@
+package cover
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif" // 注册解码器
+	"image/jpeg"
+	_ "image/png"
+	"net/http"
+
+	xdraw "golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	MIMEJPEG = "image/jpeg"
+	MIMEPNG  = "image/png"
+	MIMEWebP = "image/webp"
+	MIMEAVIF = "image/avif"
+)
+
+// minDimension 为满足字节预算而缩小时的最小边长
+const minDimension = 300
+
+// ErrUnsupportedFormat 图片格式无法解码（例如 AVIF，没有可用的纯 Go 解码器），
+// 调用方可保留原始数据嵌入
+var ErrUnsupportedFormat = errors.New("unsupported image format")
+
+// Options 封面处理参数
+type Options struct {
+	MaxDimension int // 嵌入封面最长边（像素），0 表示不限制
+	MaxBytes     int // 嵌入封面字节预算，0 表示不限制
+	Quality      int // JPEG 编码质量
+}
+
+// Image 处理后的图片
+type Image struct {
+	Data   []byte
+	MIME   string
+	Width  int
+	Height int
+}
+
+// Hash 返回图片数据的 SHA-256
+func (i *Image) Hash() string {
+	return Hash(i.Data)
+}
+
+// Result 封面处理结果
+type Result struct {
+	Full  Image // 全尺寸封面（JPEG），用于专辑目录 cover.jpg
+	Embed Image // 满足尺寸与字节预算的封面，用于写入音频标签
+}
+
+// Hash 计算数据的 SHA-256
+func Hash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// DetectMIME 根据文件头识别图片真实类型
+func DetectMIME(data []byte) string {
+	// net/http 不识别 AVIF，按 ISOBMFF ftyp box 判断。
+	if len(data) >= 12 && string(data[4:8]) == "ftyp" {
+		switch string(data[8:12]) {
+		case "avif", "avis":
+			return MIMEAVIF
+		}
+	}
+	return http.DetectContentType(data)
+}
+
+// Process 识别并转码封面：非 JPEG 转为 JPEG，按配置缩放并压缩到字节预算内。
+func Process(data []byte, opts Options) (*Result, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("empty cover data")
+	}
+	if opts.Quality <= 0 || opts.Quality > 100 {
+		opts.Quality = jpeg.DefaultQuality
+	}
+
+	mimeType := DetectMIME(data)
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrUnsupportedFormat, mimeType, err)
+	}
+	bounds := img.Bounds()
+
+	full := Image{Data: data, MIME: MIMEJPEG, Width: bounds.Dx(), Height: bounds.Dy()}
+	if mimeType != MIMEJPEG {
+		encoded, err := encodeJPEG(img, opts.Quality)
+		if err != nil {
+			return nil, err
+		}
+		full.Data = encoded
+	}
+
+	result := &Result{Full: full, Embed: full}
+	if fits(full, opts) {
+		return result, nil
+	}
+
+	embed, err := shrink(img, opts)
+	if err != nil {
+		return nil, err
+	}
+	result.Embed = *embed
+
+	return result, nil
+}
+
+func fits(img Image, opts Options) bool {
+	if opts.MaxDimension > 0 && (img.Width > opts.MaxDimension || img.Height > opts.MaxDimension) {
+		return false
+	}
+	if opts.MaxBytes > 0 && len(img.Data) > opts.MaxBytes {
+		return false
+	}
+	return true
+}
+
+// shrink 先缩放到最大边长，再逐步降低质量与尺寸直到满足字节预算。
+func shrink(src image.Image, opts Options) (*Image, error) {
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if opts.MaxDimension > 0 && (width > opts.MaxDimension || height > opts.MaxDimension) {
+		width, height = scaleToFit(width, height, opts.MaxDimension)
+	}
+
+	quality := opts.Quality
+	for {
+		scaled := resize(src, width, height)
+		data, err := encodeJPEG(scaled, quality)
+		if err != nil {
+			return nil, err
+		}
+
+		out := &Image{Data: data, MIME: MIMEJPEG, Width: width, Height: height}
+		if opts.MaxBytes <= 0 || len(data) <= opts.MaxBytes {
+			return out, nil
+		}
+
+		switch {
+		case quality > 60:
+			quality -= 10
+		case width > minDimension && height > minDimension:
+			width, height = scaleToFit(width, height, max(width, height)*3/4)
+		default:
+			// 已降到下限，返回当前最优结果。
+			return out, nil
+		}
+	}
+}
+
+func scaleToFit(width, height, limit int) (int, int) {
+	if width >= height {
+		return limit, max(1, height*limit/width)
+	}
+	return max(1, width*limit/height), limit
+}
+
+func resize(src image.Image, width, height int) image.Image {
+	bounds := src.Bounds()
+	if bounds.Dx() == width && bounds.Dy() == height {
+		return src
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	xdraw.CatmullRom.Scale(dst, dst.Bounds(), src, bounds, xdraw.Over, nil)
+	return dst
+}
+
+// encodeJPEG 编码为 JPEG，透明背景铺白色。
+func encodeJPEG(img image.Image, quality int) ([]byte, error) {
+	bounds := img.Bounds()
+	canvas := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(canvas, canvas.Bounds(), &image.Uniform{C: color.White}, image.Point{}, draw.Src)
+	draw.Draw(canvas, canvas.Bounds(), img, bounds.Min, draw.Over)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, canvas, &jpeg.Options{Quality: quality}); err != nil {
+		return nil, fmt.Errorf("failed to encode jpeg: %w", err)
+	}
+	return buf.Bytes(), nil
+}
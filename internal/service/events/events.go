@@ -0,0 +1,189 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/azin/gdstudio-embed-service/internal/config"
+	"github.com/azin/gdstudio-embed-service/internal/model"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// channelPrefix Redis pub/sub 频道前缀，每个任务一个频道：embed:job-events:<jobID>
+const channelPrefix = "embed:job-events:"
+
+// 事件类型
+const (
+	TypeStatus   = "status"   // 状态变化
+	TypeProgress = "progress" // 下载进度
+)
+
+// Event 任务事件
+type Event struct {
+	Type           string    `json:"type"`
+	JobID          string    `json:"job_id"`
+	Status         string    `json:"status,omitempty"`
+	Message        string    `json:"message,omitempty"`
+	Error          string    `json:"error,omitempty"`
+	Progress       int       `json:"progress"`
+	CompletedBytes int64     `json:"completed_bytes,omitempty"`
+	TotalBytes     int64     `json:"total_bytes,omitempty"`
+	Timestamp      time.Time `json:"timestamp"`
+}
+
+// Terminal 事件是否表示任务已结束
+func (e *Event) Terminal() bool {
+	if e.Type != TypeStatus {
+		return false
+	}
+	switch e.Status {
+	case model.JobStatusDone, model.JobStatusFailed, model.JobStatusCancelled:
+		return true
+	}
+	return false
+}
+
+// SnapshotEvent 以任务当前状态构造一个状态事件，用于订阅开始时推送
+func SnapshotEvent(job *model.Job) Event {
+	return Event{
+		Type:           TypeStatus,
+		JobID:          job.ID,
+		Status:         job.Status,
+		Message:        job.Message,
+		Error:          job.Error,
+		Progress:       job.Progress,
+		CompletedBytes: job.CompletedBytes,
+		TotalBytes:     job.TotalBytes,
+		Timestamp:      job.UpdatedAt,
+	}
+}
+
+// Bus 基于 Redis pub/sub 的任务事件总线。nil Bus 的发布操作为空操作。
+type Bus struct {
+	client    *redis.Client
+	listeners []func(Event)
+	logger    *zap.Logger
+}
+
+// NewBus 创建事件总线
+func NewBus(cfg *config.RedisConfig, logger *zap.Logger) *Bus {
+	return &Bus{
+		client: redis.NewClient(&redis.Options{
+			Addr: cfg.URL,
+			DB:   cfg.DB,
+		}),
+		logger: logger,
+	}
+}
+
+// Close 关闭 Redis 连接
+func (b *Bus) Close() error {
+	if b == nil {
+		return nil
+	}
+	return b.client.Close()
+}
+
+// AddListener 注册本进程内的事件监听（例如 webhook 投递），在 Publish 时同步调用。
+// 需在开始发布事件前注册。
+func (b *Bus) AddListener(fn func(Event)) {
+	b.listeners = append(b.listeners, fn)
+}
+
+// Publish 发布任务事件（最佳努力，失败只记录日志）
+func (b *Bus) Publish(ctx context.Context, event Event) {
+	if b == nil {
+		return
+	}
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+	for _, listener := range b.listeners {
+		listener(event)
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	if err := b.client.Publish(ctx, channelPrefix+event.JobID, data).Err(); err != nil {
+		b.logger.Debug("failed to publish job event", zap.String("job_id", event.JobID), zap.Error(err))
+	}
+}
+
+// PublishStatus 发布状态变化事件
+func (b *Bus) PublishStatus(jobID, status, message string) {
+	b.Publish(context.Background(), Event{
+		Type:    TypeStatus,
+		JobID:   jobID,
+		Status:  status,
+		Message: message,
+	})
+}
+
+// Subscription 事件订阅
+type Subscription struct {
+	pubsub *redis.PubSub
+	events chan Event
+	done   chan struct{}
+}
+
+// Subscribe 订阅指定任务的事件；jobIDs 为空时订阅全部任务。
+// 返回时订阅已生效，调用方应在之后再读取任务快照，避免遗漏事件。
+func (b *Bus) Subscribe(ctx context.Context, jobIDs []string) (*Subscription, error) {
+	var pubsub *redis.PubSub
+	if len(jobIDs) == 0 {
+		pubsub = b.client.PSubscribe(ctx, channelPrefix+"*")
+	} else {
+		channels := make([]string, len(jobIDs))
+		for i, id := range jobIDs {
+			channels[i] = channelPrefix + id
+		}
+		pubsub = b.client.Subscribe(ctx, channels...)
+	}
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, err
+	}
+
+	sub := &Subscription{
+		pubsub: pubsub,
+		events: make(chan Event, 64),
+		done:   make(chan struct{}),
+	}
+	go sub.forward(b.logger)
+	return sub, nil
+}
+
+// Events 返回事件通道，订阅关闭后通道关闭
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Close 取消订阅
+func (s *Subscription) Close() error {
+	close(s.done)
+	return s.pubsub.Close()
+}
+
+func (s *Subscription) forward(logger *zap.Logger) {
+	defer close(s.events)
+	for msg := range s.pubsub.Channel() {
+		var event Event
+		if err := json.Unmarshal([]byte(msg.Payload), &event); err != nil {
+			logger.Debug("invalid job event", zap.String("channel", msg.Channel), zap.Error(err))
+			continue
+		}
+		if event.JobID == "" {
+			event.JobID = strings.TrimPrefix(msg.Channel, channelPrefix)
+		}
+		select {
+		case s.events <- event:
+		case <-s.done:
+			return
+		}
+	}
+}
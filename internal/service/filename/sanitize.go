@@ -0,0 +1,167 @@
+package filename
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// 文件名清理策略
+const (
+	ProfilePOSIX   = "posix"        // 仅替换 "/" 与控制字符
+	ProfileWindows = "windows-safe" // 额外将全角标点转为半角，并处理 NTFS/SMB 不允许的字符与保留名（CON、NUL 等）
+	ProfileASCII   = "ascii"        // 在 windows-safe 基础上转写为 ASCII，无法转写的字符替换为 "_"
+)
+
+// MaxNameBytes 大多数文件系统单个路径组件的 UTF-8 字节上限
+const MaxNameBytes = 255
+
+// fileNameReserve 为文件名预留的字节数，供冲突后缀 " (N)" 与 ".<lang>.lrc" 等 sidecar 扩展名使用
+const fileNameReserve = 16
+
+const replacement = "_"
+
+var windowsInvalid = `\:*?"<>|`
+
+// windowsReserved Windows 保留设备名（不区分大小写，带扩展名同样保留）
+var windowsReserved = map[string]struct{}{
+	"CON": {}, "PRN": {}, "AUX": {}, "NUL": {},
+	"COM1": {}, "COM2": {}, "COM3": {}, "COM4": {}, "COM5": {}, "COM6": {}, "COM7": {}, "COM8": {}, "COM9": {},
+	"LPT1": {}, "LPT2": {}, "LPT3": {}, "LPT4": {}, "LPT5": {}, "LPT6": {}, "LPT7": {}, "LPT8": {}, "LPT9": {},
+}
+
+// asciiFallback NFKD 分解后仍不是 ASCII 的常见拉丁字母
+var asciiFallback = map[rune]string{
+	'ß': "ss", 'æ': "ae", 'Æ': "AE", 'ø': "o", 'Ø': "O", 'œ': "oe", 'Œ': "OE",
+	'đ': "d", 'Đ': "D", 'ł': "l", 'Ł': "L", 'þ': "th", 'Þ': "TH", 'ð': "d", 'Ð': "D",
+	'‘': "'", '’': "'", '“': "'", '”': "'", '–': "-", '—': "-", '…': "...",
+}
+
+// Sanitize 清理单个路径组件（目录名），结果不超过 MaxNameBytes 字节。
+func Sanitize(name, profile string) string {
+	name = truncateBytes(clean(name, profile), MaxNameBytes)
+	return finish(name, profile)
+}
+
+// SanitizeFile 清理文件名主体并拼接扩展名（ext 含 "."），截断时保留扩展名。
+func SanitizeFile(base, ext, profile string) string {
+	limit := MaxNameBytes - len(ext) - fileNameReserve
+	base = truncateBytes(clean(base, profile), limit)
+	return finish(base, profile) + ext
+}
+
+// clean 执行 NFC 归一化、字符替换与首尾空白/点号清理
+func clean(name, profile string) string {
+	name = norm.NFC.String(name)
+	switch profile {
+	case ProfileASCII:
+		name = transliterate(name)
+	case ProfileWindows:
+		name = narrowPunctuation(name)
+	}
+
+	var b strings.Builder
+	for _, r := range name {
+		switch {
+		case r == '/' || r == 0:
+			b.WriteString(replacement)
+		case unicode.IsControl(r):
+			// 丢弃控制字符（换行、制表符等）
+		case profile != ProfilePOSIX && strings.ContainsRune(windowsInvalid, r):
+			b.WriteString(replacement)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	name = b.String()
+	if profile == ProfileASCII {
+		// 大段无法转写的文字只保留一个占位符
+		for strings.Contains(name, replacement+replacement) {
+			name = strings.ReplaceAll(name, replacement+replacement, replacement)
+		}
+	}
+	return trim(name)
+}
+
+// finish 截断后再次清理首尾，并处理空名与 Windows 保留名
+func finish(name, profile string) string {
+	name = trim(name)
+	if name == "" {
+		return replacement
+	}
+	if profile != ProfilePOSIX {
+		stem, _, _ := strings.Cut(name, ".")
+		if _, ok := windowsReserved[strings.ToUpper(strings.TrimSpace(stem))]; ok {
+			name = replacement + name
+		}
+	}
+	return name
+}
+
+// trim 去掉首尾空白与点号（前导点会生成隐藏文件，NTFS 不允许结尾点号和空格）
+func trim(name string) string {
+	return strings.TrimFunc(name, func(r rune) bool {
+		return r == '.' || unicode.IsSpace(r)
+	})
+}
+
+// narrowPunctuation 将全角 ASCII 字符（"：" "？" "．" 等）与全角空格转为半角，
+// 之后按半角字符处理 Windows 非法字符与结尾点号。不改动汉字、假名等其他全角文字
+func narrowPunctuation(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= '\uFF01' && r <= '\uFF5E':
+			return r - 0xFEE0
+		case r == '\u3000':
+			return ' '
+		default:
+			return r
+		}
+	}, name)
+}
+
+// transliterate 转写为 ASCII：全角转半角、去掉变音符号，其余非 ASCII 字符替换为 "_"
+func transliterate(name string) string {
+	name = norm.NFKD.String(name)
+
+	var b strings.Builder
+	lastReplaced := false
+	for _, r := range name {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			continue
+		case r < utf8.RuneSelf:
+			b.WriteRune(r)
+			lastReplaced = false
+		case asciiFallback[r] != "":
+			b.WriteString(asciiFallback[r])
+			lastReplaced = false
+		case unicode.IsSpace(r):
+			b.WriteByte(' ')
+			lastReplaced = false
+		default:
+			if !lastReplaced {
+				b.WriteString(replacement)
+			}
+			lastReplaced = true
+		}
+	}
+	return b.String()
+}
+
+// truncateBytes 按 UTF-8 字节数截断，不切断多字节字符
+func truncateBytes(s string, limit int) string {
+	if limit <= 0 {
+		return ""
+	}
+	if len(s) <= limit {
+		return s
+	}
+	cut := limit
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut]
+}
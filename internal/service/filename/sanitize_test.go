@@ -0,0 +1,65 @@
+package filename
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSanitize(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		profile string
+		want    string
+	}{
+		{name: "posix keeps windows characters", in: `AC/DC: Live?`, profile: ProfilePOSIX, want: `AC_DC: Live?`},
+		{name: "windows replaces invalid characters", in: `AC/DC: "Live" <1>|*`, profile: ProfileWindows, want: `AC_DC_ _Live_ _1___`},
+		{name: "windows narrows full-width punctuation", in: "晴天：Live（2004）！", profile: ProfileWindows, want: "晴天_Live(2004)!"},
+		{name: "windows keeps kana and han", in: "アイドル　周杰伦", profile: ProfileWindows, want: "アイドル 周杰伦"},
+		{name: "windows trims full-width trailing dot", in: "Album．", profile: ProfileWindows, want: "Album"},
+		{name: "posix keeps full-width punctuation", in: "晴天：Live", profile: ProfilePOSIX, want: "晴天：Live"},
+		{name: "control characters dropped", in: "a\tb\nc\x00d", profile: ProfilePOSIX, want: "abc_d"},
+		{name: "leading and trailing dots and spaces", in: " ..hidden. ", profile: ProfilePOSIX, want: "hidden"},
+		{name: "empty becomes placeholder", in: " . ", profile: ProfileWindows, want: "_"},
+		{name: "reserved device name", in: "con", profile: ProfileWindows, want: "_con"},
+		{name: "reserved device name with extension", in: "NUL.txt", profile: ProfileWindows, want: "_NUL.txt"},
+		{name: "reserved name allowed on posix", in: "CON", profile: ProfilePOSIX, want: "CON"},
+		{name: "nfc normalization", in: "Beyonce\u0301", profile: ProfilePOSIX, want: "Beyonc\u00e9"},
+		{name: "ascii strips diacritics", in: "Beyoncé Motörhead", profile: ProfileASCII, want: "Beyonce Motorhead"},
+		{name: "ascii fallback letters", in: "Straße Ærø — “Hi”", profile: ProfileASCII, want: "Strasse AEro - 'Hi'"},
+		{name: "ascii full-width to half-width", in: "ＡＢＣ：１２３", profile: ProfileASCII, want: "ABC_123"},
+		{name: "ascii collapses untransliterable runs", in: "周杰伦 - 晴天", profile: ProfileASCII, want: "_ - _"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Sanitize(tt.in, tt.profile); got != tt.want {
+				t.Errorf("Sanitize(%q, %q) = %q, want %q", tt.in, tt.profile, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSanitizeTruncation(t *testing.T) {
+	long := strings.Repeat("晴", 200) // 600 字节
+
+	dir := Sanitize(long, ProfileWindows)
+	if len(dir) > MaxNameBytes || !utf8.ValidString(dir) {
+		t.Errorf("Sanitize: %d bytes, valid UTF-8 %v", len(dir), utf8.ValidString(dir))
+	}
+
+	file := SanitizeFile(long, ".flac", ProfileWindows)
+	if !strings.HasSuffix(file, ".flac") {
+		t.Errorf("SanitizeFile dropped extension: %q", file)
+	}
+	if len(file) > MaxNameBytes-fileNameReserve || !utf8.ValidString(file) {
+		t.Errorf("SanitizeFile: %d bytes, valid UTF-8 %v", len(file), utf8.ValidString(file))
+	}
+
+	// 截断后露出的结尾点号同样要去掉
+	dotted := strings.Repeat("a", MaxNameBytes-1) + ".b"
+	if got := Sanitize(dotted, ProfileWindows); strings.HasSuffix(got, ".") {
+		t.Errorf("truncated name ends with a dot: %q", got[len(got)-5:])
+	}
+}
@@ -0,0 +1,138 @@
+package fsperm
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/azin/gdstudio-embed-service/internal/config"
+)
+
+// Policy 曲库输出文件的权限与属主策略
+type Policy struct {
+	FileMode     os.FileMode
+	DirMode      os.FileMode
+	UID          int  // -1 表示不修改
+	GID          int  // -1 表示不修改
+	InheritGroup bool // 新文件/目录继承父目录的属组，目录设置 setgid 位
+}
+
+// NewPolicy 从存储配置解析权限策略
+func NewPolicy(cfg *config.StorageConfig) (*Policy, error) {
+	fileMode, err := parseMode(cfg.FileMode, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("invalid storage.file_mode: %w", err)
+	}
+	dirMode, err := parseMode(cfg.DirMode, 0755)
+	if err != nil {
+		return nil, fmt.Errorf("invalid storage.dir_mode: %w", err)
+	}
+
+	return &Policy{
+		FileMode:     fileMode,
+		DirMode:      dirMode,
+		UID:          cfg.UID,
+		GID:          cfg.GID,
+		InheritGroup: cfg.InheritGroup,
+	}, nil
+}
+
+func parseMode(value string, fallback os.FileMode) (os.FileMode, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	mode, err := strconv.ParseUint(value, 8, 32)
+	if err != nil || mode > 0777 {
+		return 0, fmt.Errorf("expected octal permission like 0644, got %q", value)
+	}
+	return os.FileMode(mode), nil
+}
+
+// ApplyFile 为曲库中的文件设置权限与属主
+func (p *Policy) ApplyFile(path string) error {
+	if p == nil {
+		return nil
+	}
+	if err := os.Chmod(path, p.FileMode); err != nil {
+		return err
+	}
+	return p.chown(path)
+}
+
+// ApplyDir 为曲库中的目录设置权限与属主
+func (p *Policy) ApplyDir(path string) error {
+	if p == nil {
+		return nil
+	}
+	mode := p.DirMode
+	if p.InheritGroup {
+		mode |= os.ModeSetgid
+	}
+	if err := os.Chmod(path, mode); err != nil {
+		return err
+	}
+	return p.chown(path)
+}
+
+// MkdirAll 创建目录，仅对新创建的各级目录应用权限与属主，已存在的目录保持不变。
+func (p *Policy) MkdirAll(path string) error {
+	if p == nil {
+		return os.MkdirAll(path, 0755)
+	}
+
+	path = filepath.Clean(path)
+	if info, err := os.Stat(path); err == nil {
+		if !info.IsDir() {
+			return fmt.Errorf("%s is not a directory", path)
+		}
+		return nil
+	}
+
+	if parent := filepath.Dir(path); parent != path {
+		if err := p.MkdirAll(parent); err != nil {
+			return err
+		}
+	}
+	if err := os.Mkdir(path, p.DirMode); err != nil {
+		if os.IsExist(err) {
+			return nil
+		}
+		return err
+	}
+	return p.ApplyDir(path)
+}
+
+// Check 启动时校验：在 dir 中创建临时文件并应用属主，确认进程确实有权限 chown。
+func (p *Policy) Check(dir string) error {
+	if p == nil || (p.UID < 0 && p.GID < 0 && !p.InheritGroup) {
+		return nil
+	}
+
+	if err := p.MkdirAll(dir); err != nil {
+		return fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	tmp, err := os.CreateTemp(dir, ".fsperm-check-*")
+	if err != nil {
+		return fmt.Errorf("failed to create probe file in %s: %w", dir, err)
+	}
+	tmpPath := tmp.Name()
+	tmp.Close()
+	defer os.Remove(tmpPath)
+
+	if err := p.ApplyFile(tmpPath); err != nil {
+		return fmt.Errorf("cannot apply ownership uid=%d gid=%d in %s: %w", p.UID, p.GID, dir, err)
+	}
+	return nil
+}
+
+func (p *Policy) chown(path string) error {
+	gid := p.GID
+	if p.InheritGroup && gid < 0 {
+		gid = parentGID(path)
+	}
+	if p.UID < 0 && gid < 0 {
+		return nil
+	}
+	return os.Lchown(path, p.UID, gid)
+}
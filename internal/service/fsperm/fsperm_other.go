@@ -0,0 +1,8 @@
+//go:build !unix
+
+package fsperm
+
+// parentGID 非 Unix 平台不支持属组继承
+func parentGID(path string) int {
+	return -1
+}
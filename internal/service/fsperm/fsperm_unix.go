@@ -0,0 +1,21 @@
+//go:build unix
+
+package fsperm
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// parentGID 返回父目录的属组，无法获取时返回 -1
+func parentGID(path string) int {
+	info, err := os.Stat(filepath.Dir(path))
+	if err != nil {
+		return -1
+	}
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return int(stat.Gid)
+	}
+	return -1
+}
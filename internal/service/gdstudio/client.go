@@ -1,6 +1,7 @@
 package gdstudio
 
 import (
+	"context"
 	"crypto/md5"
 	"fmt"
 	"net/url"
@@ -10,6 +11,7 @@ import (
 	"time"
 
 	"github.com/azin/gdstudio-embed-service/internal/config"
+	"github.com/azin/gdstudio-embed-service/internal/tracing"
 	"github.com/go-resty/resty/v2"
 	"go.uber.org/zap"
 )
@@ -27,6 +29,7 @@ func NewClient(cfg *config.GDStudioConfig, logger *zap.Logger) *Client {
 		SetTimeout(cfg.Timeout).
 		SetRetryCount(cfg.RetryCount).
 		SetHeader("User-Agent", "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15")
+	tracing.InstrumentResty(client, "gdstudio")
 
 	return &Client{
 		cfg:    cfg,
@@ -55,7 +58,7 @@ type LyricResult struct {
 }
 
 // ResolveAuxIDs 通过搜索结果反查 pic_id / lyric_id。
-func (c *Client) ResolveAuxIDs(source, trackID, title, artist string) (string, string, error) {
+func (c *Client) ResolveAuxIDs(ctx context.Context, source, trackID, title, artist string) (string, string, error) {
 	keywords := buildSearchKeywords(trackID, title, artist)
 	if len(keywords) == 0 {
 		return "", "", fmt.Errorf("search keyword is empty")
@@ -63,7 +66,7 @@ func (c *Client) ResolveAuxIDs(source, trackID, title, artist string) (string, s
 
 	var lastErr error
 	for _, keyword := range keywords {
-		items, err := c.searchTracks(source, keyword)
+		items, err := c.searchTracks(ctx, source, keyword)
 		if err != nil {
 			lastErr = err
 			continue
@@ -90,7 +93,7 @@ func (c *Client) ResolveAuxIDs(source, trackID, title, artist string) (string, s
 }
 
 // ResolveURL 解析播放链接
-func (c *Client) ResolveURL(source, trackID string, br int) (*URLResult, error) {
+func (c *Client) ResolveURL(ctx context.Context, source, trackID string, br int) (*URLResult, error) {
 	c.logger.Info("resolving url",
 		zap.String("source", source),
 		zap.String("track_id", trackID),
@@ -101,6 +104,7 @@ func (c *Client) ResolveURL(source, trackID string, br int) (*URLResult, error)
 
 	var result map[string]interface{}
 	resp, err := c.client.R().
+		SetContext(ctx).
 		SetQueryParams(map[string]string{
 			"types":  "url",
 			"source": source,
@@ -155,7 +159,7 @@ func (c *Client) ResolveURL(source, trackID string, br int) (*URLResult, error)
 }
 
 // ResolveCover 解析封面
-func (c *Client) ResolveCover(source, picID string) (string, error) {
+func (c *Client) ResolveCover(ctx context.Context, source, picID string) (string, error) {
 	if picID == "" {
 		return "", nil
 	}
@@ -164,7 +168,7 @@ func (c *Client) ResolveCover(source, picID string) (string, error) {
 	sizes := []int{1000, 640, 500, 300}
 	var lastErr error
 	for _, size := range sizes {
-		coverURL, err := c.resolveCoverWithSize(source, picID, size)
+		coverURL, err := c.resolveCoverWithSize(ctx, source, picID, size)
 		if err == nil {
 			return coverURL, nil
 		}
@@ -178,7 +182,7 @@ func (c *Client) ResolveCover(source, picID string) (string, error) {
 	return "", fmt.Errorf("cover url not found")
 }
 
-func (c *Client) resolveCoverWithSize(source, picID string, size int) (string, error) {
+func (c *Client) resolveCoverWithSize(ctx context.Context, source, picID string, size int) (string, error) {
 	c.logger.Debug("resolving cover",
 		zap.String("source", source),
 		zap.String("pic_id", picID),
@@ -189,6 +193,7 @@ func (c *Client) resolveCoverWithSize(source, picID string, size int) (string, e
 
 	var result map[string]interface{}
 	resp, err := c.client.R().
+		SetContext(ctx).
 		SetQueryParams(map[string]string{
 			"types":  "pic",
 			"source": source,
@@ -219,7 +224,7 @@ func (c *Client) resolveCoverWithSize(source, picID string, size int) (string, e
 }
 
 // ResolveLyrics 解析歌词
-func (c *Client) ResolveLyrics(source, lyricID string) (*LyricResult, error) {
+func (c *Client) ResolveLyrics(ctx context.Context, source, lyricID string) (*LyricResult, error) {
 	if lyricID == "" {
 		return nil, nil
 	}
@@ -233,6 +238,7 @@ func (c *Client) ResolveLyrics(source, lyricID string) (*LyricResult, error) {
 
 	var result map[string]interface{}
 	resp, err := c.client.R().
+		SetContext(ctx).
 		SetQueryParams(map[string]string{
 			"types":  "lyric",
 			"source": source,
@@ -271,7 +277,7 @@ func (c *Client) ResolveLyrics(source, lyricID string) (*LyricResult, error) {
 }
 
 // DownloadCover 下载封面数据
-func (c *Client) DownloadCover(source, coverURL string) ([]byte, error) {
+func (c *Client) DownloadCover(ctx context.Context, source, coverURL string) ([]byte, error) {
 	if coverURL == "" {
 		return nil, nil
 	}
@@ -282,7 +288,9 @@ func (c *Client) DownloadCover(source, coverURL string) ([]byte, error) {
 
 	for _, candidate := range candidates {
 		req := c.client.R().
-			SetHeader("Accept", "image/avif,image/webp,image/apng,image/*,*/*;q=0.8")
+			SetContext(ctx).
+			// 不声明 AVIF：封面需要转码为 JPEG，而 AVIF 无法解码；CDN 仍返回 AVIF 时按原格式嵌入。
+			SetHeader("Accept", "image/jpeg,image/png,image/webp;q=0.9,image/*;q=0.8,*/*;q=0.5")
 		if referer != "" {
 			req.SetHeader("Referer", referer)
 		}
@@ -319,6 +327,20 @@ func (c *Client) DownloadCover(source, coverURL string) ([]byte, error) {
 	return nil, fmt.Errorf("cover download failed")
 }
 
+// Ping 检查 API 入口是否可达（只看 HTTP 层，不消耗解析配额）
+func (c *Client) Ping(ctx context.Context) error {
+	resp, err := c.client.R().
+		SetContext(ctx).
+		Head(c.cfg.BaseURL)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	if resp.StatusCode() >= 500 {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode())
+	}
+	return nil
+}
+
 // selectBaseURL 根据 source 选择合适的 API 入口
 func (c *Client) selectBaseURL(source string) string {
 	source = strings.ToLower(source)
@@ -478,11 +500,12 @@ func buildSearchKeywords(trackID, title, artist string) []string {
 	return out
 }
 
-func (c *Client) searchTracks(source, keyword string) ([]map[string]interface{}, error) {
+func (c *Client) searchTracks(ctx context.Context, source, keyword string) ([]map[string]interface{}, error) {
 	baseURL := c.selectBaseURL(source)
 
 	var result []map[string]interface{}
 	resp, err := c.client.R().
+		SetContext(ctx).
 		SetQueryParams(map[string]string{
 			"types":  "search",
 			"source": source,
@@ -0,0 +1,259 @@
+package library
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/azin/gdstudio-embed-service/internal/config"
+	"github.com/azin/gdstudio-embed-service/internal/model"
+	"github.com/azin/gdstudio-embed-service/internal/repository"
+	"github.com/azin/gdstudio-embed-service/internal/service/tagger"
+	"go.uber.org/zap"
+)
+
+// Index 曲库索引：扫描 MusicDir 读取标签并缓存到数据库，用于下载前查重。
+type Index struct {
+	cfg      *config.LibraryConfig
+	musicDir string
+	repo     *repository.LibraryRepository
+	logger   *zap.Logger
+}
+
+// NewIndex 创建曲库索引
+func NewIndex(cfg *config.LibraryConfig, musicDir string, repo *repository.LibraryRepository, logger *zap.Logger) *Index {
+	return &Index{
+		cfg:      cfg,
+		musicDir: musicDir,
+		repo:     repo,
+		logger:   logger,
+	}
+}
+
+// Query 查重条件
+type Query struct {
+	Title    string
+	Artist   string
+	Album    string
+	ISRC     string
+	Duration int // 秒，未知时为 0
+}
+
+// Run 启动时扫描一次，之后按 index_interval 周期扫描，直到 ctx 取消。
+func (i *Index) Run(ctx context.Context) {
+	if !i.cfg.IndexEnabled {
+		return
+	}
+
+	ticker := time.NewTicker(i.cfg.IndexInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := i.Scan(ctx); err != nil {
+			i.logger.Warn("library scan failed", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Scan 遍历曲库目录更新索引。大小与修改时间未变的文件不重新读取标签。
+func (i *Index) Scan(ctx context.Context) error {
+	start := time.Now()
+	var indexed, unchanged, failed, skippedDirs int
+
+	err := filepath.WalkDir(i.musicDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// 曲库根目录不可读时整体失败；其下无法读取的目录或文件跳过，不中断整次扫描
+			if path == i.musicDir {
+				return err
+			}
+			if d != nil && d.IsDir() {
+				i.logger.Warn("skipping unreadable library directory", zap.String("path", path), zap.Error(err))
+				skippedDirs++
+				return filepath.SkipDir
+			}
+			i.logger.Debug("skipping unreadable library file", zap.String("path", path), zap.Error(err))
+			failed++
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if strings.HasPrefix(d.Name(), ".") && path != i.musicDir {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() || !isAudioFile(path) {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			failed++
+			return nil
+		}
+
+		existing, err := i.repo.FindByPath(path)
+		if err != nil {
+			return err
+		}
+		if existing != nil && existing.Size == info.Size() && existing.ModTime.Unix() == info.ModTime().Unix() {
+			unchanged++
+			return i.repo.Touch(existing.ID, time.Now())
+		}
+
+		jobID := ""
+		if existing != nil {
+			jobID = existing.JobID
+		}
+		if err := i.index(path, info, jobID); err != nil {
+			i.logger.Debug("failed to index file", zap.String("path", path), zap.Error(err))
+			failed++
+			return nil
+		}
+		indexed++
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// 跳过的目录中的文件本次没有被访问，不能按过期删除
+	var removed int64
+	if skippedDirs == 0 {
+		removed, err = i.repo.DeleteStale(start)
+		if err != nil {
+			return err
+		}
+	}
+
+	i.logger.Info("library scan completed",
+		zap.Int("indexed", indexed),
+		zap.Int("unchanged", unchanged),
+		zap.Int("failed", failed),
+		zap.Int("skipped_dirs", skippedDirs),
+		zap.Int64("removed", removed),
+		zap.Duration("elapsed", time.Since(start)))
+	return nil
+}
+
+// IndexFile 将单个文件（通常是刚入库的下载结果）加入索引。
+func (i *Index) IndexFile(path, jobID string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	return i.index(path, info, jobID)
+}
+
+// Remove 从索引中移除文件
+func (i *Index) Remove(path string) error {
+	return i.repo.DeletePath(path)
+}
+
+func (i *Index) index(path string, info fs.FileInfo, jobID string) error {
+	tags, err := tagger.ReadTags(path)
+	if err != nil {
+		return err
+	}
+
+	return i.repo.Upsert(&model.LibraryTrack{
+		Path:       path,
+		Format:     tags.Format,
+		Title:      tags.Title,
+		Artist:     tags.Artist,
+		Album:      tags.Album,
+		ISRC:       strings.ToUpper(strings.TrimSpace(tags.ISRC)),
+		NormArtist: NormalizeArtist(tags.Artist),
+		NormTitle:  NormalizeTitle(tags.Title),
+		NormAlbum:  NormalizeAlbum(tags.Album),
+		Duration:   tags.Duration,
+		Bitrate:    tags.Bitrate,
+		Size:       info.Size(),
+		ModTime:    info.ModTime(),
+		JobID:      jobID,
+		IndexedAt:  time.Now(),
+	})
+}
+
+// FindDuplicate 查找曲库中的同一首歌：优先按 ISRC，其次按归一化的艺术家/标题，
+// 双方都有专辑名或时长时再校验专辑与时长。多个候选时返回音质最高的一个。
+func (i *Index) FindDuplicate(q Query) (*model.LibraryTrack, error) {
+	if isrc := strings.ToUpper(strings.TrimSpace(q.ISRC)); isrc != "" {
+		tracks, err := i.repo.FindByISRC(isrc)
+		if err != nil {
+			return nil, err
+		}
+		if best := bestQuality(tracks); best != nil {
+			return best, nil
+		}
+	}
+
+	artist := NormalizeArtist(q.Artist)
+	title := NormalizeTitle(q.Title)
+	if artist == "" || title == "" {
+		return nil, nil
+	}
+
+	candidates, err := i.repo.FindByNormalized(artist, title)
+	if err != nil {
+		return nil, err
+	}
+
+	album := NormalizeAlbum(q.Album)
+	tolerance := int(i.cfg.DurationTolerance / time.Second)
+	var matched []*model.LibraryTrack
+	for _, track := range candidates {
+		if album != "" && track.NormAlbum != "" && album != track.NormAlbum {
+			continue
+		}
+		if q.Duration > 0 && track.Duration > 0 {
+			diff := q.Duration - track.Duration
+			if diff < -tolerance || diff > tolerance {
+				continue
+			}
+		}
+		matched = append(matched, track)
+	}
+
+	return bestQuality(matched), nil
+}
+
+// QualityScore 音质评分：无损格式高于任何有损码率，无损之间视为相同。
+func QualityScore(format string, bitrate int) int {
+	switch strings.ToLower(strings.TrimPrefix(format, ".")) {
+	case "flac", "wav", "ape", "alac":
+		return 10000
+	default:
+		return bitrate
+	}
+}
+
+func bestQuality(tracks []*model.LibraryTrack) *model.LibraryTrack {
+	var best *model.LibraryTrack
+	for _, track := range tracks {
+		if best == nil || QualityScore(track.Format, track.Bitrate) > QualityScore(best.Format, best.Bitrate) {
+			best = track
+		}
+	}
+	return best
+}
+
+func isAudioFile(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".mp3", ".flac":
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,52 @@
+package library
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+var (
+	featPattern      = regexp.MustCompile(`(?i)[(\[（【]\s*(feat\.?|ft\.?|featuring)\s[^)\]）】]*[)\]）】]`)
+	featSuffix       = regexp.MustCompile(`(?i)\s(feat\.?|ft\.?|featuring)\s.*$`)
+	artistSeparators = []string{"/", ",", "，", ";", "；", "&", "、"}
+)
+
+// NormalizeTitle 归一化标题：全角转半角、小写、去掉 feat. 段与标点空白。
+// 括号中的版本信息（Live、Remix 等）保留，避免不同版本被误判为重复。
+func NormalizeTitle(title string) string {
+	title = norm.NFKC.String(title)
+	title = featPattern.ReplaceAllString(title, "")
+	title = featSuffix.ReplaceAllString(title, "")
+	return compact(title)
+}
+
+// NormalizeArtist 归一化艺术家，仅取第一位艺术家。
+func NormalizeArtist(artist string) string {
+	artist = norm.NFKC.String(artist)
+	artist = featSuffix.ReplaceAllString(artist, "")
+	for _, sep := range artistSeparators {
+		if idx := strings.Index(artist, sep); idx >= 0 {
+			artist = artist[:idx]
+		}
+	}
+	return compact(artist)
+}
+
+// NormalizeAlbum 归一化专辑名
+func NormalizeAlbum(album string) string {
+	return compact(norm.NFKC.String(album))
+}
+
+// compact 小写并只保留字母与数字
+func compact(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsNumber(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
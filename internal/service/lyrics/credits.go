@@ -0,0 +1,34 @@
+package lyrics
+
+import (
+	"regexp"
+	"strings"
+)
+
+// creditPattern 匹配歌词来源常见的署名行，例如 "作词 : 张三"、"Composed by: xx"。
+var creditPattern = regexp.MustCompile(`(?i)^(作词|作曲|编曲|词|曲|制作人|监制|制作|混音|母带|和声|录音|出品|发行|OP|SP|lyricist|lyrics by|composer|composed by|arranger|arranged by|producer|produced by)\s*[:：]`)
+
+// IsCredit 判断是否为署名行
+func IsCredit(text string) bool {
+	return creditPattern.MatchString(strings.TrimSpace(text))
+}
+
+// StripCredits 移除署名行，返回新的 Document。
+func StripCredits(d *Document) *Document {
+	if d == nil {
+		return nil
+	}
+
+	out := &Document{Tags: d.Tags}
+	for _, line := range d.Lines {
+		if !IsCredit(line.Text) {
+			out.Lines = append(out.Lines, line)
+		}
+	}
+	for _, text := range d.Unsynced {
+		if !IsCredit(text) {
+			out.Unsynced = append(out.Unsynced, text)
+		}
+	}
+	return out
+}
@@ -0,0 +1,50 @@
+package lyrics
+
+import "unicode"
+
+// DetectLanguage 根据文字脚本粗略判断歌词语言，返回 ISO 639-2 代码（ID3 USLT/SYLT 要求三位代码）。
+func DetectLanguage(text string) string {
+	var han, kana, hangul, latin int
+	for _, r := range text {
+		switch {
+		case unicode.In(r, unicode.Hiragana, unicode.Katakana):
+			kana++
+		case unicode.Is(unicode.Hangul, r):
+			hangul++
+		case unicode.Is(unicode.Han, r):
+			han++
+		case unicode.Is(unicode.Latin, r):
+			latin++
+		}
+	}
+
+	switch {
+	case kana > 0 && kana*10 >= han:
+		// 日文通常混有汉字，假名占比足够即可判定。
+		return "jpn"
+	case hangul > 0 && hangul >= han:
+		return "kor"
+	case han > 0 && han*2 >= latin:
+		return "chi"
+	case latin > 0:
+		return "eng"
+	default:
+		return "und"
+	}
+}
+
+// ShortLanguageCode 将 ISO 639-2 代码转换为用于文件名的 ISO 639-1 代码（如 song.zh.lrc）。
+func ShortLanguageCode(code string) string {
+	switch code {
+	case "chi", "zho":
+		return "zh"
+	case "jpn":
+		return "ja"
+	case "kor":
+		return "ko"
+	case "eng":
+		return "en"
+	default:
+		return code
+	}
+}
@@ -0,0 +1,238 @@
+package lyrics
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	timestampPattern = regexp.MustCompile(`\[(\d{1,3}):(\d{1,2})(?:[.:](\d{1,3}))?\]`)
+	tagPattern       = regexp.MustCompile(`^\[([A-Za-z#]+):(.*)\]$`)
+)
+
+// Line 一行带时间轴的歌词
+type Line struct {
+	Time time.Duration
+	Text string
+}
+
+// Document 解析后的 LRC 歌词
+type Document struct {
+	Tags     map[string]string // ti/ar/al/by 等头部标签（小写键）
+	Lines    []Line            // 按时间排序的同步歌词
+	Unsynced []string          // 无时间轴的纯文本行
+}
+
+// Parse 解析 LRC 文本。支持一行多个时间戳、[offset:] 偏移以及无时间轴的纯文本。
+func Parse(raw string) *Document {
+	doc := &Document{Tags: map[string]string{}}
+	raw = strings.TrimPrefix(raw, "\ufeff")
+
+	for _, rawLine := range strings.Split(strings.ReplaceAll(raw, "\r\n", "\n"), "\n") {
+		line := strings.TrimSpace(rawLine)
+		if line == "" {
+			continue
+		}
+
+		matches := timestampPattern.FindAllStringSubmatchIndex(line, -1)
+		if len(matches) == 0 || matches[0][0] != 0 {
+			if m := tagPattern.FindStringSubmatch(line); m != nil {
+				doc.Tags[strings.ToLower(m[1])] = strings.TrimSpace(m[2])
+				continue
+			}
+			doc.Unsynced = append(doc.Unsynced, line)
+			continue
+		}
+
+		// 时间戳必须连续出现在行首，其后才是歌词文本。
+		textStart := 0
+		var times []time.Duration
+		for _, m := range matches {
+			if m[0] != textStart {
+				break
+			}
+			times = append(times, parseTimestamp(line, m))
+			textStart = m[1]
+		}
+
+		text := strings.TrimSpace(line[textStart:])
+		for _, ts := range times {
+			doc.Lines = append(doc.Lines, Line{Time: ts, Text: text})
+		}
+	}
+
+	if raw, ok := doc.Tags["offset"]; ok {
+		if offset, err := strconv.Atoi(strings.TrimSpace(raw)); err == nil {
+			// 正偏移表示歌词提前显示。
+			shift := time.Duration(offset) * time.Millisecond
+			for i := range doc.Lines {
+				doc.Lines[i].Time -= shift
+				if doc.Lines[i].Time < 0 {
+					doc.Lines[i].Time = 0
+				}
+			}
+		}
+		delete(doc.Tags, "offset")
+	}
+
+	sort.SliceStable(doc.Lines, func(i, j int) bool {
+		return doc.Lines[i].Time < doc.Lines[j].Time
+	})
+
+	return doc
+}
+
+func parseTimestamp(line string, m []int) time.Duration {
+	minutes, _ := strconv.Atoi(line[m[2]:m[3]])
+	seconds, _ := strconv.Atoi(line[m[4]:m[5]])
+	ts := time.Duration(minutes)*time.Minute + time.Duration(seconds)*time.Second
+
+	if m[6] >= 0 {
+		frac := line[m[6]:m[7]]
+		value, _ := strconv.Atoi(frac)
+		switch len(frac) {
+		case 1:
+			ts += time.Duration(value) * 100 * time.Millisecond
+		case 2:
+			ts += time.Duration(value) * 10 * time.Millisecond
+		default:
+			ts += time.Duration(value) * time.Millisecond
+		}
+	}
+
+	return ts
+}
+
+// Synced 是否包含时间轴
+func (d *Document) Synced() bool {
+	return d != nil && len(d.Lines) > 0
+}
+
+// Empty 是否没有任何歌词内容
+func (d *Document) Empty() bool {
+	return d == nil || (len(d.Lines) == 0 && len(d.Unsynced) == 0)
+}
+
+// Text 返回去掉时间轴后的纯文本
+func (d *Document) Text() string {
+	if d == nil {
+		return ""
+	}
+
+	var texts []string
+	if d.Synced() {
+		for _, line := range d.Lines {
+			if line.Text != "" {
+				texts = append(texts, line.Text)
+			}
+		}
+	} else {
+		texts = d.Unsynced
+	}
+	return strings.Join(texts, "\n")
+}
+
+// String 序列化为 LRC 文本（不含头部标签）
+func (d *Document) String() string {
+	if d == nil {
+		return ""
+	}
+	if !d.Synced() {
+		return strings.Join(d.Unsynced, "\n")
+	}
+
+	var b strings.Builder
+	for _, line := range d.Lines {
+		b.WriteString(FormatTimestamp(line.Time))
+		b.WriteString(line.Text)
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// FormatTimestamp 格式化为 [mm:ss.xx]
+func FormatTimestamp(ts time.Duration) string {
+	if ts < 0 {
+		ts = 0
+	}
+	centis := int64(ts / (10 * time.Millisecond))
+	return fmt.Sprintf("[%02d:%02d.%02d]", centis/6000, centis/100%60, centis%100)
+}
+
+// Merge 将翻译歌词按时间戳合并到原文下方，生成双语歌词。
+func Merge(original, translation *Document) *Document {
+	if !original.Synced() || !translation.Synced() {
+		return original
+	}
+
+	translated := make(map[time.Duration][]string)
+	for _, line := range translation.Lines {
+		text := strings.TrimSpace(line.Text)
+		if text == "" || text == "//" {
+			continue
+		}
+		key := line.Time.Truncate(10 * time.Millisecond)
+		translated[key] = append(translated[key], text)
+	}
+
+	merged := &Document{Tags: original.Tags, Unsynced: original.Unsynced}
+	for _, line := range original.Lines {
+		merged.Lines = append(merged.Lines, line)
+		if line.Text == "" {
+			continue
+		}
+		key := line.Time.Truncate(10 * time.Millisecond)
+		for _, text := range translated[key] {
+			if text != line.Text {
+				merged.Lines = append(merged.Lines, Line{Time: line.Time, Text: text})
+			}
+		}
+		delete(translated, key)
+	}
+
+	return merged
+}
+
+// Header LRC 头部标签信息
+type Header struct {
+	Title  string
+	Artist string
+	Album  string
+	Length time.Duration
+}
+
+// Render 输出带 [ti:]/[ar:]/[al:]/[length:] 头部的 LRC 文本。
+// 头部字段为空时沿用原歌词中的同名标签。
+func (d *Document) Render(h Header) string {
+	var b strings.Builder
+	writeTag := func(key, value string) {
+		value = strings.TrimSpace(value)
+		if value == "" && d != nil {
+			value = d.Tags[key]
+		}
+		if value != "" {
+			fmt.Fprintf(&b, "[%s:%s]\n", key, value)
+		}
+	}
+
+	writeTag("ti", h.Title)
+	writeTag("ar", h.Artist)
+	writeTag("al", h.Album)
+	length := ""
+	if h.Length > 0 {
+		seconds := int(h.Length / time.Second)
+		length = fmt.Sprintf("%02d:%02d", seconds/60, seconds%60)
+	}
+	writeTag("length", length)
+
+	body := d.String()
+	if body != "" && !strings.HasSuffix(body, "\n") {
+		body += "\n"
+	}
+	b.WriteString(body)
+	return b.String()
+}
@@ -0,0 +1,142 @@
+package lyrics
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func ms(v int) time.Duration { return time.Duration(v) * time.Millisecond }
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name         string
+		raw          string
+		wantLines    []Line
+		wantUnsynced []string
+		wantTags     map[string]string
+	}{
+		{
+			name:      "basic",
+			raw:       "[00:01.00]第一句\n[00:02.50]第二句",
+			wantLines: []Line{{ms(1000), "第一句"}, {ms(2500), "第二句"}},
+			wantTags:  map[string]string{},
+		},
+		{
+			name:      "fraction precision",
+			raw:       "[00:01.5]a\n[00:01.05]b\n[00:01.005]c\n[01:02]d",
+			wantLines: []Line{{ms(1005), "c"}, {ms(1050), "b"}, {ms(1500), "a"}, {ms(62000), "d"}},
+			wantTags:  map[string]string{},
+		},
+		{
+			name:      "multiple timestamps sorted",
+			raw:       "[00:10.00][00:01.00]副歌\n[00:05.00]主歌",
+			wantLines: []Line{{ms(1000), "副歌"}, {ms(5000), "主歌"}, {ms(10000), "副歌"}},
+			wantTags:  map[string]string{},
+		},
+		{
+			name:      "tags, bom and crlf",
+			raw:       "\ufeff[ti:晴天]\r\n[AR: 周杰伦 ]\r\n[00:01.00]歌词\r\n",
+			wantLines: []Line{{ms(1000), "歌词"}},
+			wantTags:  map[string]string{"ti": "晴天", "ar": "周杰伦"},
+		},
+		{
+			name:      "positive offset shifts earlier and clamps at zero",
+			raw:       "[offset:500]\n[00:00.20]a\n[00:01.00]b",
+			wantLines: []Line{{0, "a"}, {ms(500), "b"}},
+			wantTags:  map[string]string{},
+		},
+		{
+			name:         "unsynced text",
+			raw:          "第一行\n\n第二行 [00:01.00] 不在行首",
+			wantUnsynced: []string{"第一行", "第二行 [00:01.00] 不在行首"},
+			wantTags:     map[string]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := Parse(tt.raw)
+			if !reflect.DeepEqual(doc.Lines, tt.wantLines) {
+				t.Errorf("Lines = %v, want %v", doc.Lines, tt.wantLines)
+			}
+			if !reflect.DeepEqual(doc.Unsynced, tt.wantUnsynced) {
+				t.Errorf("Unsynced = %q, want %q", doc.Unsynced, tt.wantUnsynced)
+			}
+			if !reflect.DeepEqual(doc.Tags, tt.wantTags) {
+				t.Errorf("Tags = %v, want %v", doc.Tags, tt.wantTags)
+			}
+		})
+	}
+}
+
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		name        string
+		original    string
+		translation string
+		want        string
+	}{
+		{
+			name:        "translation below original",
+			original:    "[00:01.00]Hello\n[00:02.00]World",
+			translation: "[00:01.00]你好\n[00:02.00]世界",
+			want:        "[00:01.00]Hello\n[00:01.00]你好\n[00:02.00]World\n[00:02.00]世界\n",
+		},
+		{
+			name:        "skips placeholders, duplicates and empty original lines",
+			original:    "[00:01.00]Hello\n[00:02.00]\n[00:03.00]Same",
+			translation: "[00:01.00]//\n[00:02.00]空行翻译\n[00:03.00]Same",
+			want:        "[00:01.00]Hello\n[00:02.00]\n[00:03.00]Same\n",
+		},
+		{
+			name:        "millisecond jitter within 10ms",
+			original:    "[00:01.001]Hello",
+			translation: "[00:01.009]你好",
+			want:        "[00:01.00]Hello\n[00:01.00]你好\n",
+		},
+		{
+			name:        "unsynced translation keeps original",
+			original:    "[00:01.00]Hello",
+			translation: "你好",
+			want:        "[00:01.00]Hello\n",
+		},
+		{
+			name:        "empty original",
+			original:    "",
+			translation: "[00:01.00]你好",
+			want:        "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Merge(Parse(tt.original), Parse(tt.translation)).String()
+			if got != tt.want {
+				t.Errorf("Merge() =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRender(t *testing.T) {
+	doc := Parse("[ti:Old]\n[al:Album]\n[00:01.00]歌词")
+	got := doc.Render(Header{Title: "New", Artist: "Artist", Length: 3*time.Minute + 5*time.Second})
+	want := "[ti:New]\n[ar:Artist]\n[al:Album]\n[length:03:05]\n[00:01.00]歌词\n"
+	if got != want {
+		t.Errorf("Render() =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestFormatTimestamp(t *testing.T) {
+	tests := map[time.Duration]string{
+		-time.Second:               "[00:00.00]",
+		ms(1234):                   "[00:01.23]",
+		61*time.Minute + ms(59990): "[61:59.99]",
+	}
+	for in, want := range tests {
+		if got := FormatTimestamp(in); got != want {
+			t.Errorf("FormatTimestamp(%v) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package maintenance
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/azin/gdstudio-embed-service/internal/config"
+	"github.com/azin/gdstudio-embed-service/internal/repository"
+	"go.uber.org/zap"
+)
+
+// PurgeResult 一次清理的结果（dry-run 时为将被删除的数量）
+type PurgeResult struct {
+	DryRun            bool      `json:"dry_run"`
+	JobsBefore        time.Time `json:"jobs_before"`
+	SoftDeletedBefore time.Time `json:"soft_deleted_before"`
+	SoftDeleted       int64     `json:"soft_deleted"`       // 本次软删除的已结束任务数
+	HardDeleted       int64     `json:"hard_deleted"`       // 本次彻底删除的软删除任务数
+	DeliveriesDeleted int64     `json:"deliveries_deleted"` // 本次删除的 webhook 投递记录数（与任务使用相同保留期）
+}
+
+// Purger 按保留策略清理任务记录
+type Purger struct {
+	cfg      *config.RetentionConfig
+	repo     *repository.JobRepository
+	webhooks *repository.WebhookRepository
+	logger   *zap.Logger
+}
+
+// NewPurger 创建任务记录清理器
+func NewPurger(
+	cfg *config.RetentionConfig,
+	repo *repository.JobRepository,
+	webhooks *repository.WebhookRepository,
+	logger *zap.Logger,
+) *Purger {
+	return &Purger{
+		cfg:      cfg,
+		repo:     repo,
+		webhooks: webhooks,
+		logger:   logger,
+	}
+}
+
+// Purge 软删除超过保留期的已结束任务，并彻底删除软删除超过宽限期的任务。
+// dryRun 为 true 时只统计数量，不做修改。
+func (p *Purger) Purge(dryRun bool) (*PurgeResult, error) {
+	now := time.Now()
+	result := &PurgeResult{
+		DryRun:            dryRun,
+		JobsBefore:        now.Add(-p.cfg.JobRetention),
+		SoftDeletedBefore: now.Add(-p.cfg.HardDeleteAfter),
+	}
+
+	var err error
+	if dryRun {
+		if result.SoftDeleted, err = p.repo.CountOldJobs(result.JobsBefore); err != nil {
+			return nil, fmt.Errorf("failed to count old jobs: %w", err)
+		}
+		if result.HardDeleted, err = p.repo.CountSoftDeleted(result.SoftDeletedBefore); err != nil {
+			return nil, fmt.Errorf("failed to count soft deleted jobs: %w", err)
+		}
+		if result.DeliveriesDeleted, err = p.webhooks.CountDeliveriesBefore(result.JobsBefore); err != nil {
+			return nil, fmt.Errorf("failed to count webhook deliveries: %w", err)
+		}
+		return result, nil
+	}
+
+	// 先彻底删除旧的软删除记录，避免本次刚软删除的记录被同时清掉。
+	if result.HardDeleted, err = p.repo.PurgeSoftDeleted(result.SoftDeletedBefore); err != nil {
+		return nil, fmt.Errorf("failed to purge soft deleted jobs: %w", err)
+	}
+	if result.SoftDeleted, err = p.repo.DeleteOldJobs(result.JobsBefore); err != nil {
+		return nil, fmt.Errorf("failed to delete old jobs: %w", err)
+	}
+	if result.DeliveriesDeleted, err = p.webhooks.DeleteDeliveriesBefore(result.JobsBefore); err != nil {
+		return nil, fmt.Errorf("failed to delete webhook deliveries: %w", err)
+	}
+
+	p.logger.Info("job retention purge completed",
+		zap.Int64("soft_deleted", result.SoftDeleted),
+		zap.Int64("hard_deleted", result.HardDeleted),
+		zap.Int64("deliveries_deleted", result.DeliveriesDeleted))
+	return result, nil
+}
@@ -1,12 +1,14 @@
 package navidrome
 
 import (
+	"context"
 	"crypto/md5"
 	"fmt"
 	"strings"
 	"time"
 
 	"github.com/azin/gdstudio-embed-service/internal/config"
+	"github.com/azin/gdstudio-embed-service/internal/tracing"
 	"github.com/go-resty/resty/v2"
 	"go.uber.org/zap"
 )
@@ -25,6 +27,7 @@ func NewClient(cfg *config.NavidromeConfig, logger *zap.Logger) *Client {
 	client := resty.New().
 		SetTimeout(30 * time.Second).
 		SetHeader("User-Agent", "echo-embed/1.0")
+	tracing.InstrumentResty(client, "navidrome")
 
 	// 生成 token 和 salt
 	salt := generateSalt()
@@ -46,7 +49,7 @@ type ScanStatus struct {
 }
 
 // StartScan 触发扫描
-func (c *Client) StartScan() error {
+func (c *Client) StartScan(ctx context.Context) error {
 	c.logger.Info("starting navidrome scan")
 
 	var result struct {
@@ -57,6 +60,7 @@ func (c *Client) StartScan() error {
 	}
 
 	resp, err := c.client.R().
+		SetContext(ctx).
 		SetQueryParams(c.authParams()).
 		SetResult(&result).
 		Get(c.cfg.BaseURL + "/rest/startScan")
@@ -78,7 +82,7 @@ func (c *Client) StartScan() error {
 }
 
 // GetScanStatus 查询扫描状态
-func (c *Client) GetScanStatus() (*ScanStatus, error) {
+func (c *Client) GetScanStatus(ctx context.Context) (*ScanStatus, error) {
 	c.logger.Debug("getting scan status")
 
 	var result struct {
@@ -89,6 +93,7 @@ func (c *Client) GetScanStatus() (*ScanStatus, error) {
 	}
 
 	resp, err := c.client.R().
+		SetContext(ctx).
 		SetQueryParams(c.authParams()).
 		SetResult(&result).
 		Get(c.cfg.BaseURL + "/rest/getScanStatus")
@@ -113,7 +118,7 @@ func (c *Client) GetScanStatus() (*ScanStatus, error) {
 }
 
 // WaitForScan 等待扫描完成
-func (c *Client) WaitForScan(timeout time.Duration) error {
+func (c *Client) WaitForScan(ctx context.Context, timeout time.Duration) error {
 	c.logger.Info("waiting for scan to complete", zap.Duration("timeout", timeout))
 
 	deadline := time.Now().Add(timeout)
@@ -122,10 +127,12 @@ func (c *Client) WaitForScan(timeout time.Duration) error {
 
 	for {
 		select {
+		case <-ctx.Done():
+			return ctx.Err()
 		case <-time.After(time.Until(deadline)):
 			return fmt.Errorf("scan timeout after %v", timeout)
 		case <-ticker.C:
-			status, err := c.GetScanStatus()
+			status, err := c.GetScanStatus(ctx)
 			if err != nil {
 				c.logger.Warn("failed to get scan status", zap.Error(err))
 				continue
@@ -142,7 +149,7 @@ func (c *Client) WaitForScan(timeout time.Duration) error {
 }
 
 // Ping 测试连接
-func (c *Client) Ping() error {
+func (c *Client) Ping(ctx context.Context) error {
 	c.logger.Debug("pinging navidrome")
 
 	var result struct {
@@ -153,6 +160,7 @@ func (c *Client) Ping() error {
 	}
 
 	resp, err := c.client.R().
+		SetContext(ctx).
 		SetQueryParams(c.authParams()).
 		SetResult(&result).
 		Get(c.cfg.BaseURL + "/rest/ping")
@@ -169,7 +177,7 @@ func (c *Client) Ping() error {
 		return fmt.Errorf("ping failed: status=%s", result.SubsonicResponse.Status)
 	}
 
-	c.logger.Info("ping successful", zap.String("version", result.SubsonicResponse.Version))
+	c.logger.Debug("ping successful", zap.String("version", result.SubsonicResponse.Version))
 	return nil
 }
 
@@ -0,0 +1,347 @@
+// Package queue 基于 asynq.Inspector 查看与操作 Redis 中的任务队列，并将任务状态与数据库对账。
+package queue
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/azin/gdstudio-embed-service/internal/errcode"
+	"github.com/azin/gdstudio-embed-service/internal/repository"
+	"github.com/azin/gdstudio-embed-service/internal/service/events"
+	"github.com/azin/gdstudio-embed-service/internal/tasks"
+	"github.com/hibiken/asynq"
+	"go.uber.org/zap"
+)
+
+// 任务状态（asynq 的 TaskState 字符串形式）
+const (
+	StatePending   = "pending"
+	StateActive    = "active"
+	StateScheduled = "scheduled"
+	StateRetry     = "retry"
+	StateArchived  = "archived"
+	StateCompleted = "completed"
+)
+
+// States 可查询的任务状态
+var States = []string{StatePending, StateActive, StateScheduled, StateRetry, StateArchived, StateCompleted}
+
+// scanPageSize 遍历队列时每页读取的任务数
+const scanPageSize = 500
+
+// QueueInfo 队列概况
+type QueueInfo struct {
+	Queue     string    `json:"queue"`
+	Paused    bool      `json:"paused"`
+	Size      int       `json:"size"` // 除 completed 外的任务总数
+	Pending   int       `json:"pending"`
+	Active    int       `json:"active"`
+	Scheduled int       `json:"scheduled"`
+	Retry     int       `json:"retry"`
+	Archived  int       `json:"archived"`
+	Completed int       `json:"completed"`
+	Processed int       `json:"processed"` // 今日处理数
+	Failed    int       `json:"failed"`    // 今日失败数
+	LatencyMS int64     `json:"latency_ms"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// TaskInfo 队列中的单个任务
+type TaskInfo struct {
+	ID            string     `json:"id"`
+	Queue         string     `json:"queue"`
+	Type          string     `json:"type"`
+	State         string     `json:"state"`
+	JobID         string     `json:"job_id,omitempty"` // 载荷中的 job_id（下载、升级与 webhook 投递任务）
+	Retried       int        `json:"retried"`
+	MaxRetry      int        `json:"max_retry"`
+	LastError     string     `json:"last_error,omitempty"`
+	LastFailedAt  *time.Time `json:"last_failed_at,omitempty"`
+	NextProcessAt *time.Time `json:"next_process_at,omitempty"`
+}
+
+// BulkResult 批量操作结果
+type BulkResult struct {
+	Queue    string `json:"queue"`
+	Affected int    `json:"affected"`
+}
+
+// Inspector 队列查看与控制
+type Inspector struct {
+	inspector *asynq.Inspector
+	repo      *repository.JobRepository
+	events    *events.Bus
+	logger    *zap.Logger
+}
+
+// NewInspector 创建队列查看器
+func NewInspector(inspector *asynq.Inspector, repo *repository.JobRepository, eventBus *events.Bus, logger *zap.Logger) *Inspector {
+	return &Inspector{
+		inspector: inspector,
+		repo:      repo,
+		events:    eventBus,
+		logger:    logger,
+	}
+}
+
+// Queues 列出全部队列及其概况
+func (i *Inspector) Queues() ([]*QueueInfo, error) {
+	names, err := i.inspector.Queues()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list queues: %w", err)
+	}
+
+	queues := make([]*QueueInfo, 0, len(names))
+	for _, name := range names {
+		info, err := i.inspector.GetQueueInfo(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get queue %s: %w", name, err)
+		}
+		queues = append(queues, newQueueInfo(info))
+	}
+	return queues, nil
+}
+
+// Queue 查询单个队列
+func (i *Inspector) Queue(name string) (*QueueInfo, error) {
+	if err := i.ensureQueue(name); err != nil {
+		return nil, err
+	}
+	info, err := i.inspector.GetQueueInfo(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get queue %s: %w", name, err)
+	}
+	return newQueueInfo(info), nil
+}
+
+// Tasks 按状态分页列出队列中的任务，page 从 1 开始
+func (i *Inspector) Tasks(queue, state string, page, pageSize int) ([]*TaskInfo, error) {
+	if err := i.ensureQueue(queue); err != nil {
+		return nil, err
+	}
+	tasks, err := i.list(queue, state, asynq.Page(page), asynq.PageSize(pageSize))
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]*TaskInfo, 0, len(tasks))
+	for _, task := range tasks {
+		out = append(out, newTaskInfo(task))
+	}
+	return out, nil
+}
+
+// Pause 暂停队列（已暂停时直接返回当前状态）
+func (i *Inspector) Pause(queue string) (*QueueInfo, error) {
+	return i.setPaused(queue, true)
+}
+
+// Resume 恢复队列（未暂停时直接返回当前状态）
+func (i *Inspector) Resume(queue string) (*QueueInfo, error) {
+	return i.setPaused(queue, false)
+}
+
+func (i *Inspector) setPaused(queue string, paused bool) (*QueueInfo, error) {
+	info, err := i.Queue(queue)
+	if err != nil {
+		return nil, err
+	}
+	if info.Paused == paused {
+		return info, nil
+	}
+
+	if paused {
+		err = i.inspector.PauseQueue(queue)
+	} else {
+		err = i.inspector.UnpauseQueue(queue)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update queue %s: %w", queue, err)
+	}
+	i.logger.Info("queue state changed", zap.String("queue", queue), zap.Bool("paused", paused))
+
+	return i.Queue(queue)
+}
+
+// DeleteTask 删除 pending / scheduled / retry / archived 状态的任务
+func (i *Inspector) DeleteTask(queue, taskID string) error {
+	if _, err := i.taskInState(queue, taskID, StatePending, StateScheduled, StateRetry, StateArchived); err != nil {
+		return err
+	}
+	if err := i.inspector.DeleteTask(queue, taskID); err != nil {
+		return i.taskError(err)
+	}
+	i.logger.Info("queue task deleted", zap.String("queue", queue), zap.String("task_id", taskID))
+	return nil
+}
+
+// RunTask 立即执行 scheduled / retry / archived 状态的任务
+func (i *Inspector) RunTask(queue, taskID string) (*TaskInfo, error) {
+	if _, err := i.taskInState(queue, taskID, StateScheduled, StateRetry, StateArchived); err != nil {
+		return nil, err
+	}
+	if err := i.inspector.RunTask(queue, taskID); err != nil {
+		return nil, i.taskError(err)
+	}
+	i.logger.Info("queue task scheduled to run", zap.String("queue", queue), zap.String("task_id", taskID))
+
+	task, err := i.inspector.GetTaskInfo(queue, taskID)
+	if err != nil {
+		return nil, i.taskError(err)
+	}
+	return newTaskInfo(task), nil
+}
+
+// RunAllArchived 重新执行队列中全部 archived 任务
+func (i *Inspector) RunAllArchived(queue string) (*BulkResult, error) {
+	if err := i.ensureQueue(queue); err != nil {
+		return nil, err
+	}
+	n, err := i.inspector.RunAllArchivedTasks(queue)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run archived tasks: %w", err)
+	}
+	i.logger.Info("archived tasks scheduled to run", zap.String("queue", queue), zap.Int("count", n))
+	return &BulkResult{Queue: queue, Affected: n}, nil
+}
+
+// DeleteAllArchived 删除队列中全部 archived 任务
+func (i *Inspector) DeleteAllArchived(queue string) (*BulkResult, error) {
+	if err := i.ensureQueue(queue); err != nil {
+		return nil, err
+	}
+	n, err := i.inspector.DeleteAllArchivedTasks(queue)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete archived tasks: %w", err)
+	}
+	i.logger.Info("archived tasks deleted", zap.String("queue", queue), zap.Int("count", n))
+	return &BulkResult{Queue: queue, Affected: n}, nil
+}
+
+// ensureQueue 检查队列存在。asynq 对不存在的队列并非总是返回 ErrQueueNotFound，统一在此判断
+func (i *Inspector) ensureQueue(name string) error {
+	names, err := i.inspector.Queues()
+	if err != nil {
+		return fmt.Errorf("failed to list queues: %w", err)
+	}
+	for _, n := range names {
+		if n == name {
+			return nil
+		}
+	}
+	return errcode.New(errcode.QueueNotFound, "queue not found")
+}
+
+// taskInState 查询任务并检查其状态允许该操作
+func (i *Inspector) taskInState(queue, taskID string, allowed ...string) (*asynq.TaskInfo, error) {
+	if err := i.ensureQueue(queue); err != nil {
+		return nil, err
+	}
+	task, err := i.inspector.GetTaskInfo(queue, taskID)
+	if err != nil {
+		return nil, i.taskError(err)
+	}
+
+	state := task.State.String()
+	for _, s := range allowed {
+		if s == state {
+			return task, nil
+		}
+	}
+	return nil, errcode.New(errcode.InvalidState, fmt.Sprintf("task is %s", state))
+}
+
+func (i *Inspector) taskError(err error) error {
+	switch {
+	case errors.Is(err, asynq.ErrTaskNotFound):
+		return errcode.New(errcode.TaskNotFound, "task not found")
+	case errors.Is(err, asynq.ErrQueueNotFound):
+		return errcode.New(errcode.QueueNotFound, "queue not found")
+	}
+	return err
+}
+
+// list 按状态读取一页任务
+func (i *Inspector) list(queue, state string, opts ...asynq.ListOption) ([]*asynq.TaskInfo, error) {
+	var (
+		tasks []*asynq.TaskInfo
+		err   error
+	)
+	switch state {
+	case StatePending:
+		tasks, err = i.inspector.ListPendingTasks(queue, opts...)
+	case StateActive:
+		tasks, err = i.inspector.ListActiveTasks(queue, opts...)
+	case StateScheduled:
+		tasks, err = i.inspector.ListScheduledTasks(queue, opts...)
+	case StateRetry:
+		tasks, err = i.inspector.ListRetryTasks(queue, opts...)
+	case StateArchived:
+		tasks, err = i.inspector.ListArchivedTasks(queue, opts...)
+	case StateCompleted:
+		tasks, err = i.inspector.ListCompletedTasks(queue, opts...)
+	default:
+		return nil, fmt.Errorf("unsupported task state: %s", state)
+	}
+	if err != nil {
+		return nil, i.taskError(fmt.Errorf("failed to list %s tasks: %w", state, err))
+	}
+	return tasks, nil
+}
+
+// scan 遍历队列中某状态的全部任务
+func (i *Inspector) scan(queue, state string, fn func(*asynq.TaskInfo)) error {
+	for page := 1; ; page++ {
+		tasks, err := i.list(queue, state, asynq.Page(page), asynq.PageSize(scanPageSize))
+		if err != nil {
+			return err
+		}
+		for _, task := range tasks {
+			fn(task)
+		}
+		if len(tasks) < scanPageSize {
+			return nil
+		}
+	}
+}
+
+func newQueueInfo(info *asynq.QueueInfo) *QueueInfo {
+	return &QueueInfo{
+		Queue:     info.Queue,
+		Paused:    info.Paused,
+		Size:      info.Size,
+		Pending:   info.Pending,
+		Active:    info.Active,
+		Scheduled: info.Scheduled,
+		Retry:     info.Retry,
+		Archived:  info.Archived,
+		Completed: info.Completed,
+		Processed: info.Processed,
+		Failed:    info.Failed,
+		LatencyMS: info.Latency.Milliseconds(),
+		Timestamp: info.Timestamp,
+	}
+}
+
+func newTaskInfo(task *asynq.TaskInfo) *TaskInfo {
+	info := &TaskInfo{
+		ID:        task.ID,
+		Queue:     task.Queue,
+		Type:      task.Type,
+		State:     task.State.String(),
+		JobID:     tasks.PayloadJobID(task.Payload),
+		Retried:   task.Retried,
+		MaxRetry:  task.MaxRetry,
+		LastError: task.LastErr,
+	}
+	if !task.LastFailedAt.IsZero() {
+		t := task.LastFailedAt
+		info.LastFailedAt = &t
+	}
+	if !task.NextProcessAt.IsZero() {
+		t := task.NextProcessAt
+		info.NextProcessAt = &t
+	}
+	return info
+}
@@ -0,0 +1,191 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/azin/gdstudio-embed-service/internal/errcode"
+	"github.com/azin/gdstudio-embed-service/internal/model"
+	"github.com/azin/gdstudio-embed-service/internal/repository"
+	"github.com/azin/gdstudio-embed-service/internal/service/events"
+	"github.com/azin/gdstudio-embed-service/internal/tasks"
+	"github.com/hibiken/asynq"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// DefaultStaleAfter 对账只处理超过该时长未更新的任务，避免误判刚创建、尚未入队的任务
+const DefaultStaleAfter = 10 * time.Minute
+
+// unfinishedStatuses 应当在队列中有对应下载任务的状态
+var unfinishedStatuses = []string{
+	model.JobStatusQueued,
+	model.JobStatusResolving,
+	model.JobStatusDownloading,
+	model.JobStatusTagging,
+	model.JobStatusMoving,
+	model.JobStatusScanning,
+}
+
+// ReconcileResult 一次对账的结果（dry-run 时为将要执行的修改）
+type ReconcileResult struct {
+	DryRun      bool            `json:"dry_run"`
+	StaleBefore time.Time       `json:"stale_before"`
+	Checked     int             `json:"checked"`      // 检查的未结束任务数
+	FailedJobs  []ReconciledJob `json:"failed_jobs"`  // 队列中没有存活任务、被标记为失败的任务
+	OrphanTasks []*TaskInfo     `json:"orphan_tasks"` // 对应任务已结束或不存在、被删除的排队任务
+}
+
+// ReconciledJob 对账中被标记失败的任务
+type ReconciledJob struct {
+	JobID  string `json:"job_id"`
+	Status string `json:"status"` // 对账前的状态
+	Reason string `json:"reason"`
+	TaskID string `json:"task_id,omitempty"` // 已归档的下载任务 ID（可通过 run 重新执行）
+}
+
+// Reconcile 将数据库中的任务状态与队列对账：
+//   - 未结束但队列中没有 pending / active / scheduled / retry 下载任务的任务标记为 TASK_LOST 失败
+//     （例如 worker 被强杀后任务已归档，或 Redis 数据丢失），之后可通过 retry 重新入队；
+//   - 排队中但对应任务已取消、已完成或已删除的下载任务从队列删除。
+//
+// 只处理 staleAfter 之前更新过的任务；dryRun 为 true 时只返回结果，不做修改。
+func (i *Inspector) Reconcile(staleAfter time.Duration, dryRun bool) (*ReconcileResult, error) {
+	result := &ReconcileResult{
+		DryRun:      dryRun,
+		StaleBefore: time.Now().Add(-staleAfter),
+		FailedJobs:  []ReconciledJob{},
+		OrphanTasks: []*TaskInfo{},
+	}
+
+	// 先读取队列再查数据库：扫描期间状态有变化的任务，其记录更新于 StaleBefore 之后，不会被误判。
+	// 按任务流转方向（retry / scheduled → pending → active）依次扫描，期间前移的任务不会漏掉
+	live := make(map[string]*asynq.TaskInfo)
+	archived := make(map[string]*asynq.TaskInfo)
+	queues, err := i.inspector.Queues()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list queues: %w", err)
+	}
+	for _, queue := range queues {
+		for _, state := range []string{StateRetry, StateScheduled, StatePending, StateActive} {
+			if err := i.scan(queue, state, collectDownloads(live)); err != nil {
+				return nil, err
+			}
+		}
+		if err := i.scan(queue, StateArchived, collectDownloads(archived)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := i.reconcileJobs(result, live, archived); err != nil {
+		return nil, err
+	}
+	if err := i.reconcileTasks(result, live); err != nil {
+		return nil, err
+	}
+
+	i.logger.Info("queue reconcile completed",
+		zap.Bool("dry_run", dryRun),
+		zap.Int("checked", result.Checked),
+		zap.Int("failed_jobs", len(result.FailedJobs)),
+		zap.Int("orphan_tasks", len(result.OrphanTasks)))
+	return result, nil
+}
+
+// reconcileJobs 标记队列中已没有存活任务的未结束任务
+func (i *Inspector) reconcileJobs(result *ReconcileResult, live, archived map[string]*asynq.TaskInfo) error {
+	filter := repository.JobFilter{Statuses: unfinishedStatuses, UpdatedBefore: &result.StaleBefore}
+	sort := repository.JobSort{Field: "updated_at"}
+
+	var cursor *repository.JobCursor
+	for {
+		jobs, err := i.repo.List(filter, sort, cursor, scanPageSize)
+		if err != nil {
+			return fmt.Errorf("failed to list unfinished jobs: %w", err)
+		}
+
+		for _, job := range jobs {
+			result.Checked++
+			if live[job.ID] != nil {
+				continue
+			}
+
+			reconciled := ReconciledJob{JobID: job.ID, Status: job.Status, Reason: "no task in queue"}
+			if task := archived[job.ID]; task != nil {
+				reconciled.TaskID = task.ID
+				reconciled.Reason = fmt.Sprintf("task archived after %d retries: %s", task.Retried, task.LastErr)
+			}
+			result.FailedJobs = append(result.FailedJobs, reconciled)
+			if result.DryRun {
+				continue
+			}
+
+			jobErr := errcode.New(errcode.TaskLost, reconciled.Reason)
+			if err := i.repo.MarkFailed(job.ID, jobErr); err != nil {
+				return fmt.Errorf("failed to mark job %s as failed: %w", job.ID, err)
+			}
+			i.events.Publish(context.Background(), events.Event{
+				Type:   events.TypeStatus,
+				JobID:  job.ID,
+				Status: model.JobStatusFailed,
+				Error:  jobErr.Error(),
+			})
+			i.logger.Warn("job marked failed by reconcile",
+				zap.String("job_id", job.ID),
+				zap.String("previous_status", job.Status),
+				zap.String("reason", reconciled.Reason))
+		}
+
+		if len(jobs) < scanPageSize {
+			return nil
+		}
+		next := sort.CursorAfter(jobs[len(jobs)-1])
+		cursor = &next
+	}
+}
+
+// reconcileTasks 删除对应任务已结束或不存在的排队下载任务（执行中的任务不处理）
+func (i *Inspector) reconcileTasks(result *ReconcileResult, live map[string]*asynq.TaskInfo) error {
+	for jobID, task := range live {
+		if task.State == asynq.TaskStateActive {
+			continue
+		}
+
+		job, err := i.repo.FindByID(jobID)
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+		case err != nil:
+			return fmt.Errorf("failed to find job %s: %w", jobID, err)
+		case job.Status == model.JobStatusCancelled || job.Status == model.JobStatusDone:
+		default:
+			// 失败的任务仍可能由 asynq 自动重试，保留
+			continue
+		}
+
+		result.OrphanTasks = append(result.OrphanTasks, newTaskInfo(task))
+		if result.DryRun {
+			continue
+		}
+		if err := i.inspector.DeleteTask(task.Queue, task.ID); err != nil && !errors.Is(err, asynq.ErrTaskNotFound) {
+			return fmt.Errorf("failed to delete orphan task %s: %w", task.ID, err)
+		}
+		i.logger.Info("orphan task deleted by reconcile",
+			zap.String("task_id", task.ID),
+			zap.String("job_id", jobID))
+	}
+	return nil
+}
+
+// collectDownloads 按 job_id 收集下载任务
+func collectDownloads(into map[string]*asynq.TaskInfo) func(*asynq.TaskInfo) {
+	return func(task *asynq.TaskInfo) {
+		if task.Type != tasks.TypeDownload {
+			return
+		}
+		if jobID := tasks.PayloadJobID(task.Payload); jobID != "" {
+			into[jobID] = task
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package tagger
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/azin/gdstudio-embed-service/internal/service/cover"
+	"go.uber.org/zap"
+)
+
+// albumCoverMarker 记录本服务写入的专辑封面哈希，用于区分用户自行放置的封面。
+const albumCoverMarker = ".embed-cover"
+
+// albumCoverLocks 同一专辑的多个任务可能并发写封面，按目录串行化。
+var albumCoverLocks sync.Map
+
+// WriteAlbumCover 为专辑目录写入封面（cover.jpg/folder.jpg 等）。
+// 目录已有封面时：内容相同则跳过；本服务写入的封面在新封面更大时替换；
+// 用户放置的封面仅在 folder_overwrite 开启且新封面更大时替换。返回是否写入了文件。
+func (t *Tagger) WriteAlbumCover(albumDir string, data []byte) (bool, error) {
+	names := t.cfg.Cover.FolderNames
+	if len(data) == 0 || len(names) == 0 {
+		return false, nil
+	}
+
+	lock, _ := albumCoverLocks.LoadOrStore(albumDir, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	defer lock.(*sync.Mutex).Unlock()
+
+	newHash := cover.Hash(data)
+	markerPath := filepath.Join(albumDir, albumCoverMarker)
+	ownHash := ""
+	if marker, err := os.ReadFile(markerPath); err == nil {
+		ownHash = strings.TrimSpace(string(marker))
+	}
+
+	target := filepath.Join(albumDir, names[0])
+	for _, name := range names {
+		path := filepath.Join(albumDir, name)
+		existing, err := os.ReadFile(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return false, fmt.Errorf("failed to read album cover: %w", err)
+		}
+
+		existingHash := cover.Hash(existing)
+		if existingHash == newHash {
+			t.logger.Debug("album cover unchanged", zap.String("path", path))
+			return false, nil
+		}
+		if existingHash != ownHash && !t.cfg.Cover.FolderOverwrite {
+			t.logger.Debug("album cover placed by user, keep it", zap.String("path", path))
+			return false, nil
+		}
+		if !isLargerImage(data, existing) {
+			return false, nil
+		}
+
+		target = path
+		break
+	}
+
+	if err := writeFileAtomic(target, data, 0644); err != nil {
+		return false, fmt.Errorf("failed to write album cover: %w", err)
+	}
+	if err := t.perms.ApplyFile(target); err != nil {
+		t.logger.Warn("failed to apply album cover permissions", zap.String("path", target), zap.Error(err))
+	}
+	if err := os.WriteFile(markerPath, []byte(newHash+"\n"), 0644); err != nil {
+		t.logger.Warn("failed to write album cover marker", zap.Error(err))
+	} else if err := t.perms.ApplyFile(markerPath); err != nil {
+		t.logger.Warn("failed to apply album cover marker permissions", zap.Error(err))
+	}
+
+	t.logger.Info("album cover written", zap.String("path", target))
+	return true, nil
+}
+
+// isLargerImage 新图片像素数是否大于旧图片；旧图片无法解码时视为更大。
+func isLargerImage(newData, oldData []byte) bool {
+	newCfg, _, err := image.DecodeConfig(bytes.NewReader(newData))
+	if err != nil {
+		return false
+	}
+	oldCfg, _, err := image.DecodeConfig(bytes.NewReader(oldData))
+	if err != nil {
+		return true
+	}
+	return newCfg.Width*newCfg.Height > oldCfg.Width*oldCfg.Height
+}
+
+// writeFileAtomic 先写同目录临时文件再 rename，避免播放器读到半截文件。
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,224 @@
+package tagger
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/azin/gdstudio-embed-service/internal/model"
+	id3v2 "github.com/bogem/id3v2/v2"
+	"go.uber.org/zap"
+)
+
+// CopyTags 将 src 的标签复制到 dst，用于替换音频文件时保留用户修改过的标签。
+// 同格式时完整复制（ID3v2 全部帧 / VorbisComment 与 PICTURE 块），跨格式时复制常用字段、歌词与封面。
+func (t *Tagger) CopyTags(src, dst string) error {
+	srcExt := strings.ToLower(filepath.Ext(src))
+	dstExt := strings.ToLower(filepath.Ext(dst))
+
+	var err error
+	switch {
+	case srcExt == ".mp3" && dstExt == ".mp3":
+		err = copyID3Frames(src, dst)
+	case srcExt == ".flac" && dstExt == ".flac":
+		err = copyFLACTags(src, dst)
+	default:
+		var metadata *model.TrackMetadata
+		metadata, err = ReadMetadata(src)
+		if err == nil {
+			err = t.WriteTags(dst, metadata)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to copy tags from %s: %w", src, err)
+	}
+
+	t.logger.Info("tags copied", zap.String("src", src), zap.String("dst", dst))
+	return nil
+}
+
+func copyID3Frames(src, dst string) error {
+	srcTag, err := id3v2.Open(src, id3v2.Options{Parse: true})
+	if err != nil {
+		return err
+	}
+	defer srcTag.Close()
+
+	dstTag, err := id3v2.Open(dst, id3v2.Options{Parse: true})
+	if err != nil {
+		return err
+	}
+	defer dstTag.Close()
+
+	dstTag.DeleteAllFrames()
+	dstTag.SetVersion(srcTag.Version())
+	for id, frames := range srcTag.AllFrames() {
+		for _, frame := range frames {
+			dstTag.AddFrame(id, frame)
+		}
+	}
+
+	return dstTag.Save()
+}
+
+func copyFLACTags(src, dst string) error {
+	srcFile, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer srcFile.Close()
+
+	srcParsed, err := readFLACMetadata(srcFile)
+	if err != nil {
+		return err
+	}
+
+	dstFile, err := os.OpenFile(dst, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer dstFile.Close()
+
+	dstParsed, err := readFLACMetadata(dstFile)
+	if err != nil {
+		return err
+	}
+
+	// 保留目标文件的流信息（STREAMINFO、SEEKTABLE 等），替换标签类块。
+	isTagBlock := func(typ byte) bool {
+		return typ == flacBlockVorbisComment || typ == flacBlockPicture
+	}
+	var blocks []flacBlock
+	for _, block := range dstParsed.blocks {
+		if block.typ != flacBlockPadding && !isTagBlock(block.typ) {
+			blocks = append(blocks, block)
+		}
+	}
+	for _, block := range srcParsed.blocks {
+		if isTagBlock(block.typ) {
+			blocks = append(blocks, block)
+		}
+	}
+
+	_, err = saveFLACBlocks(dstFile, dst, dstParsed, blocks)
+	return err
+}
+
+// ReadMetadata 读取音频文件中的标签、歌词与封面。
+func ReadMetadata(filePath string) (*model.TrackMetadata, error) {
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".mp3":
+		return readMP3Metadata(filePath)
+	case ".flac":
+		return readFLACTrackMetadata(filePath)
+	default:
+		return nil, fmt.Errorf("unsupported file format: %s", filepath.Ext(filePath))
+	}
+}
+
+func readMP3Metadata(filePath string) (*model.TrackMetadata, error) {
+	tag, err := id3v2.Open(filePath, id3v2.Options{Parse: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open mp3 file: %w", err)
+	}
+	defer tag.Close()
+
+	metadata := &model.TrackMetadata{
+		Title:       tag.Title(),
+		Artist:      tag.Artist(),
+		Album:       tag.Album(),
+		TrackNumber: leadingInt(tag.GetTextFrame(tag.CommonID("Track number/Position in set")).Text),
+		Year:        leadingInt(tag.Year()),
+	}
+	if metadata.Year == 0 {
+		metadata.Year = leadingInt(tag.GetTextFrame("TDRC").Text)
+	}
+
+	for _, frame := range tag.GetFrames(tag.CommonID("Unsynchronised lyrics/text transcription")) {
+		uslt, ok := frame.(id3v2.UnsynchronisedLyricsFrame)
+		if !ok {
+			continue
+		}
+		if uslt.ContentDescriptor == "Translation" {
+			metadata.Translation = uslt.Lyrics
+		} else if metadata.Lyrics == "" {
+			metadata.Lyrics = uslt.Lyrics
+		}
+	}
+
+	for _, frame := range tag.GetFrames(tag.CommonID("Attached picture")) {
+		pic, ok := frame.(id3v2.PictureFrame)
+		if ok && (pic.PictureType == id3v2.PTFrontCover || metadata.CoverData == nil) {
+			metadata.CoverData = pic.Picture
+			metadata.CoverMIME = pic.MimeType
+		}
+	}
+
+	return metadata, nil
+}
+
+func readFLACTrackMetadata(filePath string) (*model.TrackMetadata, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	parsed, err := readFLACMetadata(file)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := &model.TrackMetadata{}
+	for _, block := range parsed.blocks {
+		switch block.typ {
+		case flacBlockVorbisComment:
+			_, comments, err := parseVorbisComment(block.data)
+			if err != nil {
+				continue
+			}
+			for _, comment := range comments {
+				key, value, ok := strings.Cut(comment, "=")
+				if !ok {
+					continue
+				}
+				switch strings.ToUpper(key) {
+				case "TITLE":
+					metadata.Title = value
+				case "ARTIST":
+					metadata.Artist = value
+				case "ALBUM":
+					metadata.Album = value
+				case "TRACKNUMBER":
+					metadata.TrackNumber = leadingInt(value)
+				case "DATE":
+					metadata.Year = leadingInt(value)
+				case "LYRICS":
+					metadata.Lyrics = value
+				case "LYRICS_TRANSLATED":
+					metadata.Translation = value
+				}
+			}
+		case flacBlockPicture:
+			picType, data, ok := parseFLACPicture(block.data)
+			if ok && (picType == 3 || metadata.CoverData == nil) {
+				metadata.CoverData = data
+			}
+		}
+	}
+
+	return metadata, nil
+}
+
+// leadingInt 解析字符串开头的数字，例如 "3/12" -> 3、"2021-05-01" -> 2021
+func leadingInt(s string) int {
+	s = strings.TrimSpace(s)
+	end := 0
+	for end < len(s) && s[end] >= '0' && s[end] <= '9' {
+		end++
+	}
+	n, _ := strconv.Atoi(s[:end])
+	return n
+}
@@ -1,44 +1,235 @@
 package tagger
 
 import (
+	"bytes"
+	"encoding/binary"
 	"fmt"
+	"image"
+	"io"
 	"os"
-	"os/exec"
+	"path/filepath"
 	"strconv"
 	"strings"
 
 	"github.com/azin/gdstudio-embed-service/internal/model"
+	"github.com/azin/gdstudio-embed-service/internal/service/cover"
 	"go.uber.org/zap"
 )
 
-// writeFLACTags 使用 metaflac 写入 FLAC VorbisComment/PICTURE。
+// FLAC 元数据块类型，见 https://xiph.org/flac/format.html#metadata_block_header
+const (
+	flacBlockStreamInfo    byte = 0
+	flacBlockPadding       byte = 1
+	flacBlockVorbisComment byte = 4
+	flacBlockPicture       byte = 6
+)
+
+const (
+	flacMaxBlockLength = 1<<24 - 1
+	// flacDefaultPadding 重写整个文件时预留的 padding，便于后续原地更新标签。
+	flacDefaultPadding = 8 * 1024
+	flacVendor         = "gdstudio-embed-service"
+)
+
+// flacManagedTags 当前任务会覆盖的 VorbisComment 字段，写入前先删除旧值，避免重复值堆积。
+var flacManagedTags = []string{
+	"TITLE",
+	"ARTIST",
+	"ALBUM",
+	"TRACKNUMBER",
+	"DATE",
+	"LYRICS",
+	"LYRICS_TRANSLATED",
+}
+
+type flacBlock struct {
+	typ  byte
+	data []byte
+}
+
+// flacFile 描述 FLAC 文件的元数据区布局。
+type flacFile struct {
+	prefixLen int64 // "fLaC" 之前的字节数（部分文件带有 ID3v2 头）
+	metaEnd   int64 // 音频帧起始偏移
+	blocks    []flacBlock
+}
+
+// metadataLen 返回原文件中元数据块（含 padding）占用的字节数。
+func (f *flacFile) metadataLen() int64 {
+	return f.metaEnd - f.prefixLen - 4
+}
+
+// writeFLACTags 写入 FLAC VorbisComment/PICTURE。
+// 新元数据能放进原有空间（借用 padding）时原地改写，否则经临时文件整体重写后原子替换。
 func (t *Tagger) writeFLACTags(filePath string, metadata *model.TrackMetadata) error {
-	if _, err := exec.LookPath("metaflac"); err != nil {
-		return fmt.Errorf("metaflac not found, cannot write FLAC tags: %w", err)
-	}
-
-	// 先删除当前任务可能覆盖的标签，避免重复值堆积。
-	removeArgs := []string{
-		"--remove-tag=TITLE",
-		"--remove-tag=ARTIST",
-		"--remove-tag=ALBUM",
-		"--remove-tag=TRACKNUMBER",
-		"--remove-tag=DATE",
-		"--remove-tag=LYRICS",
-		"--remove-tag=LYRICS_TRANSLATED",
-		filePath,
-	}
-	if err := t.runMetaflac(removeArgs...); err != nil {
+	file, err := os.OpenFile(filePath, os.O_RDWR, 0)
+	if err != nil {
+		return fmt.Errorf("failed to open flac file: %w", err)
+	}
+	defer file.Close()
+
+	parsed, err := readFLACMetadata(file)
+	if err != nil {
 		return err
 	}
 
-	var setArgs []string
+	blocks, err := buildFLACBlocks(parsed.blocks, metadata)
+	if err != nil {
+		return err
+	}
+
+	inPlace, err := saveFLACBlocks(file, filePath, parsed, blocks)
+	if err != nil {
+		return err
+	}
+
+	t.logger.Info("FLAC tags written successfully",
+		zap.String("file", filePath),
+		zap.Bool("in_place", inPlace),
+		zap.Bool("has_cover", len(metadata.CoverData) > 0),
+		zap.Bool("has_lyrics", metadata.Lyrics != ""))
+
+	return nil
+}
+
+// saveFLACBlocks 写回元数据块（不含 padding）。新元数据能放进原有空间时原地改写并调整 padding，
+// 否则经临时文件整体重写。返回是否原地写入。
+func saveFLACBlocks(file *os.File, filePath string, parsed *flacFile, blocks []flacBlock) (bool, error) {
+	var usedLen int64
+	for _, block := range blocks {
+		usedLen += 4 + int64(len(block.data))
+	}
+
+	oldLen := parsed.metadataLen()
+	if usedLen == oldLen || usedLen+4 <= oldLen {
+		if usedLen < oldLen {
+			blocks = append(blocks, flacBlock{typ: flacBlockPadding, data: make([]byte, oldLen-usedLen-4)})
+		}
+		if _, err := file.WriteAt(encodeFLACBlocks(blocks), parsed.prefixLen+4); err != nil {
+			return false, fmt.Errorf("failed to write flac metadata: %w", err)
+		}
+		if err := file.Sync(); err != nil {
+			return false, fmt.Errorf("failed to sync flac file: %w", err)
+		}
+		return true, nil
+	}
+
+	blocks = append(blocks, flacBlock{typ: flacBlockPadding, data: make([]byte, flacDefaultPadding)})
+	if err := rewriteFLACFile(file, filePath, parsed, encodeFLACBlocks(blocks)); err != nil {
+		return false, err
+	}
+	return false, nil
+}
+
+// readFLACMetadata 解析 FLAC 文件头部的全部元数据块。
+func readFLACMetadata(r io.ReadSeeker) (*flacFile, error) {
+	header := make([]byte, 10)
+	if _, err := io.ReadFull(r, header[:4]); err != nil {
+		return nil, fmt.Errorf("failed to read flac header: %w", err)
+	}
+
+	var prefixLen int64
+	if string(header[:3]) == "ID3" {
+		// 跳过 ID3v2 头：10 字节头 + syncsafe 长度（+ 可选 10 字节 footer）。
+		if _, err := io.ReadFull(r, header[4:]); err != nil {
+			return nil, fmt.Errorf("failed to read id3 header: %w", err)
+		}
+		size := int64(header[6]&0x7f)<<21 | int64(header[7]&0x7f)<<14 | int64(header[8]&0x7f)<<7 | int64(header[9]&0x7f)
+		prefixLen = 10 + size
+		if header[5]&0x10 != 0 {
+			prefixLen += 10
+		}
+		if _, err := r.Seek(prefixLen, io.SeekStart); err != nil {
+			return nil, fmt.Errorf("failed to skip id3 header: %w", err)
+		}
+		if _, err := io.ReadFull(r, header[:4]); err != nil {
+			return nil, fmt.Errorf("failed to read flac header: %w", err)
+		}
+	}
+
+	if string(header[:4]) != "fLaC" {
+		return nil, fmt.Errorf("not a flac file")
+	}
+
+	parsed := &flacFile{prefixLen: prefixLen}
+	offset := prefixLen + 4
+	for {
+		blockHeader := make([]byte, 4)
+		if _, err := io.ReadFull(r, blockHeader); err != nil {
+			return nil, fmt.Errorf("failed to read metadata block header: %w", err)
+		}
+		last := blockHeader[0]&0x80 != 0
+		typ := blockHeader[0] & 0x7f
+		length := int(blockHeader[1])<<16 | int(blockHeader[2])<<8 | int(blockHeader[3])
+
+		data := make([]byte, length)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, fmt.Errorf("failed to read metadata block (type %d): %w", typ, err)
+		}
+		parsed.blocks = append(parsed.blocks, flacBlock{typ: typ, data: data})
+		offset += 4 + int64(length)
+
+		if last {
+			break
+		}
+	}
+	parsed.metaEnd = offset
+
+	if len(parsed.blocks) == 0 || parsed.blocks[0].typ != flacBlockStreamInfo {
+		return nil, fmt.Errorf("invalid flac file: missing STREAMINFO")
+	}
+
+	return parsed, nil
+}
+
+// buildFLACBlocks 基于原有元数据块生成新的块列表（不含 padding）。
+func buildFLACBlocks(existing []flacBlock, metadata *model.TrackMetadata) ([]flacBlock, error) {
+	vendor := flacVendor
+	var comments []string
+	blocks := make([]flacBlock, 0, len(existing)+2)
+
+	// 文件中已有相同的封面时保留原 PICTURE 块，不重复写入。
+	replaceCover := len(metadata.CoverData) > 0
+	if replaceCover {
+		newHash := cover.Hash(metadata.CoverData)
+		for _, block := range existing {
+			if block.typ != flacBlockPicture {
+				continue
+			}
+			if picType, data, ok := parseFLACPicture(block.data); ok && picType == 3 && cover.Hash(data) == newHash {
+				replaceCover = false
+				break
+			}
+		}
+	}
+
+	for _, block := range existing {
+		switch block.typ {
+		case flacBlockPadding:
+			continue
+		case flacBlockVorbisComment:
+			v, c, err := parseVorbisComment(block.data)
+			if err != nil {
+				return nil, err
+			}
+			vendor = v
+			comments = append(comments, c...)
+			continue
+		case flacBlockPicture:
+			if replaceCover {
+				continue
+			}
+		}
+		blocks = append(blocks, block)
+	}
+
+	comments = removeVorbisFields(comments, flacManagedTags)
 	addTag := func(key, value string) {
 		value = strings.TrimSpace(value)
 		if value == "" {
 			return
 		}
-		setArgs = append(setArgs, "--set-tag="+key+"="+value)
+		comments = append(comments, key+"="+value)
 	}
 
 	addTag("TITLE", metadata.Title)
@@ -53,55 +244,227 @@ func (t *Tagger) writeFLACTags(filePath string, metadata *model.TrackMetadata) e
 	addTag("LYRICS", metadata.Lyrics)
 	addTag("LYRICS_TRANSLATED", metadata.Translation)
 
-	if len(setArgs) > 0 {
-		setArgs = append(setArgs, filePath)
-		if err := t.runMetaflac(setArgs...); err != nil {
-			return err
+	blocks = append(blocks, flacBlock{typ: flacBlockVorbisComment, data: encodeVorbisComment(vendor, comments)})
+
+	if replaceCover {
+		blocks = append(blocks, flacBlock{typ: flacBlockPicture, data: encodeFLACPicture(metadata.CoverData, metadata.CoverMIME)})
+	}
+
+	for _, block := range blocks {
+		if len(block.data) > flacMaxBlockLength {
+			return nil, fmt.Errorf("flac metadata block (type %d) too large: %d bytes", block.typ, len(block.data))
 		}
 	}
 
-	if len(metadata.CoverData) > 0 {
-		coverFile, err := os.CreateTemp("", "embed-cover-*.img")
-		if err != nil {
-			return fmt.Errorf("create temp cover file failed: %w", err)
+	return blocks, nil
+}
+
+// encodeFLACBlocks 序列化元数据块，并为最后一个块设置 last 标志。
+func encodeFLACBlocks(blocks []flacBlock) []byte {
+	var buf bytes.Buffer
+	for i, block := range blocks {
+		typ := block.typ
+		if i == len(blocks)-1 {
+			typ |= 0x80
 		}
-		coverPath := coverFile.Name()
-		defer os.Remove(coverPath)
+		length := len(block.data)
+		buf.Write([]byte{typ, byte(length >> 16), byte(length >> 8), byte(length)})
+		buf.Write(block.data)
+	}
+	return buf.Bytes()
+}
 
-		if _, err := coverFile.Write(metadata.CoverData); err != nil {
-			coverFile.Close()
-			return fmt.Errorf("write temp cover file failed: %w", err)
+// rewriteFLACFile 将新的元数据与原音频帧写入同目录临时文件，fsync 后替换原文件。
+func rewriteFLACFile(src *os.File, filePath string, parsed *flacFile, metadata []byte) error {
+	info, err := src.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat flac file: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(filePath), "."+filepath.Base(filePath)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	tmpPath := tmp.Name()
+	committed := false
+	defer func() {
+		if !committed {
+			tmp.Close()
+			os.Remove(tmpPath)
 		}
-		if err := coverFile.Close(); err != nil {
-			return fmt.Errorf("close temp cover file failed: %w", err)
+	}()
+
+	if parsed.prefixLen > 0 {
+		if _, err := io.Copy(tmp, io.NewSectionReader(src, 0, parsed.prefixLen)); err != nil {
+			return fmt.Errorf("failed to copy id3 header: %w", err)
 		}
+	}
+	if _, err := tmp.Write([]byte("fLaC")); err != nil {
+		return fmt.Errorf("failed to write flac marker: %w", err)
+	}
+	if _, err := tmp.Write(metadata); err != nil {
+		return fmt.Errorf("failed to write flac metadata: %w", err)
+	}
+	if _, err := io.Copy(tmp, io.NewSectionReader(src, parsed.metaEnd, info.Size()-parsed.metaEnd)); err != nil {
+		return fmt.Errorf("failed to copy audio frames: %w", err)
+	}
+	if err := tmp.Chmod(info.Mode().Perm()); err != nil {
+		return fmt.Errorf("failed to chmod temp file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		return fmt.Errorf("failed to sync temp file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close temp file: %w", err)
+	}
+	if err := os.Rename(tmpPath, filePath); err != nil {
+		return fmt.Errorf("failed to replace flac file: %w", err)
+	}
+	committed = true
 
-		// 清理旧封面并写入新封面。
-		if err := t.runMetaflac("--remove", "--block-type=PICTURE", filePath); err != nil {
-			return err
+	return nil
+}
+
+// parseVorbisComment 解析 VORBIS_COMMENT 块（小端长度前缀）。
+func parseVorbisComment(data []byte) (string, []string, error) {
+	r := bytes.NewReader(data)
+	readString := func() (string, error) {
+		var n uint32
+		if err := binary.Read(r, binary.LittleEndian, &n); err != nil {
+			return "", err
+		}
+		if int64(n) > int64(r.Len()) {
+			return "", io.ErrUnexpectedEOF
 		}
-		if err := t.runMetaflac("--import-picture-from="+coverPath, filePath); err != nil {
-			return err
+		buf := make([]byte, n)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return "", err
 		}
+		return string(buf), nil
 	}
 
-	t.logger.Info("FLAC tags written successfully",
-		zap.String("file", filePath),
-		zap.Bool("has_cover", len(metadata.CoverData) > 0),
-		zap.Bool("has_lyrics", metadata.Lyrics != ""))
+	vendor, err := readString()
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid vorbis comment vendor: %w", err)
+	}
 
-	return nil
+	var count uint32
+	if err := binary.Read(r, binary.LittleEndian, &count); err != nil {
+		return "", nil, fmt.Errorf("invalid vorbis comment count: %w", err)
+	}
+
+	// 每条注释至少占 4 字节长度前缀，按剩余数据限制容量，避免损坏的计数导致超大分配。
+	comments := make([]string, 0, min(int64(count), int64(r.Len()/4)))
+	for i := uint32(0); i < count; i++ {
+		comment, err := readString()
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid vorbis comment #%d: %w", i, err)
+		}
+		comments = append(comments, comment)
+	}
+
+	return vendor, comments, nil
 }
 
-func (t *Tagger) runMetaflac(args ...string) error {
-	cmd := exec.Command("metaflac", args...)
-	out, err := cmd.CombinedOutput()
-	if err != nil {
-		msg := strings.TrimSpace(string(out))
-		if msg == "" {
-			return fmt.Errorf("metaflac %s failed: %w", strings.Join(args, " "), err)
+func encodeVorbisComment(vendor string, comments []string) []byte {
+	var buf bytes.Buffer
+	writeString := func(s string) {
+		binary.Write(&buf, binary.LittleEndian, uint32(len(s)))
+		buf.WriteString(s)
+	}
+
+	writeString(vendor)
+	binary.Write(&buf, binary.LittleEndian, uint32(len(comments)))
+	for _, comment := range comments {
+		writeString(comment)
+	}
+	return buf.Bytes()
+}
+
+// removeVorbisFields 删除指定字段（字段名大小写不敏感）。
+func removeVorbisFields(comments []string, fields []string) []string {
+	out := comments[:0]
+	for _, comment := range comments {
+		name := comment
+		if idx := strings.IndexByte(comment, '='); idx >= 0 {
+			name = comment[:idx]
+		}
+
+		managed := false
+		for _, field := range fields {
+			if strings.EqualFold(name, field) {
+				managed = true
+				break
+			}
+		}
+		if !managed {
+			out = append(out, comment)
 		}
-		return fmt.Errorf("metaflac %s failed: %w: %s", strings.Join(args, " "), err, msg)
 	}
-	return nil
+	return out
+}
+
+// parseFLACPicture 读取 PICTURE 块的图片类型与图片数据。
+func parseFLACPicture(block []byte) (uint32, []byte, bool) {
+	r := bytes.NewReader(block)
+	var picType, length uint32
+	skip := func() bool {
+		if binary.Read(r, binary.BigEndian, &length) != nil || int64(length) > int64(r.Len()) {
+			return false
+		}
+		_, err := r.Seek(int64(length), io.SeekCurrent)
+		return err == nil
+	}
+
+	if binary.Read(r, binary.BigEndian, &picType) != nil {
+		return 0, nil, false
+	}
+	// MIME 与描述
+	if !skip() || !skip() {
+		return 0, nil, false
+	}
+	// 宽、高、色深、索引色数
+	if _, err := r.Seek(16, io.SeekCurrent); err != nil {
+		return 0, nil, false
+	}
+	if binary.Read(r, binary.BigEndian, &length) != nil || int64(length) > int64(r.Len()) {
+		return 0, nil, false
+	}
+	offset := len(block) - r.Len()
+	return picType, block[offset : offset+int(length)], true
+}
+
+// encodeFLACPicture 生成 front cover 类型的 PICTURE 块（大端长度前缀）。
+func encodeFLACPicture(data []byte, mimeType string) []byte {
+	if mimeType == "" {
+		mimeType = cover.DetectMIME(data)
+	}
+	if !strings.HasPrefix(mimeType, "image/") {
+		mimeType = cover.MIMEJPEG
+	}
+
+	var width, height, depth uint32
+	if cfg, _, err := image.DecodeConfig(bytes.NewReader(data)); err == nil {
+		width = uint32(cfg.Width)
+		height = uint32(cfg.Height)
+		depth = 24
+	}
+
+	var buf bytes.Buffer
+	writeUint32 := func(v uint32) {
+		binary.Write(&buf, binary.BigEndian, v)
+	}
+
+	writeUint32(3) // Cover (front)
+	writeUint32(uint32(len(mimeType)))
+	buf.WriteString(mimeType)
+	writeUint32(0) // description
+	writeUint32(width)
+	writeUint32(height)
+	writeUint32(depth)
+	writeUint32(0) // indexed colors
+	writeUint32(uint32(len(data)))
+	buf.Write(data)
+
+	return buf.Bytes()
 }
@@ -0,0 +1,222 @@
+package tagger
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/azin/gdstudio-embed-service/internal/model"
+	"go.uber.org/zap"
+)
+
+// testAudioFrames 伪造的音频帧，写标签前后必须逐字节保持不变。
+var testAudioFrames = bytes.Repeat([]byte{0xff, 0xf8, 0x69, 0x08}, 256)
+
+// buildTestFLAC 生成 STREAMINFO + 可选块 + 指定大小 padding + 音频帧的 FLAC 文件内容。
+func buildTestFLAC(padding int, extra ...flacBlock) []byte {
+	blocks := append([]flacBlock{{typ: flacBlockStreamInfo, data: make([]byte, 34)}}, extra...)
+	if padding >= 0 {
+		blocks = append(blocks, flacBlock{typ: flacBlockPadding, data: make([]byte, padding)})
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("fLaC")
+	buf.Write(encodeFLACBlocks(blocks))
+	buf.Write(testAudioFrames)
+	return buf.Bytes()
+}
+
+func writeTestFile(t *testing.T, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func readTestFLAC(t *testing.T, path string) (*flacFile, []byte) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := readFLACMetadata(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("re-reading written file: %v", err)
+	}
+	return parsed, data[parsed.metaEnd:]
+}
+
+func testPNG(t *testing.T) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 4, 3))
+	img.Set(1, 1, color.RGBA{R: 255, A: 255})
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func vorbisComments(t *testing.T, parsed *flacFile) []string {
+	t.Helper()
+	for _, block := range parsed.blocks {
+		if block.typ == flacBlockVorbisComment {
+			_, comments, err := parseVorbisComment(block.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return comments
+		}
+	}
+	return nil
+}
+
+func TestWriteFLACTags(t *testing.T) {
+	oldComment := flacBlock{typ: flacBlockVorbisComment, data: encodeVorbisComment("ref", []string{"title=Old", "GENRE=Pop"})}
+
+	tests := []struct {
+		name        string
+		file        []byte
+		wantInPlace bool
+	}{
+		{name: "fits in padding", file: buildTestFLAC(4096, oldComment), wantInPlace: true},
+		{name: "no padding", file: buildTestFLAC(-1, oldComment), wantInPlace: false},
+		{name: "padding too small", file: buildTestFLAC(2), wantInPlace: false},
+	}
+
+	tagger := &Tagger{logger: zap.NewNop()}
+	metadata := &model.TrackMetadata{Title: "晴天", Artist: "周杰伦", Album: "叶惠美", TrackNumber: 3, Year: 2003}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeTestFile(t, "track.flac", tt.file)
+
+			if err := tagger.writeFLACTags(path, metadata); err != nil {
+				t.Fatalf("writeFLACTags: %v", err)
+			}
+
+			parsed, audio := readTestFLAC(t, path)
+			if !bytes.Equal(audio, testAudioFrames) {
+				t.Fatal("audio frames changed")
+			}
+
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if inPlace := info.Size() == int64(len(tt.file)); inPlace != tt.wantInPlace {
+				t.Errorf("in place = %v (size %d -> %d), want %v", inPlace, len(tt.file), info.Size(), tt.wantInPlace)
+			}
+			if !tt.wantInPlace {
+				last := parsed.blocks[len(parsed.blocks)-1]
+				if last.typ != flacBlockPadding || len(last.data) != flacDefaultPadding {
+					t.Errorf("rewritten file should end with %d bytes of padding", flacDefaultPadding)
+				}
+			}
+
+			got := strings.Join(vorbisComments(t, parsed), "\n")
+			for _, want := range []string{"TITLE=晴天", "ARTIST=周杰伦", "ALBUM=叶惠美", "TRACKNUMBER=3", "DATE=2003"} {
+				if !strings.Contains(got, want) {
+					t.Errorf("comments %q missing %q", got, want)
+				}
+			}
+			if strings.Contains(got, "title=Old") {
+				t.Errorf("managed field was not replaced: %q", got)
+			}
+			if bytes.Contains(tt.file, []byte("GENRE=Pop")) && !strings.Contains(got, "GENRE=Pop") {
+				t.Errorf("unmanaged field was dropped: %q", got)
+			}
+		})
+	}
+}
+
+func TestWriteFLACTagsPictureRoundTrip(t *testing.T) {
+	coverData := testPNG(t)
+	path := writeTestFile(t, "track.flac", buildTestFLAC(64*1024))
+	tagger := &Tagger{logger: zap.NewNop()}
+	metadata := &model.TrackMetadata{Title: "A", CoverData: coverData, CoverMIME: "image/png"}
+
+	// 第二次写入相同封面时应保留原 PICTURE 块，而不是追加新的。
+	for i := 0; i < 2; i++ {
+		if err := tagger.writeFLACTags(path, metadata); err != nil {
+			t.Fatalf("write #%d: %v", i+1, err)
+		}
+	}
+
+	parsed, audio := readTestFLAC(t, path)
+	if !bytes.Equal(audio, testAudioFrames) {
+		t.Fatal("audio frames changed")
+	}
+
+	var pictures [][]byte
+	for _, block := range parsed.blocks {
+		if block.typ == flacBlockPicture {
+			pictures = append(pictures, block.data)
+		}
+	}
+	if len(pictures) != 1 {
+		t.Fatalf("got %d PICTURE blocks, want 1", len(pictures))
+	}
+
+	picType, data, ok := parseFLACPicture(pictures[0])
+	if !ok {
+		t.Fatal("PICTURE block did not parse")
+	}
+	if picType != 3 {
+		t.Errorf("picture type = %d, want 3 (front cover)", picType)
+	}
+	if !bytes.Equal(data, coverData) {
+		t.Error("picture data does not round-trip")
+	}
+	if !bytes.Contains(pictures[0], []byte("image/png")) {
+		t.Error("picture MIME type not written")
+	}
+}
+
+func TestReadFLACMetadataCorrupt(t *testing.T) {
+	valid := buildTestFLAC(16)
+	hugeCount := encodeVorbisComment("v", nil)
+	copy(hugeCount[len(hugeCount)-4:], []byte{0xff, 0xff, 0xff, 0xff})
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "empty", data: nil},
+		{name: "not flac", data: []byte("RIFF....WAVEfmt ")},
+		{name: "marker only", data: []byte("fLaC")},
+		{name: "truncated block header", data: valid[:6]},
+		{name: "truncated block data", data: valid[:4+4+10]},
+		{name: "block length beyond file", data: append([]byte("fLaC"), 0x80, 0xff, 0xff, 0xff, 0x00)},
+		{name: "missing streaminfo", data: append([]byte("fLaC"), encodeFLACBlocks([]flacBlock{{typ: flacBlockPadding, data: make([]byte, 8)}})...)},
+		{name: "id3 size beyond file", data: []byte("ID3\x04\x00\x00\x7f\x7f\x7f\x7f")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := readFLACMetadata(bytes.NewReader(tt.data)); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+
+	t.Run("corrupt vorbis comment", func(t *testing.T) {
+		for _, data := range [][]byte{hugeCount, {0xff, 0xff, 0xff, 0x7f}, {1, 0}} {
+			file := buildTestFLAC(16, flacBlock{typ: flacBlockVorbisComment, data: data})
+			path := writeTestFile(t, "corrupt.flac", file)
+			if err := (&Tagger{logger: zap.NewNop()}).writeFLACTags(path, &model.TrackMetadata{Title: "A"}); err == nil {
+				t.Fatal("expected an error")
+			}
+			if got, _ := os.ReadFile(path); !bytes.Equal(got, file) {
+				t.Fatal("corrupt file was modified")
+			}
+		}
+	})
+}
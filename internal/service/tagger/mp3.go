@@ -1,11 +1,14 @@
 package tagger
 
 import (
+	"encoding/binary"
 	"fmt"
-	"os"
-	"path/filepath"
+	"io"
+	"time"
 
 	"github.com/azin/gdstudio-embed-service/internal/model"
+	"github.com/azin/gdstudio-embed-service/internal/service/cover"
+	"github.com/azin/gdstudio-embed-service/internal/service/lyrics"
 	id3v2 "github.com/bogem/id3v2/v2"
 	"go.uber.org/zap"
 )
@@ -41,30 +44,11 @@ func (t *Tagger) WriteMP3TagsWithID3v2(filePath string, metadata *model.TrackMet
 		tag.SetYear(fmt.Sprintf("%d", metadata.Year))
 	}
 
-	// 写入封面
-	if len(metadata.CoverData) > 0 {
-		pic := id3v2.PictureFrame{
-			Encoding:    id3v2.EncodingUTF8,
-			MimeType:    "image/jpeg",
-			PictureType: id3v2.PTFrontCover,
-			Description: "Cover",
-			Picture:     metadata.CoverData,
-		}
-		tag.AddAttachedPicture(pic)
-		t.logger.Debug("attached cover", zap.Int("size", len(metadata.CoverData)))
-	}
+	// 写入封面（与现有封面相同时跳过，避免重复写入）
+	t.addMP3Cover(tag, metadata)
 
-	// 写入歌词
-	if metadata.Lyrics != "" {
-		lyricFrame := id3v2.UnsynchronisedLyricsFrame{
-			Encoding:          id3v2.EncodingUTF8,
-			Language:          "eng",
-			ContentDescriptor: "Lyrics",
-			Lyrics:            metadata.Lyrics,
-		}
-		tag.AddUnsynchronisedLyricsFrame(lyricFrame)
-		t.logger.Debug("attached lyrics", zap.Int("length", len(metadata.Lyrics)))
-	}
+	// 写入歌词（USLT 纯文本 + SYLT 同步歌词，翻译歌词单独成帧或合并为双语）
+	t.addMP3Lyrics(tag, metadata)
 
 	// 保存标签
 	if err := tag.Save(); err != nil {
@@ -75,17 +59,125 @@ func (t *Tagger) WriteMP3TagsWithID3v2(filePath string, metadata *model.TrackMet
 	return nil
 }
 
-// WriteCoverToFile 将封面保存为独立文件
-func (t *Tagger) WriteCoverToFile(audioPath string, coverData []byte) error {
-	if len(coverData) == 0 {
-		return nil
+// addMP3Cover 替换 APIC 封面帧。文件中已有相同封面（哈希一致）时保持不变。
+func (t *Tagger) addMP3Cover(tag *id3v2.Tag, metadata *model.TrackMetadata) {
+	if len(metadata.CoverData) == 0 {
+		return
 	}
 
-	coverPath := audioPath[:len(audioPath)-len(filepath.Ext(audioPath))] + ".jpg"
-	if err := os.WriteFile(coverPath, coverData, 0644); err != nil {
-		return fmt.Errorf("failed to write cover file: %w", err)
+	newHash := cover.Hash(metadata.CoverData)
+	for _, frame := range tag.GetFrames(tag.CommonID("Attached picture")) {
+		pic, ok := frame.(id3v2.PictureFrame)
+		if ok && pic.PictureType == id3v2.PTFrontCover && cover.Hash(pic.Picture) == newHash {
+			t.logger.Debug("embedded cover unchanged, skip", zap.String("hash", newHash))
+			return
+		}
 	}
 
-	t.logger.Debug("wrote cover file", zap.String("path", coverPath))
-	return nil
+	mimeType := metadata.CoverMIME
+	if mimeType == "" {
+		mimeType = cover.DetectMIME(metadata.CoverData)
+	}
+
+	tag.DeleteFrames(tag.CommonID("Attached picture"))
+	tag.AddAttachedPicture(id3v2.PictureFrame{
+		Encoding:    id3v2.EncodingUTF8,
+		MimeType:    mimeType,
+		PictureType: id3v2.PTFrontCover,
+		Description: "Cover",
+		Picture:     metadata.CoverData,
+	})
+	t.logger.Debug("attached cover",
+		zap.String("mime", mimeType),
+		zap.Int("size", len(metadata.CoverData)))
+}
+
+// addMP3Lyrics 写入 USLT/SYLT 歌词帧。先清理旧帧，避免重复写入时堆积。
+func (t *Tagger) addMP3Lyrics(tag *id3v2.Tag, metadata *model.TrackMetadata) {
+	tag.DeleteFrames("USLT")
+	tag.DeleteFrames("SYLT")
+
+	original := lyrics.Parse(metadata.Lyrics)
+	if original.Empty() {
+		return
+	}
+	translation := lyrics.Parse(metadata.Translation)
+	lang := lyrics.DetectLanguage(original.Text())
+
+	if !t.cfg.Lyrics.EmbedTranslation {
+		translation = nil
+	}
+	if t.cfg.Lyrics.MergeTranslation && translation.Synced() {
+		original = lyrics.Merge(original, translation)
+		translation = nil
+	}
+
+	t.addLyricFrames(tag, original, lang, "Lyrics")
+	if !translation.Empty() {
+		// USLT/SYLT 以 语言+描述 区分，即使检测到的语言相同也不会互相覆盖。
+		t.addLyricFrames(tag, translation, lyrics.DetectLanguage(translation.Text()), "Translation")
+	}
+
+	t.logger.Debug("attached lyrics",
+		zap.String("lang", lang),
+		zap.Bool("synced", original.Synced()),
+		zap.Bool("has_translation", !translation.Empty()))
+}
+
+func (t *Tagger) addLyricFrames(tag *id3v2.Tag, doc *lyrics.Document, lang, descriptor string) {
+	tag.AddUnsynchronisedLyricsFrame(id3v2.UnsynchronisedLyricsFrame{
+		Encoding:          id3v2.EncodingUTF8,
+		Language:          lang,
+		ContentDescriptor: descriptor,
+		Lyrics:            doc.String(),
+	})
+
+	if t.cfg.Lyrics.EmbedSynced && doc.Synced() {
+		tag.AddFrame("SYLT", synchronisedLyricsFrame{
+			Language:          lang,
+			ContentDescriptor: descriptor,
+			Lines:             doc.Lines,
+		})
+	}
+}
+
+// synchronisedLyricsFrame ID3v2 SYLT 帧（UTF-8 编码，毫秒时间戳）。id3v2 库未内置该帧类型。
+type synchronisedLyricsFrame struct {
+	Language          string
+	ContentDescriptor string
+	Lines             []lyrics.Line
+}
+
+func (f synchronisedLyricsFrame) Size() int {
+	// encoding(1) + language(3) + timestamp format(1) + content type(1) + descriptor + \0
+	size := 6 + len(f.ContentDescriptor) + 1
+	for _, line := range f.Lines {
+		size += len(line.Text) + 1 + 4
+	}
+	return size
+}
+
+func (f synchronisedLyricsFrame) UniqueIdentifier() string {
+	return f.Language + f.ContentDescriptor
+}
+
+func (f synchronisedLyricsFrame) WriteTo(w io.Writer) (int64, error) {
+	if len(f.Language) != 3 {
+		return 0, id3v2.ErrInvalidLanguageLength
+	}
+
+	buf := make([]byte, 0, f.Size())
+	buf = append(buf, id3v2.EncodingUTF8.Key)
+	buf = append(buf, f.Language...)
+	buf = append(buf, 2, 1) // 时间戳单位：毫秒；内容类型：歌词
+	buf = append(buf, f.ContentDescriptor...)
+	buf = append(buf, 0)
+	for _, line := range f.Lines {
+		buf = append(buf, line.Text...)
+		buf = append(buf, 0)
+		buf = binary.BigEndian.AppendUint32(buf, uint32(line.Time/time.Millisecond))
+	}
+
+	n, err := w.Write(buf)
+	return int64(n), err
 }
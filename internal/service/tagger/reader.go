@@ -0,0 +1,216 @@
+package tagger
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	id3v2 "github.com/bogem/id3v2/v2"
+)
+
+// TagInfo 从音频文件读取的标签与音频信息
+type TagInfo struct {
+	Title    string
+	Artist   string
+	Album    string
+	ISRC     string
+	Duration int // 秒
+	Bitrate  int // kbps（FLAC 为平均码率）
+	Format   string
+}
+
+// ReadTags 读取音频文件的基础标签、时长与码率。
+func ReadTags(filePath string) (*TagInfo, error) {
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".mp3":
+		return readMP3Info(filePath)
+	case ".flac":
+		return readFLACInfo(filePath)
+	default:
+		return nil, fmt.Errorf("unsupported file format: %s", filepath.Ext(filePath))
+	}
+}
+
+func readFLACInfo(filePath string) (*TagInfo, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	parsed, err := readFLACMetadata(file)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &TagInfo{Format: "flac"}
+	for _, block := range parsed.blocks {
+		switch block.typ {
+		case flacBlockStreamInfo:
+			// STREAMINFO: 采样率 20 bit，声道 3 bit，位深 5 bit，总采样数 36 bit
+			if len(block.data) < 18 {
+				continue
+			}
+			packed := binary.BigEndian.Uint64(block.data[10:18])
+			sampleRate := packed >> 44
+			totalSamples := packed & (1<<36 - 1)
+			if sampleRate > 0 && totalSamples > 0 {
+				info.Duration = int(totalSamples / sampleRate)
+			}
+		case flacBlockVorbisComment:
+			_, comments, err := parseVorbisComment(block.data)
+			if err != nil {
+				continue
+			}
+			for _, comment := range comments {
+				key, value, ok := strings.Cut(comment, "=")
+				if !ok {
+					continue
+				}
+				switch strings.ToUpper(key) {
+				case "TITLE":
+					info.Title = value
+				case "ARTIST":
+					info.Artist = value
+				case "ALBUM":
+					info.Album = value
+				case "ISRC":
+					info.ISRC = value
+				}
+			}
+		}
+	}
+
+	if stat, err := file.Stat(); err == nil && info.Duration > 0 {
+		audioBytes := stat.Size() - parsed.metaEnd
+		info.Bitrate = int(audioBytes * 8 / int64(info.Duration) / 1000)
+	}
+
+	return info, nil
+}
+
+func readMP3Info(filePath string) (*TagInfo, error) {
+	tag, err := id3v2.Open(filePath, id3v2.Options{Parse: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open mp3 file: %w", err)
+	}
+	defer tag.Close()
+
+	info := &TagInfo{
+		Title:  tag.Title(),
+		Artist: tag.Artist(),
+		Album:  tag.Album(),
+		ISRC:   tag.GetTextFrame("TSRC").Text,
+		Format: "mp3",
+	}
+	if tlen, err := strconv.Atoi(strings.TrimSpace(tag.GetTextFrame("TLEN").Text)); err == nil && tlen > 0 {
+		info.Duration = tlen / 1000
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return info, nil
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return info, nil
+	}
+
+	// 跳过 ID3v2 标签（含 padding）后读取首个 MPEG 帧。
+	offset := int64(0)
+	header := make([]byte, 10)
+	if _, err := file.ReadAt(header, 0); err == nil && string(header[:3]) == "ID3" {
+		size := int64(header[6]&0x7f)<<21 | int64(header[7]&0x7f)<<14 | int64(header[8]&0x7f)<<7 | int64(header[9]&0x7f)
+		offset = 10 + size
+		if header[5]&0x10 != 0 {
+			offset += 10
+		}
+	}
+	duration, bitrate := probeMPEG(file, offset, stat.Size())
+	if info.Duration == 0 {
+		info.Duration = duration
+	}
+	info.Bitrate = bitrate
+
+	return info, nil
+}
+
+var (
+	// MPEG1 Layer III 与 MPEG2/2.5 Layer III 码率表（kbps）
+	mpeg1L3Bitrates = [16]int{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 0}
+	mpeg2L3Bitrates = [16]int{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, 0}
+	mpegSampleRates = [3][3]int{
+		{44100, 48000, 32000}, // MPEG1
+		{22050, 24000, 16000}, // MPEG2
+		{11025, 12000, 8000},  // MPEG2.5
+	}
+)
+
+// probeMPEG 解析首个 MPEG 音频帧，优先使用 Xing/Info 头计算时长，否则按 CBR 估算。
+func probeMPEG(r io.ReaderAt, offset, fileSize int64) (int, int) {
+	buf := make([]byte, 64*1024)
+	n, _ := r.ReadAt(buf, offset)
+	buf = buf[:n]
+
+	for i := 0; i+4 <= len(buf); i++ {
+		if buf[i] != 0xff || buf[i+1]&0xe0 != 0xe0 {
+			continue
+		}
+
+		versionBits := (buf[i+1] >> 3) & 0x03
+		layerBits := (buf[i+1] >> 1) & 0x03
+		bitrateIdx := buf[i+2] >> 4
+		sampleIdx := (buf[i+2] >> 2) & 0x03
+		if versionBits == 1 || layerBits != 1 || bitrateIdx == 0 || bitrateIdx == 15 || sampleIdx == 3 {
+			continue
+		}
+
+		var versionIdx, samplesPerFrame, bitrate int
+		switch versionBits {
+		case 3:
+			versionIdx, samplesPerFrame, bitrate = 0, 1152, mpeg1L3Bitrates[bitrateIdx]
+		case 2:
+			versionIdx, samplesPerFrame, bitrate = 1, 576, mpeg2L3Bitrates[bitrateIdx]
+		default:
+			versionIdx, samplesPerFrame, bitrate = 2, 576, mpeg2L3Bitrates[bitrateIdx]
+		}
+		sampleRate := mpegSampleRates[versionIdx][sampleIdx]
+		audioBytes := fileSize - offset - int64(i)
+
+		// Xing/Info 头位于 side info 之后，记录 VBR 文件的总帧数。
+		mono := buf[i+3]>>6 == 3
+		sideInfo := 32
+		switch {
+		case versionIdx == 0 && mono:
+			sideInfo = 17
+		case versionIdx != 0 && mono:
+			sideInfo = 9
+		case versionIdx != 0:
+			sideInfo = 17
+		}
+		xing := i + 4 + sideInfo
+		if xing+12 <= len(buf) {
+			id := buf[xing : xing+4]
+			if (bytes.Equal(id, []byte("Xing")) || bytes.Equal(id, []byte("Info"))) && buf[xing+7]&0x01 != 0 {
+				frames := int64(binary.BigEndian.Uint32(buf[xing+8 : xing+12]))
+				if frames > 0 {
+					seconds := frames * int64(samplesPerFrame) / int64(sampleRate)
+					if seconds > 0 {
+						return int(seconds), int(audioBytes * 8 / seconds / 1000)
+					}
+				}
+			}
+		}
+
+		return int(audioBytes * 8 / int64(bitrate*1000)), bitrate
+	}
+
+	return 0, 0
+}
@@ -4,19 +4,28 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
+	"github.com/azin/gdstudio-embed-service/internal/config"
 	"github.com/azin/gdstudio-embed-service/internal/model"
+	"github.com/azin/gdstudio-embed-service/internal/service/fsperm"
+	"github.com/azin/gdstudio-embed-service/internal/service/lyrics"
 	"go.uber.org/zap"
 )
 
 // Tagger 音频标签写入器
 type Tagger struct {
+	cfg    *config.TaggerConfig
+	perms  *fsperm.Policy // 直接写入曲库的文件（专辑封面）使用的权限策略
 	logger *zap.Logger
 }
 
 // NewTagger 创建标签写入器
-func NewTagger(logger *zap.Logger) *Tagger {
+func NewTagger(cfg *config.TaggerConfig, perms *fsperm.Policy, logger *zap.Logger) *Tagger {
 	return &Tagger{
+		cfg:    cfg,
+		perms:  perms,
 		logger: logger,
 	}
 }
@@ -46,15 +55,91 @@ func (t *Tagger) writeMP3Tags(filePath string, metadata *model.TrackMetadata) er
 	return t.WriteMP3TagsWithID3v2(filePath, metadata)
 }
 
-// WriteLyricFile 写入 .lrc 歌词文件
-func (t *Tagger) WriteLyricFile(audioPath string, lyrics string) error {
-	if lyrics == "" {
+// LRC 输出模式
+const (
+	LRCModeOriginal    = "original"
+	LRCModeTranslation = "translation"
+	LRCModeMerged      = "merged"
+	LRCModeSeparate    = "separate"
+)
+
+// WriteLyricFile 写入 .lrc 歌词文件，按曲库配置选择原文、翻译、双语合并或分文件输出。
+func (t *Tagger) WriteLyricFile(audioPath, libraryID string, metadata *model.TrackMetadata) error {
+	original := lyrics.Parse(metadata.Lyrics)
+	translation := lyrics.Parse(metadata.Translation)
+	if original.Empty() && translation.Empty() {
 		return nil
 	}
 
-	lrcPath := audioPath[:len(audioPath)-len(filepath.Ext(audioPath))] + ".lrc"
+	mode, stripCredits := t.lrcOptions(libraryID)
+	if stripCredits {
+		original = lyrics.StripCredits(original)
+		translation = lyrics.StripCredits(translation)
+	}
+
+	header := lyrics.Header{
+		Title:  metadata.Title,
+		Artist: metadata.Artist,
+		Album:  metadata.Album,
+		Length: time.Duration(metadata.Duration) * time.Second,
+	}
+	basePath := strings.TrimSuffix(audioPath, filepath.Ext(audioPath))
+
+	var primary *lyrics.Document
+	switch mode {
+	case LRCModeTranslation:
+		primary = translation
+		if primary.Empty() {
+			primary = original
+		}
+	case LRCModeMerged:
+		primary = lyrics.Merge(original, translation)
+		if primary.Empty() {
+			// 只有翻译时合并结果为空，退回只写翻译
+			primary = translation
+		}
+	default:
+		primary = original
+	}
+
+	if err := t.writeLRC(basePath+".lrc", primary, header); err != nil {
+		return err
+	}
+
+	if mode == LRCModeSeparate && !translation.Empty() {
+		lang := lyrics.ShortLanguageCode(lyrics.DetectLanguage(translation.Text()))
+		if err := t.writeLRC(basePath+"."+lang+".lrc", translation, header); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// lrcOptions 返回曲库对应的 LRC 输出模式及是否去除署名行。
+func (t *Tagger) lrcOptions(libraryID string) (string, bool) {
+	mode := t.cfg.LRC.Mode
+	stripCredits := t.cfg.LRC.StripCredits
+
+	// viper 会将 map 键转为小写
+	if lib, ok := t.cfg.LRC.Libraries[strings.ToLower(libraryID)]; ok {
+		if lib.Mode != "" {
+			mode = lib.Mode
+		}
+		if lib.StripCredits != nil {
+			stripCredits = *lib.StripCredits
+		}
+	}
+
+	return strings.ToLower(mode), stripCredits
+}
+
+func (t *Tagger) writeLRC(lrcPath string, doc *lyrics.Document, header lyrics.Header) error {
+	if doc.Empty() {
+		return nil
+	}
 
-	if err := os.WriteFile(lrcPath, []byte(lyrics), 0644); err != nil {
+	if err := os.WriteFile(lrcPath, []byte(doc.Render(header)), 0644); err != nil {
 		return fmt.Errorf("failed to write lyric file: %w", err)
 	}
 
@@ -0,0 +1,73 @@
+package tagger
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/azin/gdstudio-embed-service/internal/config"
+	"github.com/azin/gdstudio-embed-service/internal/model"
+	"go.uber.org/zap"
+)
+
+func TestWriteLyricFile(t *testing.T) {
+	const (
+		original    = "[00:01.00]Hello"
+		translation = "[00:01.00]你好"
+	)
+
+	tests := []struct {
+		name        string
+		mode        string
+		original    string
+		translation string
+		want        map[string]string // 文件后缀 -> 需要包含的内容，空字符串表示不应生成
+	}{
+		{name: "original", mode: LRCModeOriginal, original: original, translation: translation,
+			want: map[string]string{".lrc": "[00:01.00]Hello\n", ".zh.lrc": ""}},
+		{name: "translation", mode: LRCModeTranslation, original: original, translation: translation,
+			want: map[string]string{".lrc": "[00:01.00]你好\n"}},
+		{name: "translation falls back to original", mode: LRCModeTranslation, original: original,
+			want: map[string]string{".lrc": "[00:01.00]Hello\n"}},
+		{name: "merged", mode: LRCModeMerged, original: original, translation: translation,
+			want: map[string]string{".lrc": "[00:01.00]Hello\n[00:01.00]你好\n"}},
+		{name: "merged without original writes translation", mode: LRCModeMerged, translation: translation,
+			want: map[string]string{".lrc": "[00:01.00]你好\n"}},
+		{name: "separate", mode: LRCModeSeparate, original: original, translation: translation,
+			want: map[string]string{".lrc": "[00:01.00]Hello\n", ".zh.lrc": "[00:01.00]你好\n"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tagger := NewTagger(&config.TaggerConfig{LRC: config.LRCConfig{Mode: tt.mode}}, nil, zap.NewNop())
+			audioPath := filepath.Join(t.TempDir(), "track.flac")
+
+			err := tagger.WriteLyricFile(audioPath, "", &model.TrackMetadata{
+				Title:       "Song",
+				Lyrics:      tt.original,
+				Translation: tt.translation,
+			})
+			if err != nil {
+				t.Fatalf("WriteLyricFile: %v", err)
+			}
+
+			base := strings.TrimSuffix(audioPath, ".flac")
+			for suffix, want := range tt.want {
+				data, err := os.ReadFile(base + suffix)
+				if want == "" {
+					if err == nil {
+						t.Errorf("%s should not be written", suffix)
+					}
+					continue
+				}
+				if err != nil {
+					t.Fatalf("read %s: %v", suffix, err)
+				}
+				if !strings.HasPrefix(string(data), "[ti:Song]\n") || !strings.HasSuffix(string(data), want) {
+					t.Errorf("%s = %q, want header and body %q", suffix, data, want)
+				}
+			}
+		})
+	}
+}
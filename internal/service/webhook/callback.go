@@ -0,0 +1,121 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/azin/gdstudio-embed-service/internal/config"
+)
+
+// 任务级 callback_url 由 API 调用方提供，worker 会向其发起请求，需要限制目标，避免 SSRF。
+// API Key 的 webhook_url 由运维配置，不受这些限制。
+
+// ErrCallbackDisabled 未配置 webhook.secret 或 webhook.allowed_hosts 时不接受 callback_url
+var ErrCallbackDisabled = errors.New("callback_url is disabled: webhook.secret and webhook.allowed_hosts must be configured")
+
+// ErrForbiddenAddress 回调地址解析到了回环、内网或链路本地地址
+var ErrForbiddenAddress = errors.New("callback address is not publicly routable")
+
+// ValidateCallbackURL 检查 callback_url：必须为 http(s)，主机在 webhook.allowed_hosts 中，
+// 且不是内网 IP 字面量（域名在投递时按解析结果检查）
+func ValidateCallbackURL(cfg *config.WebhookConfig, raw string) error {
+	if cfg.Secret == "" || len(cfg.AllowedHosts) == 0 {
+		return ErrCallbackDisabled
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("invalid url: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+	if u.User != nil {
+		return errors.New("url must not contain user info")
+	}
+
+	host := strings.ToLower(u.Hostname())
+	if !HostAllowed(cfg.AllowedHosts, host) {
+		return fmt.Errorf("host %q is not in webhook.allowed_hosts", host)
+	}
+	if addr, err := netip.ParseAddr(host); err == nil && !publicAddr(addr) {
+		return ErrForbiddenAddress
+	}
+	return nil
+}
+
+// HostAllowed 主机是否匹配允许列表（不区分大小写）："*.example.com" 只匹配子域名，
+// 不匹配 example.com 本身，需要时单独列出；其余按完整主机名匹配
+func HostAllowed(patterns []string, host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		if suffix, ok := strings.CutPrefix(pattern, "*"); ok {
+			if strings.HasPrefix(suffix, ".") && strings.HasSuffix(host, suffix) {
+				return true
+			}
+			continue
+		}
+		if host == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// publicAddr 是否为公网地址（排除回环、内网、链路本地、组播与未指定地址）
+func publicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsValid() &&
+		!addr.IsLoopback() &&
+		!addr.IsPrivate() &&
+		!addr.IsLinkLocalUnicast() &&
+		!addr.IsLinkLocalMulticast() &&
+		!addr.IsInterfaceLocalMulticast() &&
+		!addr.IsMulticast() &&
+		!addr.IsUnspecified() &&
+		!sharedAddressSpace.Contains(addr)
+}
+
+// sharedAddressSpace 运营商级 NAT 地址段（RFC 6598），netip 不视其为内网
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// newCallbackClient 投递 callback_url 的 HTTP 客户端：连接时检查实际连接的 IP（防止 DNS rebinding），
+// 不跟随重定向（重定向目标未经过允许列表检查）
+func newCallbackClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			addr, err := netip.ParseAddr(host)
+			if err != nil || !publicAddr(addr) {
+				return fmt.Errorf("%w: %s", ErrForbiddenAddress, host)
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil // 经代理时 Control 只能看到代理地址
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return dialer.DialContext(ctx, network, addr)
+	}
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
@@ -0,0 +1,346 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/azin/gdstudio-embed-service/internal/config"
+	"github.com/azin/gdstudio-embed-service/internal/model"
+	"github.com/azin/gdstudio-embed-service/internal/repository"
+	"github.com/azin/gdstudio-embed-service/internal/service/events"
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// TypeDelivery Webhook 投递 asynq 任务类型
+const TypeDelivery = "webhook:deliver"
+
+// Queue 投递任务使用独立队列，订阅方不可达时不会挤占下载任务
+const Queue = "webhooks"
+
+// Webhook 事件名
+const (
+	EventQueued       = "job.queued"
+	EventStarted      = "job.started"
+	EventStageChanged = "job.stage_changed"
+	EventDone         = "job.done"
+	EventFailed       = "job.failed"
+	EventCancelled    = "job.cancelled"
+)
+
+// 请求头
+const (
+	HeaderSignature = "X-Webhook-Signature" // sha256=<hex(HMAC-SHA256(secret, timestamp + "." + body))>
+	HeaderTimestamp = "X-Webhook-Timestamp" // Unix 秒
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderWebhookID = "X-Webhook-ID"
+)
+
+// maxResponseBody 投递记录中保存的响应体长度上限
+const maxResponseBody = 1024
+
+// EventForStatus 将任务状态映射为 webhook 事件名，无对应事件时返回空字符串
+func EventForStatus(status string) string {
+	switch status {
+	case model.JobStatusQueued:
+		return EventQueued
+	case model.JobStatusResolving:
+		return EventStarted
+	case model.JobStatusDownloading, model.JobStatusTagging, model.JobStatusMoving, model.JobStatusScanning:
+		return EventStageChanged
+	case model.JobStatusDone:
+		return EventDone
+	case model.JobStatusFailed:
+		return EventFailed
+	case model.JobStatusCancelled:
+		return EventCancelled
+	default:
+		return ""
+	}
+}
+
+// Sign 计算签名：HMAC-SHA256(secret, timestamp + "." + body) 的十六进制
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Backoff 第 n 次重试（从 0 开始）前的等待时间：base * 2^n，不超过 max
+func Backoff(cfg *config.WebhookConfig, n int) time.Duration {
+	delay := cfg.BackoffBase
+	for i := 0; i < n && delay < cfg.BackoffMax; i++ {
+		delay *= 2
+	}
+	return min(delay, cfg.BackoffMax)
+}
+
+// RetryDelayFunc 返回 asynq 重试间隔函数：webhook 投递使用指数退避，其余任务使用 asynq 默认策略
+func RetryDelayFunc(cfg *config.WebhookConfig) asynq.RetryDelayFunc {
+	return func(n int, err error, task *asynq.Task) time.Duration {
+		if task.Type() == TypeDelivery {
+			return Backoff(cfg, n)
+		}
+		return asynq.DefaultRetryDelayFunc(n, err, task)
+	}
+}
+
+// DeliveryPayload 投递任务载荷，事件发生时确定，重试时不变
+type DeliveryPayload struct {
+	DeliveryID string    `json:"delivery_id"`
+	WebhookID  string    `json:"webhook_id"`
+	JobID      string    `json:"job_id"`
+	Event      string    `json:"event"`
+	Status     string    `json:"status"`
+	Message    string    `json:"message,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// Body 推送给订阅方的 JSON
+type Body struct {
+	ID         string     `json:"id"`
+	Event      string     `json:"event"`
+	OccurredAt time.Time  `json:"occurred_at"`
+	Status     string     `json:"status"`
+	Message    string     `json:"message,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	Job        *model.Job `json:"job,omitempty"` // 投递时的任务快照
+}
+
+// Dispatcher 监听任务状态事件，为相关订阅入队投递任务
+type Dispatcher struct {
+	cfg     *config.WebhookConfig
+	repo    *repository.WebhookRepository
+	jobRepo *repository.JobRepository
+	client  *asynq.Client
+	logger  *zap.Logger
+}
+
+// NewDispatcher 创建投递调度器
+func NewDispatcher(
+	cfg *config.WebhookConfig,
+	repo *repository.WebhookRepository,
+	jobRepo *repository.JobRepository,
+	client *asynq.Client,
+	logger *zap.Logger,
+) *Dispatcher {
+	return &Dispatcher{
+		cfg:     cfg,
+		repo:    repo,
+		jobRepo: jobRepo,
+		client:  client,
+		logger:  logger,
+	}
+}
+
+// SyncAPIKeys 将 API Key 配置中的 webhook 同步到数据库，ID 为 "apikey-<name>"。
+// 配置了 webhook_url 而没有 webhook_secret 时返回错误，不投递未签名的请求
+func (d *Dispatcher) SyncAPIKeys(keys []config.APIKey) error {
+	for _, key := range keys {
+		if key.WebhookURL == "" {
+			continue
+		}
+		if key.WebhookSecret == "" {
+			return fmt.Errorf("api key %s: webhook_url requires webhook_secret", key.Name)
+		}
+		if err := d.repo.Upsert(&model.Webhook{
+			ID:         APIKeyWebhookID(key.Name),
+			URL:        key.WebhookURL,
+			Secret:     key.WebhookSecret,
+			APIKeyName: key.Name,
+		}); err != nil {
+			return fmt.Errorf("failed to sync webhook for api key %s: %w", key.Name, err)
+		}
+	}
+	return nil
+}
+
+// APIKeyWebhookID API Key 订阅的固定 ID
+func APIKeyWebhookID(name string) string {
+	return "apikey-" + name
+}
+
+// Handle 事件总线监听函数：状态事件按任务查找订阅并入队投递
+func (d *Dispatcher) Handle(event events.Event) {
+	if event.Type != events.TypeStatus {
+		return
+	}
+	name := EventForStatus(event.Status)
+	if name == "" {
+		return
+	}
+
+	job, err := d.jobRepo.FindByID(event.JobID)
+	if err != nil {
+		d.logger.Warn("webhook dispatch: job not found", zap.String("job_id", event.JobID), zap.Error(err))
+		return
+	}
+	webhooks, err := d.repo.ListForJob(job.ID, job.APIKeyName)
+	if err != nil {
+		d.logger.Warn("webhook dispatch: failed to list webhooks", zap.String("job_id", job.ID), zap.Error(err))
+		return
+	}
+
+	for _, hook := range webhooks {
+		payload, _ := json.Marshal(DeliveryPayload{
+			DeliveryID: uuid.New().String(),
+			WebhookID:  hook.ID,
+			JobID:      job.ID,
+			Event:      name,
+			Status:     event.Status,
+			Message:    event.Message,
+			Error:      event.Error,
+			OccurredAt: event.Timestamp,
+		})
+		task := asynq.NewTask(TypeDelivery, payload)
+		if _, err := d.client.Enqueue(task, asynq.Queue(Queue), asynq.MaxRetry(d.cfg.MaxRetries), asynq.Timeout(2*d.cfg.Timeout)); err != nil {
+			d.logger.Warn("failed to enqueue webhook delivery",
+				zap.String("webhook_id", hook.ID),
+				zap.String("job_id", job.ID),
+				zap.Error(err))
+		}
+	}
+}
+
+// Deliverer 执行单次投递并记录结果
+type Deliverer struct {
+	cfg      *config.WebhookConfig
+	repo     *repository.WebhookRepository
+	jobRepo  *repository.JobRepository
+	http     *http.Client
+	callback *http.Client // 任务 callback_url 专用：只连接公网地址、不跟随重定向
+	logger   *zap.Logger
+}
+
+// NewDeliverer 创建投递执行器
+func NewDeliverer(
+	cfg *config.WebhookConfig,
+	repo *repository.WebhookRepository,
+	jobRepo *repository.JobRepository,
+	logger *zap.Logger,
+) *Deliverer {
+	return &Deliverer{
+		cfg:      cfg,
+		repo:     repo,
+		jobRepo:  jobRepo,
+		http:     &http.Client{Timeout: cfg.Timeout},
+		callback: newCallbackClient(cfg.Timeout),
+		logger:   logger,
+	}
+}
+
+// Deliver 投递一次。返回错误时由 asynq 按退避策略重试。
+func (d *Deliverer) Deliver(ctx context.Context, payload *DeliveryPayload, attempt int) error {
+	hook, err := d.repo.FindByID(payload.WebhookID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			d.logger.Info("webhook removed, drop delivery", zap.String("webhook_id", payload.WebhookID))
+			return nil
+		}
+		return err
+	}
+
+	body := Body{
+		ID:         payload.DeliveryID,
+		Event:      payload.Event,
+		OccurredAt: payload.OccurredAt,
+		Status:     payload.Status,
+		Message:    payload.Message,
+		Error:      payload.Error,
+	}
+	if job, err := d.jobRepo.FindByID(payload.JobID); err == nil {
+		body.Job = job
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	delivery := &model.WebhookDelivery{
+		DeliveryID: payload.DeliveryID,
+		WebhookID:  hook.ID,
+		JobID:      payload.JobID,
+		Event:      payload.Event,
+		URL:        hook.URL,
+		Attempt:    attempt,
+	}
+	start := time.Now()
+	deliverErr := d.post(ctx, hook, payload, data, delivery)
+	delivery.DurationMs = time.Since(start).Milliseconds()
+	if deliverErr != nil {
+		delivery.Error = deliverErr.Error()
+	}
+	if err := d.repo.CreateDelivery(delivery); err != nil {
+		d.logger.Warn("failed to record webhook delivery", zap.Error(err))
+	}
+
+	if deliverErr != nil {
+		d.logger.Warn("webhook delivery failed",
+			zap.String("webhook_id", hook.ID),
+			zap.String("event", payload.Event),
+			zap.Int("attempt", attempt),
+			zap.Error(deliverErr))
+	}
+	return deliverErr
+}
+
+func (d *Deliverer) post(ctx context.Context, hook *model.Webhook, payload *DeliveryPayload, data []byte, delivery *model.WebhookDelivery) error {
+	// 缺少密钥或主机已不在允许列表中时重试也不会成功
+	if hook.Secret == "" {
+		return fmt.Errorf("webhook has no signing secret, refusing to send unsigned payload: %w", asynq.SkipRetry)
+	}
+	client := d.http
+	if hook.JobID != "" {
+		if u, err := url.Parse(hook.URL); err != nil || !HostAllowed(d.cfg.AllowedHosts, u.Hostname()) {
+			return fmt.Errorf("callback host is not in webhook.allowed_hosts: %w", asynq.SkipRetry)
+		}
+		client = d.callback
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "gdstudio-embed-service-webhook/1.0")
+	req.Header.Set(HeaderEvent, payload.Event)
+	req.Header.Set(HeaderDelivery, payload.DeliveryID)
+	req.Header.Set(HeaderWebhookID, hook.ID)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, "sha256="+Sign(hook.Secret, timestamp, data))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+	delivery.StatusCode = resp.StatusCode
+	delivery.ResponseBody = strings.ToValidUTF8(string(respBody), "")
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+// Package tasks 定义 API 与 worker 共用的 asynq 任务类型与载荷。
+// 入队方（API、队列管理）只依赖本包，不需要引入 worker 及其下载、标签依赖。
+package tasks
+
+import (
+	"encoding/json"
+
+	"github.com/azin/gdstudio-embed-service/internal/tracing"
+)
+
+const (
+	TypeDownload     = "download"
+	TypeUpgrade      = "upgrade"
+	TypeUpgradeSweep = "upgrade:sweep"
+	TypePurge        = "maintenance:purge"
+)
+
+// DefaultQueue 下载与音质升级任务所在的队列
+const DefaultQueue = "default"
+
+// DownloadPayload 下载任务载荷
+type DownloadPayload struct {
+	JobID     string `json:"job_id"`
+	Source    string `json:"source"`
+	TrackID   string `json:"track_id"`
+	PicID     string `json:"pic_id,omitempty"`
+	LyricID   string `json:"lyric_id,omitempty"`
+	LibraryID string `json:"library_id"`
+	Quality   string `json:"quality"`
+	RequestID string `json:"request_id,omitempty"` // 入队的 API 请求 ID，用于关联 API 与 worker 日志
+
+	TraceContext tracing.Carrier `json:"trace_context,omitempty"` // 入队时的 trace 上下文，worker 的 span 挂在其下
+}
+
+// UpgradePayload 音质升级任务载荷
+type UpgradePayload struct {
+	JobID     string `json:"job_id"`
+	RequestID string `json:"request_id,omitempty"` // 手动发起时的 API 请求 ID
+
+	TraceContext tracing.Carrier `json:"trace_context,omitempty"`
+}
+
+// PayloadJobID 读取载荷中的 job_id，不是 JSON 或没有该字段时返回空
+func PayloadJobID(payload []byte) string {
+	var p struct {
+		JobID string `json:"job_id"`
+	}
+	if len(payload) == 0 || json.Unmarshal(payload, &p) != nil {
+		return ""
+	}
+	return p.JobID
+}
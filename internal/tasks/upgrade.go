@@ -0,0 +1,61 @@
+package tasks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/azin/gdstudio-embed-service/internal/tracing"
+	"github.com/hibiken/asynq"
+)
+
+// upgradeMaxRetry 音质升级失败的重试次数。升级是最佳努力，下次扫描还会再尝试，不需要 asynq 默认的 25 次
+const upgradeMaxRetry = 3
+
+// NewUpgradeTask 创建单个任务的音质升级 asynq 任务。同一任务同时只会排队一次。
+// requestID 为发起升级的 API 请求 ID，周期扫描时为空；ctx 中的 trace 上下文随载荷传给 worker。
+func NewUpgradeTask(ctx context.Context, jobID, requestID string) (*asynq.Task, []asynq.Option, error) {
+	payload, err := json.Marshal(UpgradePayload{
+		JobID:        jobID,
+		RequestID:    requestID,
+		TraceContext: tracing.Inject(ctx),
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return asynq.NewTask(TypeUpgrade, payload), []asynq.Option{
+		asynq.Queue(DefaultQueue),
+		asynq.TaskID(UpgradeTaskID(jobID)),
+		asynq.MaxRetry(upgradeMaxRetry),
+	}, nil
+}
+
+// UpgradeTaskID 任务对应的音质升级 asynq 任务 ID
+func UpgradeTaskID(jobID string) string {
+	return TypeUpgrade + ":" + jobID
+}
+
+// EnqueueUpgrade 入队音质升级任务。同一任务的升级仍在排队或执行时返回 asynq.ErrTaskIDConflict；
+// 上一次升级已失败归档时删除归档记录后重新入队，否则归档记录会一直占用任务 ID。
+func EnqueueUpgrade(ctx context.Context, client *asynq.Client, inspector *asynq.Inspector, jobID, requestID string) error {
+	task, opts, err := NewUpgradeTask(ctx, jobID, requestID)
+	if err != nil {
+		return err
+	}
+
+	_, err = client.EnqueueContext(ctx, task, opts...)
+	if !errors.Is(err, asynq.ErrTaskIDConflict) {
+		return err
+	}
+
+	info, infoErr := inspector.GetTaskInfo(DefaultQueue, UpgradeTaskID(jobID))
+	if infoErr != nil || info.State != asynq.TaskStateArchived {
+		return err
+	}
+	if err := inspector.DeleteTask(DefaultQueue, info.ID); err != nil && !errors.Is(err, asynq.ErrTaskNotFound) {
+		return fmt.Errorf("failed to delete archived upgrade task: %w", err)
+	}
+	_, err = client.EnqueueContext(ctx, task, opts...)
+	return err
+}
@@ -0,0 +1,77 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/go-resty/resty/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// parentKey 保存首次尝试前的 context，重试时新的 span 仍挂在原调用方下，而不是上一次尝试下
+type parentKey struct{}
+
+// InstrumentResty 为 resty 客户端的每次 HTTP 尝试创建 client span，并向下游注入 traceparent。
+// 调用方需通过 Request.SetContext 传入父 context。记录的 URL 去掉查询参数与用户信息，签名与认证参数不会写入 span。
+func InstrumentResty(client *resty.Client, peerService string) {
+	client.OnBeforeRequest(func(_ *resty.Client, req *resty.Request) error {
+		parent := req.Context()
+		if p, ok := parent.Value(parentKey{}).(context.Context); ok {
+			parent = p
+		}
+
+		ctx, _ := Tracer().Start(parent, peerService+" "+req.Method,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				attribute.String("peer.service", peerService),
+				semconv.HTTPRequestMethodKey.String(req.Method),
+				semconv.URLFull(redactURL(req.URL)),
+				attribute.Int("http.request.resend_count", req.Attempt-1),
+			))
+		otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+		req.SetContext(context.WithValue(ctx, parentKey{}, parent))
+		return nil
+	})
+
+	// 收到响应（包括会被重试的响应）时结束本次尝试的 span
+	client.OnAfterResponse(func(_ *resty.Client, resp *resty.Response) error {
+		span := trace.SpanFromContext(resp.Request.Context())
+		span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode()))
+		if resp.StatusCode() >= http.StatusBadRequest {
+			span.SetStatus(codes.Error, resp.Status())
+		}
+		span.End()
+		return nil
+	})
+
+	// 网络错误不会经过 OnAfterResponse：重试前与最终失败时分别结束 span（End 可重复调用）
+	client.AddRetryHook(func(resp *resty.Response, err error) {
+		if err != nil && resp != nil && resp.Request != nil {
+			End(trace.SpanFromContext(resp.Request.Context()), err)
+		}
+	})
+	client.OnError(func(req *resty.Request, err error) {
+		End(trace.SpanFromContext(req.Context()), err)
+	})
+}
+
+// redactURL 去掉查询参数、片段与用户信息。重试时 req.URL 已包含编码后的 SetQueryParams，
+// 直接传入的 CDN 地址也可能带签名，因此不能原样记录
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	u.RawQuery = ""
+	u.ForceQuery = false
+	u.Fragment = ""
+	u.RawFragment = ""
+	u.User = nil
+	return u.String()
+}
@@ -3,6 +3,7 @@ package worker
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -12,29 +13,35 @@ import (
 	"time"
 
 	"github.com/azin/gdstudio-embed-service/internal/config"
+	"github.com/azin/gdstudio-embed-service/internal/errcode"
 	"github.com/azin/gdstudio-embed-service/internal/model"
 	"github.com/azin/gdstudio-embed-service/internal/repository"
+	"github.com/azin/gdstudio-embed-service/internal/service/cover"
+	"github.com/azin/gdstudio-embed-service/internal/service/events"
+	"github.com/azin/gdstudio-embed-service/internal/service/filename"
+	"github.com/azin/gdstudio-embed-service/internal/service/fsperm"
 	"github.com/azin/gdstudio-embed-service/internal/service/gdstudio"
+	"github.com/azin/gdstudio-embed-service/internal/service/library"
 	"github.com/azin/gdstudio-embed-service/internal/service/navidrome"
 	"github.com/azin/gdstudio-embed-service/internal/service/tagger"
+	"github.com/azin/gdstudio-embed-service/internal/tasks"
+	"github.com/azin/gdstudio-embed-service/internal/tracing"
 	"github.com/hibiken/asynq"
+	"go.opentelemetry.io/otel/attribute"
 	"go.uber.org/zap"
 )
 
-const (
-	TypeDownload = "download"
-)
+// progressEventInterval 下载进度事件的最小推送间隔
+const progressEventInterval = 250 * time.Millisecond
 
-// DownloadPayload 下载任务载荷
-type DownloadPayload struct {
-	JobID     string `json:"job_id"`
-	Source    string `json:"source"`
-	TrackID   string `json:"track_id"`
-	PicID     string `json:"pic_id,omitempty"`
-	LyricID   string `json:"lyric_id,omitempty"`
-	LibraryID string `json:"library_id"`
-	Quality   string `json:"quality"`
-}
+// stagedCoverName 工作目录中暂存的全尺寸封面
+const stagedCoverName = "cover.jpg"
+
+// errDuplicateSkipped 曲库中已有同一首歌，按重复策略跳过下载
+var errDuplicateSkipped = errors.New("duplicate of existing library track")
+
+// errCollisionSkipped 目标路径已有同名文件，按冲突策略保留已有文件
+var errCollisionSkipped = errors.New("target file already exists")
 
 // DownloadTask 下载任务处理器
 type DownloadTask struct {
@@ -43,6 +50,9 @@ type DownloadTask struct {
 	gdClient   *gdstudio.Client
 	naviClient *navidrome.Client
 	tagger     *tagger.Tagger
+	library    *library.Index
+	perms      *fsperm.Policy
+	events     *events.Bus
 	logger     *zap.Logger
 }
 
@@ -53,6 +63,9 @@ func NewDownloadTask(
 	gdClient *gdstudio.Client,
 	naviClient *navidrome.Client,
 	tagger *tagger.Tagger,
+	libraryIndex *library.Index,
+	perms *fsperm.Policy,
+	eventBus *events.Bus,
 	logger *zap.Logger,
 ) *DownloadTask {
 	return &DownloadTask{
@@ -61,50 +74,93 @@ func NewDownloadTask(
 		gdClient:   gdClient,
 		naviClient: naviClient,
 		tagger:     tagger,
+		library:    libraryIndex,
+		perms:      perms,
+		events:     eventBus,
 		logger:     logger,
 	}
 }
 
 // ProcessTask 处理任务
-func (t *DownloadTask) ProcessTask(ctx context.Context, task *asynq.Task) error {
-	var payload DownloadPayload
+func (t *DownloadTask) ProcessTask(ctx context.Context, task *asynq.Task) (err error) {
+	var payload tasks.DownloadPayload
 	if err := json.Unmarshal(task.Payload(), &payload); err != nil {
 		return fmt.Errorf("unmarshal payload failed: %w", err)
 	}
 
-	t.logger.Info("processing download task",
+	ctx, span := tracing.Start(tracing.Extract(ctx, payload.TraceContext), "job.download",
+		attribute.String("job.id", payload.JobID),
+		attribute.String("job.source", payload.Source),
+		attribute.String("job.track_id", payload.TrackID),
+		attribute.String("request.id", payload.RequestID))
+	defer func() { tracing.End(span, err) }()
+
+	// 任务级日志携带 request_id，可与创建任务的 API 访问日志关联
+	log := t.logger.With(
 		zap.String("job_id", payload.JobID),
+		zap.String("request_id", payload.RequestID))
+
+	log.Info("processing download task",
 		zap.String("source", payload.Source),
 		zap.String("track_id", payload.TrackID))
 
 	// 执行状态机流程
+	// code 为阶段失败时写入 Job.Error 的错误码（阶段返回的错误已带错误码时以其为准）
 	stages := []struct {
 		name string
-		fn   func(context.Context, *DownloadPayload) error
+		code errcode.Code
+		fn   func(context.Context, *tasks.DownloadPayload) error
 	}{
-		{model.JobStatusResolving, t.stageResolve},
-		{model.JobStatusDownloading, t.stageDownload},
-		{model.JobStatusTagging, t.stageTagging},
-		{model.JobStatusMoving, t.stageMoving},
-		{model.JobStatusScanning, t.stageScanning},
+		{model.JobStatusResolving, errcode.ResolveFailed, t.stageResolve},
+		{model.JobStatusDownloading, errcode.DownloadFailed, t.stageDownload},
+		{model.JobStatusTagging, errcode.TaggingFailed, t.stageTagging},
+		{model.JobStatusMoving, errcode.MoveFailed, t.stageMoving},
+		{model.JobStatusScanning, errcode.ScanFailed, t.stageScanning},
 	}
 
 	for _, stage := range stages {
 		// 更新状态。不要覆盖 message，message 字段在当前实现中用于阶段间传递下载 URL。
 		if err := t.repo.UpdateStatus(payload.JobID, stage.name, ""); err != nil {
-			t.logger.Error("failed to update status", zap.Error(err))
+			log.Error("failed to update status", zap.Error(err))
+		}
+		t.events.PublishStatus(payload.JobID, stage.name, "")
+
+		// 执行阶段，每个阶段一个子 span
+		stageCtx, stageSpan := tracing.Start(ctx, "stage."+stage.name)
+		err := stage.fn(stageCtx, &payload)
+		if errors.Is(err, errDuplicateSkipped) || errors.Is(err, errCollisionSkipped) {
+			stageSpan.SetAttributes(attribute.String("job.skipped", err.Error()))
+			tracing.End(stageSpan, nil)
+		} else {
+			tracing.End(stageSpan, err)
 		}
+		if err != nil {
+			if errors.Is(err, errDuplicateSkipped) {
+				t.removeWorkDir(payload.JobID)
+				return t.finishDuplicate(payload.JobID)
+			}
+			if errors.Is(err, errCollisionSkipped) {
+				t.removeWorkDir(payload.JobID)
+				return t.finishCollision(payload.JobID)
+			}
 
-		// 执行阶段
-		if err := stage.fn(ctx, &payload); err != nil {
-			t.logger.Error("stage failed",
+			if _, ok := errcode.As(err); !ok {
+				err = errcode.Wrap(stage.code, "", err)
+			}
+
+			log.Error("stage failed",
 				zap.String("stage", stage.name),
-				zap.String("job_id", payload.JobID),
 				zap.Error(err))
 
 			if markErr := t.repo.MarkFailed(payload.JobID, err); markErr != nil {
-				t.logger.Error("failed to mark job as failed", zap.Error(markErr))
+				log.Error("failed to mark job as failed", zap.Error(markErr))
 			}
+			t.events.Publish(ctx, events.Event{
+				Type:   events.TypeStatus,
+				JobID:  payload.JobID,
+				Status: model.JobStatusFailed,
+				Error:  err.Error(),
+			})
 
 			return fmt.Errorf("%s failed: %w", stage.name, err)
 		}
@@ -119,15 +175,40 @@ func (t *DownloadTask) ProcessTask(ctx context.Context, task *asynq.Task) error
 	if err := t.repo.MarkDone(payload.JobID, job.FilePath, job.FileSize); err != nil {
 		return fmt.Errorf("failed to mark job as done: %w", err)
 	}
-
-	t.logger.Info("download task completed", zap.String("job_id", payload.JobID))
+	t.removeWorkDir(payload.JobID)
+	t.events.Publish(ctx, events.Event{
+		Type:     events.TypeStatus,
+		JobID:    payload.JobID,
+		Status:   model.JobStatusDone,
+		Progress: 100,
+	})
+
+	log.Info("download task completed")
 	return nil
 }
 
 // stageResolve 阶段1：解析元数据
-func (t *DownloadTask) stageResolve(ctx context.Context, payload *DownloadPayload) error {
+func (t *DownloadTask) stageResolve(ctx context.Context, payload *tasks.DownloadPayload) error {
 	t.logger.Info("resolving metadata", zap.String("job_id", payload.JobID))
 
+	job, err := t.repo.FindByID(payload.JobID)
+	if err != nil {
+		return fmt.Errorf("failed to find job: %w", err)
+	}
+
+	// 下载前查重（只能使用客户端提供的元数据，下载后会按文件标签再查一次）
+	duplicate := t.findDuplicate(job, library.Query{
+		Title:    job.Title,
+		Artist:   job.Artist,
+		Album:    job.Album,
+		ISRC:     job.ISRC,
+		Duration: job.Duration,
+	})
+	policy := t.duplicatePolicy(job)
+	if duplicate != nil && policy == model.DuplicatePolicySkip {
+		return t.markDuplicate(job, duplicate)
+	}
+
 	// 解析音频 URL
 	bitrates := t.getBitrateCandidates(payload.Quality)
 	var (
@@ -135,7 +216,7 @@ func (t *DownloadTask) stageResolve(ctx context.Context, payload *DownloadPayloa
 		lastErr   error
 	)
 	for idx, bitrate := range bitrates {
-		urlResult, lastErr = t.gdClient.ResolveURL(payload.Source, payload.TrackID, bitrate)
+		urlResult, lastErr = t.gdClient.ResolveURL(ctx, payload.Source, payload.TrackID, bitrate)
 		if lastErr == nil {
 			if idx > 0 {
 				t.logger.Warn("resolve url succeeded after bitrate fallback",
@@ -161,12 +242,14 @@ func (t *DownloadTask) stageResolve(ctx context.Context, payload *DownloadPayloa
 		return fmt.Errorf("failed to resolve url after trying bitrates %v: %w", bitrates, lastErr)
 	}
 
-	// 更新任务信息
-	job, err := t.repo.FindByID(payload.JobID)
-	if err != nil {
-		return fmt.Errorf("failed to find job: %w", err)
+	// replace_if_better：只有新版本音质更高时才继续下载并替换曲库文件。
+	if duplicate != nil && policy == model.DuplicatePolicyReplaceIfBetter {
+		if err := t.replaceIfBetter(job, duplicate, urlResult.Extension, urlResult.Bitrate); err != nil {
+			return err
+		}
 	}
 
+	// 更新任务信息
 	job.TotalBytes = urlResult.Size
 	job.Bitrate = urlResult.Bitrate
 
@@ -181,7 +264,7 @@ func (t *DownloadTask) stageResolve(ctx context.Context, payload *DownloadPayloa
 }
 
 // stageDownload 阶段2：下载文件
-func (t *DownloadTask) stageDownload(ctx context.Context, payload *DownloadPayload) error {
+func (t *DownloadTask) stageDownload(ctx context.Context, payload *tasks.DownloadPayload) error {
 	t.logger.Info("downloading audio", zap.String("job_id", payload.JobID))
 
 	job, err := t.repo.FindByID(payload.JobID)
@@ -219,11 +302,30 @@ func (t *DownloadTask) stageDownload(ctx context.Context, payload *DownloadPaylo
 	if fileInfo != nil {
 		job.FileSize = fileInfo.Size()
 	}
+	// 标签读取失败不影响下载，只是无法补全时长、无法按文件标签查重
+	info, tagErr := tagger.ReadTags(tempFilePath)
+	if tagErr != nil {
+		t.logger.Warn("failed to read downloaded file tags",
+			zap.String("job_id", job.ID),
+			zap.Error(tagErr))
+		info = nil
+	}
+	if info != nil && info.Duration > 0 {
+		job.Duration = info.Duration
+	}
 
 	if err := t.repo.Update(job); err != nil {
 		return fmt.Errorf("failed to update job: %w", err)
 	}
 
+	// 下载后按文件自带的标签再查重：下载前客户端可能没有提供元数据，时长也未知，
+	// 其他音源下载的同一首歌只能在这里识别。已匹配过的任务（replace_if_better）不再处理
+	if info != nil && job.DuplicateOf == "" {
+		if err := t.checkDownloadedDuplicate(job, info); err != nil {
+			return err
+		}
+	}
+
 	t.logger.Info("download completed",
 		zap.String("job_id", payload.JobID),
 		zap.Int64("size", job.FileSize))
@@ -232,7 +334,7 @@ func (t *DownloadTask) stageDownload(ctx context.Context, payload *DownloadPaylo
 }
 
 // stageTagging 阶段3：写入标签
-func (t *DownloadTask) stageTagging(ctx context.Context, payload *DownloadPayload) error {
+func (t *DownloadTask) stageTagging(ctx context.Context, payload *tasks.DownloadPayload) error {
 	t.logger.Info("writing tags", zap.String("job_id", payload.JobID))
 
 	job, err := t.repo.FindByID(payload.JobID)
@@ -252,7 +354,7 @@ func (t *DownloadTask) stageTagging(ctx context.Context, payload *DownloadPayloa
 
 	// 当未显式提供 pic_id / lyric_id 时，先通过 search 反查。
 	if payload.PicID == "" || payload.PicID == payload.TrackID || payload.LyricID == "" {
-		resolvedPicID, resolvedLyricID, err := t.gdClient.ResolveAuxIDs(payload.Source, payload.TrackID, job.Title, job.Artist)
+		resolvedPicID, resolvedLyricID, err := t.gdClient.ResolveAuxIDs(ctx, payload.Source, payload.TrackID, job.Title, job.Artist)
 		if err != nil {
 			t.logger.Debug("failed to resolve aux ids from search",
 				zap.String("source", payload.Source),
@@ -273,7 +375,7 @@ func (t *DownloadTask) stageTagging(ctx context.Context, payload *DownloadPayloa
 	var coverURL string
 	var coverData []byte
 	if coverID != "" {
-		resolvedCoverURL, err := t.gdClient.ResolveCover(payload.Source, coverID)
+		resolvedCoverURL, err := t.gdClient.ResolveCover(ctx, payload.Source, coverID)
 		if err != nil {
 			errMsg := strings.ToLower(err.Error())
 			if strings.Contains(errMsg, "not found") || strings.Contains(errMsg, "empty or error response") {
@@ -283,7 +385,7 @@ func (t *DownloadTask) stageTagging(ctx context.Context, payload *DownloadPayloa
 			}
 		} else if resolvedCoverURL != "" {
 			coverURL = resolvedCoverURL
-			data, err := t.gdClient.DownloadCover(payload.Source, resolvedCoverURL)
+			data, err := t.gdClient.DownloadCover(ctx, payload.Source, resolvedCoverURL)
 			if err != nil {
 				t.logger.Warn("failed to download cover",
 					zap.String("source", payload.Source),
@@ -297,11 +399,17 @@ func (t *DownloadTask) stageTagging(ctx context.Context, payload *DownloadPayloa
 		}
 	}
 
+	// 处理封面：转码为 JPEG、按预算缩放后嵌入，全尺寸版本留给专辑目录。
+	var coverMIME string
+	if len(coverData) > 0 {
+		coverData, coverMIME = t.processCover(job, coverData)
+	}
+
 	// 解析歌词（最佳努力，不阻塞主流程）。
 	var lyrics string
 	var translation string
 	if lyricID != "" {
-		lyricResult, err := t.gdClient.ResolveLyrics(payload.Source, lyricID)
+		lyricResult, err := t.gdClient.ResolveLyrics(ctx, payload.Source, lyricID)
 		if err != nil {
 			errMsg := strings.ToLower(err.Error())
 			if strings.Contains(errMsg, "not found") || strings.Contains(errMsg, "empty or error response") {
@@ -322,8 +430,10 @@ func (t *DownloadTask) stageTagging(ctx context.Context, payload *DownloadPayloa
 		Album:       job.Album,
 		TrackNumber: job.TrackNumber,
 		Year:        job.Year,
+		Duration:    job.Duration,
 		CoverURL:    coverURL,
 		CoverData:   coverData,
+		CoverMIME:   coverMIME,
 		Lyrics:      lyrics,
 		Translation: translation,
 	}
@@ -335,8 +445,8 @@ func (t *DownloadTask) stageTagging(ctx context.Context, payload *DownloadPayloa
 	}
 
 	// 写入 .lrc 文件
-	if lyrics != "" {
-		if err := t.tagger.WriteLyricFile(job.FilePath, lyrics); err != nil {
+	if lyrics != "" || translation != "" {
+		if err := t.tagger.WriteLyricFile(job.FilePath, job.LibraryID, metadata); err != nil {
 			t.logger.Warn("failed to write lyric file", zap.Error(err))
 		}
 	}
@@ -345,7 +455,7 @@ func (t *DownloadTask) stageTagging(ctx context.Context, payload *DownloadPayloa
 }
 
 // stageMoving 阶段4：移动到目标目录
-func (t *DownloadTask) stageMoving(ctx context.Context, payload *DownloadPayload) error {
+func (t *DownloadTask) stageMoving(ctx context.Context, payload *tasks.DownloadPayload) error {
 	t.logger.Info("moving to library", zap.String("job_id", payload.JobID))
 
 	job, err := t.repo.FindByID(payload.JobID)
@@ -353,55 +463,87 @@ func (t *DownloadTask) stageMoving(ctx context.Context, payload *DownloadPayload
 		return fmt.Errorf("failed to find job: %w", err)
 	}
 
-	// 构建目标路径
+	// 构建目标路径（替换曲库已有版本时沿用原路径，仅扩展名可能变化）
 	sourcePath := job.FilePath
 	targetPath := t.buildTargetPath(job)
+	replacing := job.DuplicateOf != "" && t.duplicatePolicy(job) == model.DuplicatePolicyReplaceIfBetter
+	if replacing {
+		targetPath = strings.TrimSuffix(job.DuplicateOf, filepath.Ext(job.DuplicateOf)) + filepath.Ext(sourcePath)
+	}
 	targetDir := filepath.Dir(targetPath)
 
 	// 创建目标目录
-	if err := os.MkdirAll(targetDir, 0755); err != nil {
+	if err := t.perms.MkdirAll(targetDir); err != nil {
 		return fmt.Errorf("failed to create target dir: %w", err)
 	}
 
-	// 移动文件（同分区使用 rename，跨分区使用 copy）
-	if err := os.Rename(sourcePath, targetPath); err != nil {
-		// Fallback: copy then delete
-		if err := t.copyFile(sourcePath, targetPath); err != nil {
-			return fmt.Errorf("failed to copy file: %w", err)
+	// 移动文件（同分区使用 rename，跨分区经临时文件复制校验后 rename）。
+	// 替换曲库已有版本时覆盖是预期行为，其余情况按冲突策略处理同名文件
+	if replacing {
+		if err := moveFile(sourcePath, targetPath, t.logger); err != nil {
+			return fmt.Errorf("failed to move file: %w", err)
+		}
+	} else {
+		targetPath, err = t.placeFile(job, sourcePath, targetPath)
+		if err != nil {
+			return err
 		}
-		os.Remove(sourcePath)
+	}
+	// 文件已进入曲库，权限设置失败只记录日志，不中止任务
+	if err := t.perms.ApplyFile(targetPath); err != nil {
+		t.logger.Warn("failed to apply file permissions", zap.String("path", targetPath), zap.Error(err))
+	}
+
+	// 写入专辑目录封面（cover.jpg/folder.jpg）。
+	if err := t.moveAlbumCover(filepath.Dir(sourcePath), targetDir); err != nil {
+		t.logger.Warn("failed to write album cover", zap.Error(err))
 	}
 
-	// 把同名的 sidecar 文件一并移动到目标目录。
-	for _, ext := range []string{".lrc", ".nfo"} {
+	// 把同名的 sidecar 文件一并移动到目标目录（含 separate 模式生成的 .<lang>.lrc）。
+	for _, ext := range t.sidecarExts(sourcePath) {
 		if err := t.moveSidecar(sourcePath, targetPath, ext); err != nil {
 			t.logger.Warn("failed to move sidecar", zap.String("ext", ext), zap.Error(err))
 		}
 	}
 
+	// 扩展名变化时（例如 mp3 升级为 flac）删除被替换的旧文件。
+	if replacing && job.DuplicateOf != targetPath {
+		if err := os.Remove(job.DuplicateOf); err != nil && !os.IsNotExist(err) {
+			t.logger.Warn("failed to remove replaced file", zap.String("path", job.DuplicateOf), zap.Error(err))
+		}
+		if err := t.library.Remove(job.DuplicateOf); err != nil {
+			t.logger.Warn("failed to remove replaced file from index", zap.Error(err))
+		}
+	}
+
 	// 更新文件路径
 	job.FilePath = targetPath
 	if err := t.repo.Update(job); err != nil {
 		return fmt.Errorf("failed to update job: %w", err)
 	}
 
+	// 加入曲库索引，后续任务可据此查重。
+	if err := t.library.IndexFile(targetPath, job.ID); err != nil {
+		t.logger.Warn("failed to index library file", zap.String("path", targetPath), zap.Error(err))
+	}
+
 	t.logger.Info("file moved", zap.String("path", targetPath))
 	return nil
 }
 
 // stageScanning 阶段5：触发 Navidrome 扫描
-func (t *DownloadTask) stageScanning(ctx context.Context, payload *DownloadPayload) error {
+func (t *DownloadTask) stageScanning(ctx context.Context, payload *tasks.DownloadPayload) error {
 	t.logger.Info("triggering navidrome scan", zap.String("job_id", payload.JobID))
 
 	// 触发扫描
-	if err := t.naviClient.StartScan(); err != nil {
+	if err := t.naviClient.StartScan(ctx); err != nil {
 		t.logger.Warn("failed to start scan", zap.Error(err))
 		// 非致命错误
 		return nil
 	}
 
 	// 等待扫描完成（带超时）
-	if err := t.naviClient.WaitForScan(t.cfg.Worker.ScanTimeout); err != nil {
+	if err := t.naviClient.WaitForScan(ctx, t.cfg.Worker.ScanTimeout); err != nil {
 		t.logger.Warn("scan wait failed", zap.Error(err))
 		// 非致命错误
 	}
@@ -409,18 +551,400 @@ func (t *DownloadTask) stageScanning(ctx context.Context, payload *DownloadPaylo
 	return nil
 }
 
+// duplicatePolicy 返回任务的重复处理策略（未指定时使用配置默认值）
+func (t *DownloadTask) duplicatePolicy(job *model.Job) string {
+	if job.DuplicatePolicy != "" {
+		return job.DuplicatePolicy
+	}
+	return t.cfg.Library.DuplicatePolicy
+}
+
+// findDuplicate 在曲库索引中查找同一首歌（最佳努力，查询失败时视为不重复）
+func (t *DownloadTask) findDuplicate(job *model.Job, q library.Query) *model.LibraryTrack {
+	if t.duplicatePolicy(job) == model.DuplicatePolicyKeepBoth {
+		return nil
+	}
+
+	duplicate, err := t.library.FindDuplicate(q)
+	if err != nil {
+		t.logger.Warn("library duplicate check failed", zap.String("job_id", job.ID), zap.Error(err))
+		return nil
+	}
+	return duplicate
+}
+
+// checkDownloadedDuplicate 以下载文件的标签（缺失时用任务元数据）查重并按策略处理
+func (t *DownloadTask) checkDownloadedDuplicate(job *model.Job, info *tagger.TagInfo) error {
+	q := library.Query{
+		Title:    firstNonEmpty(info.Title, job.Title),
+		Artist:   firstNonEmpty(info.Artist, job.Artist),
+		Album:    firstNonEmpty(info.Album, job.Album),
+		ISRC:     firstNonEmpty(info.ISRC, job.ISRC),
+		Duration: info.Duration,
+	}
+	duplicate := t.findDuplicate(job, q)
+	if duplicate == nil {
+		return nil
+	}
+
+	switch t.duplicatePolicy(job) {
+	case model.DuplicatePolicySkip:
+		return t.markDuplicate(job, duplicate)
+	case model.DuplicatePolicyReplaceIfBetter:
+		bitrate := job.Bitrate
+		if info.Bitrate > 0 {
+			bitrate = info.Bitrate
+		}
+		if err := t.replaceIfBetter(job, duplicate, info.Format, bitrate); err != nil {
+			return err
+		}
+		if err := t.repo.Update(job); err != nil {
+			return fmt.Errorf("failed to update job: %w", err)
+		}
+	}
+	return nil
+}
+
+// replaceIfBetter 新版本音质更高时记录要替换的曲库文件，否则按重复跳过
+func (t *DownloadTask) replaceIfBetter(job *model.Job, duplicate *model.LibraryTrack, format string, bitrate int) error {
+	newScore := library.QualityScore(format, bitrate)
+	oldScore := library.QualityScore(duplicate.Format, duplicate.Bitrate)
+	if newScore <= oldScore {
+		return t.markDuplicate(job, duplicate)
+	}
+	t.logger.Info("better version found, will replace library track",
+		zap.String("job_id", job.ID),
+		zap.String("path", duplicate.Path),
+		zap.Int("old_bitrate", duplicate.Bitrate),
+		zap.Int("new_bitrate", bitrate))
+	job.DuplicateOf = duplicate.Path
+	return nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// markDuplicate 记录匹配到的曲库文件并中止流水线
+func (t *DownloadTask) markDuplicate(job *model.Job, duplicate *model.LibraryTrack) error {
+	job.DuplicateOf = duplicate.Path
+	if err := t.repo.Update(job); err != nil {
+		return fmt.Errorf("failed to update job: %w", err)
+	}
+
+	t.logger.Info("track already in library, skip download",
+		zap.String("job_id", job.ID),
+		zap.String("path", duplicate.Path),
+		zap.String("policy", t.duplicatePolicy(job)))
+	return errDuplicateSkipped
+}
+
+// finishDuplicate 将因重复而跳过的任务标记为完成，文件路径指向曲库中已有的文件
+func (t *DownloadTask) finishDuplicate(jobID string) error {
+	job, err := t.repo.FindByID(jobID)
+	if err != nil {
+		return fmt.Errorf("failed to find job: %w", err)
+	}
+
+	message := "skipped: " + errDuplicateSkipped.Error()
+	if err := t.repo.MarkSkipped(jobID, job.DuplicateOf, message); err != nil {
+		return fmt.Errorf("failed to mark job as skipped: %w", err)
+	}
+	t.events.PublishStatus(jobID, model.JobStatusDone, message)
+	return nil
+}
+
+// collisionPolicy 返回任务的文件名冲突策略（未指定时使用配置默认值）
+func (t *DownloadTask) collisionPolicy(job *model.Job) string {
+	if job.CollisionPolicy != "" {
+		return job.CollisionPolicy
+	}
+	return t.cfg.Storage.CollisionPolicy
+}
+
+// placeFile 将新文件放入曲库：按冲突策略决定最终路径并把处理结果记录到 job，返回最终路径。
+// 文件先移动到目标目录中按任务 ID 命名的隐藏临时文件，再以硬链接占用最终文件名（已存在时失败，不会覆盖），
+// 曲库中不会出现空的或未写完的文件，并发任务也不会互相覆盖同名文件。
+// 占用的路径记录在 job.ReservedPath，重试时直接覆盖自己上次写入的文件，不会当作冲突再生成副本。
+// 保留已有文件时返回 errCollisionSkipped。
+func (t *DownloadTask) placeFile(job *model.Job, sourcePath, targetPath string) (string, error) {
+	if job.ReservedPath != "" {
+		if _, err := os.Stat(job.ReservedPath); err == nil {
+			return t.replaceOwnFile(job, sourcePath, targetPath)
+		}
+	}
+
+	// keep_better 需要比较音质，暂存文件没有音频扩展名，先读取源文件
+	policy := t.collisionPolicy(job)
+	sourceScore := 0
+	if policy == model.CollisionPolicyKeepBetter {
+		sourceScore = fileQualityScore(sourcePath)
+	}
+
+	stagedPath, err := t.stageInDir(job, sourcePath, filepath.Dir(targetPath))
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(stagedPath) // 占用成功后临时文件名已无用，失败时丢弃新文件
+
+	ok, err := claimPath(stagedPath, targetPath)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		switch policy {
+		case model.CollisionPolicySkip:
+			job.CollisionOutcome = model.CollisionOutcomeSkipped
+		case model.CollisionPolicyOverwrite:
+			job.CollisionOutcome = model.CollisionOutcomeOverwritten
+		case model.CollisionPolicyKeepBetter:
+			if sourceScore > fileQualityScore(targetPath) {
+				job.CollisionOutcome = model.CollisionOutcomeReplaced
+			} else {
+				job.CollisionOutcome = model.CollisionOutcomeKeptExisting
+			}
+		default:
+			suffixed, err := claimSuffixedPath(stagedPath, targetPath)
+			if err != nil {
+				return "", err
+			}
+			job.CollisionOutcome = model.CollisionOutcomeSuffixed
+			targetPath = suffixed
+		}
+
+		t.logger.Info("target file already exists",
+			zap.String("job_id", job.ID),
+			zap.String("path", targetPath),
+			zap.String("policy", policy),
+			zap.String("outcome", job.CollisionOutcome))
+
+		switch job.CollisionOutcome {
+		case model.CollisionOutcomeSkipped, model.CollisionOutcomeKeptExisting:
+			// 丢弃新下载的文件，任务指向已有文件
+			job.FilePath = targetPath
+			if err := t.repo.Update(job); err != nil {
+				return "", fmt.Errorf("failed to update job: %w", err)
+			}
+			return "", errCollisionSkipped
+		case model.CollisionOutcomeOverwritten, model.CollisionOutcomeReplaced:
+			if err := os.Rename(stagedPath, targetPath); err != nil {
+				return "", fmt.Errorf("failed to replace existing file: %w", err)
+			}
+		}
+	}
+	syncDirBestEffort(filepath.Dir(targetPath), t.logger)
+
+	job.ReservedPath = targetPath
+	if err := t.repo.Update(job); err != nil {
+		return "", fmt.Errorf("failed to update job: %w", err)
+	}
+	return targetPath, nil
+}
+
+// replaceOwnFile 重试时用新文件覆盖本任务上次写入曲库的文件
+func (t *DownloadTask) replaceOwnFile(job *model.Job, sourcePath, targetPath string) (string, error) {
+	stagedPath, err := t.stageInDir(job, sourcePath, filepath.Dir(job.ReservedPath))
+	if err != nil {
+		return "", err
+	}
+	if err := os.Rename(stagedPath, job.ReservedPath); err != nil {
+		os.Remove(stagedPath)
+		return "", fmt.Errorf("failed to replace previous attempt: %w", err)
+	}
+	syncDirBestEffort(filepath.Dir(job.ReservedPath), t.logger)
+
+	if job.ReservedPath != targetPath && job.CollisionOutcome == "" {
+		job.CollisionOutcome = model.CollisionOutcomeSuffixed
+	}
+	t.logger.Info("replacing file written by previous attempt",
+		zap.String("job_id", job.ID),
+		zap.String("path", job.ReservedPath))
+	return job.ReservedPath, nil
+}
+
+// stageInDir 将新文件移动到目标目录中的隐藏临时文件（同一任务的文件名固定，覆盖上次中断时的遗留）
+func (t *DownloadTask) stageInDir(job *model.Job, sourcePath, dir string) (string, error) {
+	stagedPath := filepath.Join(dir, "."+job.ID+filepath.Ext(sourcePath)+".tmp")
+	if err := moveFile(sourcePath, stagedPath, t.logger); err != nil {
+		return "", fmt.Errorf("failed to move file: %w", err)
+	}
+	return stagedPath, nil
+}
+
+// finishCollision 将因文件名冲突而保留已有文件的任务标记为完成
+func (t *DownloadTask) finishCollision(jobID string) error {
+	job, err := t.repo.FindByID(jobID)
+	if err != nil {
+		return fmt.Errorf("failed to find job: %w", err)
+	}
+
+	message := fmt.Sprintf("%s: %s", job.CollisionOutcome, errCollisionSkipped.Error())
+	if err := t.repo.MarkSkipped(jobID, job.FilePath, message); err != nil {
+		return fmt.Errorf("failed to mark job as skipped: %w", err)
+	}
+	t.events.PublishStatus(jobID, model.JobStatusDone, message)
+	return nil
+}
+
+// claimSuffixedPath 以第一个可用的 "name (N).ext" 路径占用暂存文件
+func claimSuffixedPath(stagedPath, path string) (string, error) {
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+	for n := 2; n < 1000; n++ {
+		candidate := fmt.Sprintf("%s (%d)%s", base, n, ext)
+		ok, err := claimPath(stagedPath, candidate)
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("no free file name for %s", path)
+}
+
+// claimPath 以硬链接让暂存文件占用 path，path 已存在时返回 false。
+// 文件系统不支持硬链接时退回检查后 rename（不再是原子操作）
+func claimPath(stagedPath, path string) (bool, error) {
+	err := os.Link(stagedPath, path)
+	if err == nil {
+		return true, nil
+	}
+	if os.IsExist(err) {
+		return false, nil
+	}
+
+	if _, statErr := os.Lstat(path); statErr == nil {
+		return false, nil
+	} else if !os.IsNotExist(statErr) {
+		return false, fmt.Errorf("failed to check target path: %w", statErr)
+	}
+	if err := os.Rename(stagedPath, path); err != nil {
+		return false, fmt.Errorf("failed to claim target path: %w", err)
+	}
+	return true, nil
+}
+
+// fileQualityScore 读取文件实际格式与码率并换算为音质评分，无法读取时为 0
+func fileQualityScore(path string) int {
+	info, err := tagger.ReadTags(path)
+	if err != nil {
+		return 0
+	}
+	return library.QualityScore(info.Format, info.Bitrate)
+}
+
+// processCover 转码并压缩封面，返回用于嵌入的数据与 MIME。
+// 全尺寸 JPEG 写入任务工作目录，供 stageMoving 放到专辑目录。
+// 任务将替换的曲库文件中已有相同封面时直接沿用，不再重新处理与写入专辑封面；
+// 无法解码的格式（例如 AVIF）不嵌入，避免播放器无法显示。
+func (t *DownloadTask) processCover(job *model.Job, data []byte) ([]byte, string) {
+	existing := t.libraryCover(job)
+	if len(existing) > 0 && cover.Hash(existing) == cover.Hash(data) {
+		t.logger.Debug("cover identical to library file, skip processing", zap.String("job_id", job.ID))
+		return existing, cover.DetectMIME(existing)
+	}
+
+	coverCfg := t.cfg.Tagger.Cover
+	result, err := cover.Process(data, cover.Options{
+		MaxDimension: coverCfg.MaxDimension,
+		MaxBytes:     coverCfg.MaxBytes,
+		Quality:      coverCfg.JPEGQuality,
+	})
+	if err != nil {
+		// 无法解码（如 AVIF）时保留原始封面嵌入，不转码、不生成专辑目录 cover.jpg；
+		// 不是图片的数据（例如 CDN 返回的错误页）才丢弃。
+		mimeType := cover.DetectMIME(data)
+		if !strings.HasPrefix(mimeType, "image/") {
+			t.logger.Warn("cover is not an image, cover skipped",
+				zap.String("job_id", job.ID),
+				zap.String("mime", mimeType),
+				zap.Error(err))
+			return nil, ""
+		}
+		t.logger.Warn("failed to process cover, embedding original",
+			zap.String("job_id", job.ID),
+			zap.String("mime", mimeType),
+			zap.Error(err))
+		return data, mimeType
+	}
+
+	if len(existing) > 0 && cover.Hash(existing) == result.Embed.Hash() {
+		t.logger.Debug("cover identical to library file, keep album cover", zap.String("job_id", job.ID))
+		return existing, result.Embed.MIME
+	}
+
+	fullPath := filepath.Join(filepath.Dir(job.FilePath), stagedCoverName)
+	if err := os.WriteFile(fullPath, result.Full.Data, 0644); err != nil {
+		t.logger.Warn("failed to save full size cover", zap.Error(err))
+	}
+
+	t.logger.Debug("cover processed",
+		zap.String("job_id", job.ID),
+		zap.Int("full_width", result.Full.Width),
+		zap.Int("full_size", len(result.Full.Data)),
+		zap.Int("embed_width", result.Embed.Width),
+		zap.Int("embed_size", len(result.Embed.Data)))
+
+	return result.Embed.Data, result.Embed.MIME
+}
+
+// libraryCover 读取任务将替换的曲库文件（重复替换或目标路径上已有的文件）中嵌入的封面，没有时返回 nil
+func (t *DownloadTask) libraryCover(job *model.Job) []byte {
+	path := job.DuplicateOf
+	if path == "" {
+		path = t.buildTargetPath(job)
+	}
+	if _, err := os.Stat(path); err != nil {
+		return nil
+	}
+	metadata, err := tagger.ReadMetadata(path)
+	if err != nil {
+		t.logger.Debug("failed to read library file cover", zap.String("path", path), zap.Error(err))
+		return nil
+	}
+	return metadata.CoverData
+}
+
+// moveAlbumCover 将工作目录中的全尺寸封面写入专辑目录（是否覆盖由 tagger 按配置决定）。
+func (t *DownloadTask) moveAlbumCover(workDir, albumDir string) error {
+	srcPath := filepath.Join(workDir, stagedCoverName)
+	data, err := os.ReadFile(srcPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer os.Remove(srcPath)
+
+	_, err = t.tagger.WriteAlbumCover(albumDir, data)
+	return err
+}
+
 // downloadFile 下载文件并报告进度
-func (t *DownloadTask) downloadFile(ctx context.Context, url, destPath, jobID string) error {
+func (t *DownloadTask) downloadFile(ctx context.Context, url, destPath, jobID string) (err error) {
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return err
 	}
 
+	// CDN 地址带有临时签名，span 中只记录主机名
+	ctx, span := tracing.Start(ctx, "cdn.download", attribute.String("server.address", req.URL.Host))
+	defer func() { tracing.End(span, err) }()
+	req = req.WithContext(ctx)
+
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
+	span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
 
 	if resp.StatusCode != 200 {
 		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
@@ -438,6 +962,8 @@ func (t *DownloadTask) downloadFile(ctx context.Context, url, destPath, jobID st
 
 	buffer := make([]byte, 32*1024)
 	lastUpdate := time.Now()
+	lastEvent := time.Now()
+	lastProgress := -1
 
 	for {
 		n, err := resp.Body.Read(buffer)
@@ -447,15 +973,30 @@ func (t *DownloadTask) downloadFile(ctx context.Context, url, destPath, jobID st
 			}
 			completedBytes += int64(n)
 
-			// 每秒更新一次进度
-			if time.Since(lastUpdate) > time.Second {
-				progress := 0
-				if totalBytes > 0 {
-					progress = int(float64(completedBytes) / float64(totalBytes) * 100)
-				}
+			progress := 0
+			if totalBytes > 0 {
+				progress = int(float64(completedBytes) / float64(totalBytes) * 100)
+			}
+
+			// 每秒写一次数据库（jobID 为空时不记录进度，例如音质升级）
+			if jobID != "" && time.Since(lastUpdate) > time.Second {
 				t.repo.UpdateProgress(jobID, progress, completedBytes, totalBytes)
 				lastUpdate = time.Now()
 			}
+
+			// 进度事件更频繁但节流，且百分比不变时不重复推送
+			if jobID != "" && progress != lastProgress && time.Since(lastEvent) >= progressEventInterval {
+				t.events.Publish(ctx, events.Event{
+					Type:           events.TypeProgress,
+					JobID:          jobID,
+					Status:         model.JobStatusDownloading,
+					Progress:       progress,
+					CompletedBytes: completedBytes,
+					TotalBytes:     totalBytes,
+				})
+				lastEvent = time.Now()
+				lastProgress = progress
+			}
 		}
 
 		if err == io.EOF {
@@ -469,22 +1010,17 @@ func (t *DownloadTask) downloadFile(ctx context.Context, url, destPath, jobID st
 	return nil
 }
 
-// copyFile 跨文件系统复制文件
-func (t *DownloadTask) copyFile(src, dst string) error {
-	source, err := os.Open(src)
-	if err != nil {
-		return err
-	}
-	defer source.Close()
+// sidecarExts 返回与音频同名的 sidecar 扩展名列表。
+func (t *DownloadTask) sidecarExts(audioPath string) []string {
+	exts := []string{".lrc", ".nfo"}
 
-	destination, err := os.Create(dst)
-	if err != nil {
-		return err
+	base := strings.TrimSuffix(audioPath, filepath.Ext(audioPath))
+	matches, _ := filepath.Glob(base + ".*.lrc")
+	for _, match := range matches {
+		exts = append(exts, strings.TrimPrefix(match, base))
 	}
-	defer destination.Close()
 
-	_, err = io.Copy(destination, source)
-	return err
+	return exts
 }
 
 func (t *DownloadTask) moveSidecar(srcAudioPath, dstAudioPath, ext string) error {
@@ -497,31 +1033,30 @@ func (t *DownloadTask) moveSidecar(srcAudioPath, dstAudioPath, ext string) error
 	}
 
 	dstPath := strings.TrimSuffix(dstAudioPath, filepath.Ext(dstAudioPath)) + ext
-	if err := os.Rename(srcPath, dstPath); err != nil {
-		if err := t.copyFile(srcPath, dstPath); err != nil {
-			return err
-		}
-		return os.Remove(srcPath)
+	if err := moveFile(srcPath, dstPath, t.logger); err != nil {
+		return err
+	}
+	if err := t.perms.ApplyFile(dstPath); err != nil {
+		t.logger.Warn("failed to apply file permissions", zap.String("path", dstPath), zap.Error(err))
 	}
-
 	return nil
 }
 
 // buildTargetPath 构建目标路径
 func (t *DownloadTask) buildTargetPath(job *model.Job) string {
-	// 清理文件名中的非法字符
-	cleanArtist := sanitizeFilename(job.Artist)
-	cleanAlbum := sanitizeFilename(job.Album)
-	cleanTitle := sanitizeFilename(job.Title)
+	// 按配置的策略清理路径组件中的非法字符
+	profile := t.cfg.Storage.SanitizeProfile
+	cleanArtist := filename.Sanitize(job.Artist, profile)
+	cleanAlbum := filename.Sanitize(job.Album, profile)
 
 	ext := filepath.Ext(job.FilePath)
-	filename := fmt.Sprintf("%02d - %s%s", job.TrackNumber, cleanTitle, ext)
+	name := filename.SanitizeFile(fmt.Sprintf("%02d - %s", job.TrackNumber, job.Title), ext, profile)
 
 	return filepath.Join(
 		t.cfg.Storage.MusicDir,
 		cleanArtist,
 		cleanAlbum,
-		filename,
+		name,
 	)
 }
 
@@ -567,14 +1102,3 @@ func (t *DownloadTask) getBitrateCandidates(quality string) []int {
 
 	return unique
 }
-
-// sanitizeFilename 清理文件名
-func sanitizeFilename(name string) string {
-	// 移除非法字符
-	invalid := []string{"/", "\\", ":", "*", "?", "\"", "<", ">", "|"}
-	result := name
-	for _, char := range invalid {
-		result = strings.ReplaceAll(result, char, "_")
-	}
-	return strings.TrimSpace(result)
-}
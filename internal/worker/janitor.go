@@ -0,0 +1,144 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/azin/gdstudio-embed-service/internal/config"
+	"github.com/azin/gdstudio-embed-service/internal/model"
+	"github.com/azin/gdstudio-embed-service/internal/repository"
+	"github.com/azin/gdstudio-embed-service/internal/tasks"
+	"github.com/hibiken/asynq"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// upgradeWorkSuffix 音质升级任务工作目录后缀（WorkDir/<jobID>-upgrade）
+const upgradeWorkSuffix = "-upgrade"
+
+// Janitor 周期清理工作目录中的孤儿任务目录
+type Janitor struct {
+	cfg       *config.Config
+	repo      *repository.JobRepository
+	inspector *asynq.Inspector
+	logger    *zap.Logger
+}
+
+// NewJanitor 创建工作目录清理器，inspector 用于判断音质升级任务是否仍在执行
+func NewJanitor(cfg *config.Config, repo *repository.JobRepository, inspector *asynq.Inspector, logger *zap.Logger) *Janitor {
+	return &Janitor{
+		cfg:       cfg,
+		repo:      repo,
+		inspector: inspector,
+		logger:    logger,
+	}
+}
+
+// Run 启动时清理一次，之后按 workdir_gc_interval 周期清理，直到 ctx 取消。
+func (j *Janitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.cfg.Worker.WorkDirGCInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := j.Sweep(); err != nil {
+			j.logger.Warn("work dir cleanup failed", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sweep 删除任务已完成/已取消/不存在的工作目录，失败任务的目录保留 failed_work_retention 后删除。
+// 最近 download_timeout 内修改过的目录视为仍在使用，不做处理。
+func (j *Janitor) Sweep() error {
+	entries, err := os.ReadDir(j.cfg.Storage.WorkDir)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	removed := 0
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || now.Sub(info.ModTime()) < j.cfg.Worker.DownloadTimeout {
+			continue
+		}
+
+		// 升级目录对应的任务已是 done，写入文件不会更新目录的修改时间，需按升级任务本身判断
+		jobID := strings.TrimSuffix(entry.Name(), upgradeWorkSuffix)
+		if jobID != entry.Name() && j.upgradeRunning(jobID) {
+			continue
+		}
+		if !j.expired(jobID, now) {
+			continue
+		}
+
+		path := filepath.Join(j.cfg.Storage.WorkDir, entry.Name())
+		if err := os.RemoveAll(path); err != nil {
+			j.logger.Warn("failed to remove work dir", zap.String("path", path), zap.Error(err))
+			continue
+		}
+		removed++
+	}
+
+	if removed > 0 {
+		j.logger.Info("work dir cleanup completed", zap.Int("removed", removed))
+	}
+	return nil
+}
+
+// expired 判断任务的工作目录是否可以删除
+func (j *Janitor) expired(jobID string, now time.Time) bool {
+	job, err := j.repo.FindByID(jobID)
+	if err != nil {
+		return errors.Is(err, gorm.ErrRecordNotFound)
+	}
+
+	switch job.Status {
+	case model.JobStatusDone, model.JobStatusCancelled:
+		return true
+	case model.JobStatusFailed:
+		return now.Sub(job.UpdatedAt) >= j.cfg.Worker.FailedWorkRetention
+	default:
+		return false
+	}
+}
+
+// upgradeRunning 任务的音质升级是否仍在队列中（任务 ID 固定为 upgrade:<jobID>）。
+// 查询失败时视为仍在执行，避免误删
+func (j *Janitor) upgradeRunning(jobID string) bool {
+	info, err := j.inspector.GetTaskInfo(tasks.DefaultQueue, tasks.UpgradeTaskID(jobID))
+	if errors.Is(err, asynq.ErrTaskNotFound) || errors.Is(err, asynq.ErrQueueNotFound) {
+		return false
+	}
+	if err != nil {
+		j.logger.Warn("failed to check upgrade task", zap.String("job_id", jobID), zap.Error(err))
+		return true
+	}
+	// 已归档或已完成的任务不会再使用工作目录
+	switch info.State {
+	case asynq.TaskStateArchived, asynq.TaskStateCompleted:
+		return false
+	default:
+		return true
+	}
+}
+
+// removeWorkDir 删除任务工作目录（最佳努力）
+func (t *DownloadTask) removeWorkDir(jobID string) {
+	workDir := filepath.Join(t.cfg.Storage.WorkDir, jobID)
+	if err := os.RemoveAll(workDir); err != nil {
+		t.logger.Warn("failed to remove work dir", zap.String("path", workDir), zap.Error(err))
+	}
+}
@@ -0,0 +1,24 @@
+package worker
+
+import (
+	"context"
+
+	"github.com/azin/gdstudio-embed-service/internal/service/maintenance"
+	"github.com/hibiken/asynq"
+)
+
+// MaintenanceTask 定时维护任务处理器
+type MaintenanceTask struct {
+	purger *maintenance.Purger
+}
+
+// NewMaintenanceTask 创建定时维护任务处理器
+func NewMaintenanceTask(purger *maintenance.Purger) *MaintenanceTask {
+	return &MaintenanceTask{purger: purger}
+}
+
+// ProcessPurge 按保留策略清理任务记录
+func (t *MaintenanceTask) ProcessPurge(ctx context.Context, task *asynq.Task) error {
+	_, err := t.purger.Purge(false)
+	return err
+}
@@ -0,0 +1,120 @@
+package worker
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	"go.uber.org/zap"
+)
+
+// moveFile 移动文件：同分区直接 rename；跨分区（EXDEV）时先复制到目标目录的临时文件，
+// fsync 并校验大小与 SHA-256 后再 rename 到最终路径，最后才删除源文件。
+// 任何时刻目标路径上要么是旧文件，要么是完整的新文件。
+func moveFile(src, dst string, logger *zap.Logger) error {
+	err := os.Rename(src, dst)
+	if err == nil {
+		syncDirBestEffort(filepath.Dir(dst), logger)
+		return nil
+	}
+	if !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+
+	if err := copyFileAtomic(src, dst, logger); err != nil {
+		return err
+	}
+	if err := os.Remove(src); err != nil {
+		return fmt.Errorf("failed to remove source after copy: %w", err)
+	}
+	return nil
+}
+
+// copyFileAtomic 将 src 复制到 dst（经由同目录临时文件 + fsync + 校验 + rename）
+func copyFileAtomic(src, dst string, logger *zap.Logger) (err error) {
+	source, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	srcInfo, err := source.Stat()
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(dst), "."+filepath.Base(dst)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmpPath)
+		}
+	}()
+
+	srcHash := sha256.New()
+	written, err := io.Copy(tmp, io.TeeReader(source, srcHash))
+	if err != nil {
+		return fmt.Errorf("failed to copy data: %w", err)
+	}
+	if written != srcInfo.Size() {
+		return fmt.Errorf("short copy: wrote %d of %d bytes", written, srcInfo.Size())
+	}
+	if err = tmp.Chmod(srcInfo.Mode().Perm()); err != nil {
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		return fmt.Errorf("failed to fsync: %w", err)
+	}
+
+	// 回读临时文件校验内容，防止写入过程中的静默损坏。
+	if _, err = tmp.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	dstHash := sha256.New()
+	if _, err = io.Copy(dstHash, tmp); err != nil {
+		return fmt.Errorf("failed to verify copy: %w", err)
+	}
+	if !bytes.Equal(srcHash.Sum(nil), dstHash.Sum(nil)) {
+		err = fmt.Errorf("checksum mismatch after copying %s", src)
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+
+	if err = os.Rename(tmpPath, dst); err != nil {
+		return err
+	}
+	syncDirBestEffort(filepath.Dir(dst), logger)
+	return nil
+}
+
+// syncDirBestEffort rename 已成功、文件已在目标位置，目录 fsync 失败只记录日志，
+// 避免任务失败后重试时与已移动的文件冲突
+func syncDirBestEffort(dir string, logger *zap.Logger) {
+	if err := syncDir(dir); err != nil {
+		logger.Warn("failed to fsync directory after rename", zap.String("dir", dir), zap.Error(err))
+	}
+}
+
+// syncDir fsync 目录，确保 rename 持久化
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	if err := d.Sync(); err != nil {
+		return fmt.Errorf("failed to fsync dir %s: %w", dir, err)
+	}
+	return nil
+}
@@ -0,0 +1,227 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/azin/gdstudio-embed-service/internal/model"
+	"github.com/azin/gdstudio-embed-service/internal/service/gdstudio"
+	"github.com/azin/gdstudio-embed-service/internal/service/library"
+	"github.com/azin/gdstudio-embed-service/internal/tasks"
+	"github.com/azin/gdstudio-embed-service/internal/tracing"
+	"github.com/hibiken/asynq"
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/zap"
+)
+
+// UpgradeTask 音质升级处理器：为低码率的已完成任务重新解析更高码率，
+// 找到更好的版本后原地替换曲库文件并保留用户修改过的标签。
+type UpgradeTask struct {
+	*DownloadTask
+	client    *asynq.Client
+	inspector *asynq.Inspector
+}
+
+// NewUpgradeTaskHandler 创建音质升级处理器
+func NewUpgradeTaskHandler(downloadTask *DownloadTask, client *asynq.Client, inspector *asynq.Inspector) *UpgradeTask {
+	return &UpgradeTask{
+		DownloadTask: downloadTask,
+		client:       client,
+		inspector:    inspector,
+	}
+}
+
+// ProcessSweep 周期扫描：将低于期望音质的已完成任务加入升级队列。
+func (t *UpgradeTask) ProcessSweep(ctx context.Context, task *asynq.Task) error {
+	checkedBefore := time.Now().Add(-t.cfg.Upgrade.RecheckAfter)
+	jobs, err := t.repo.ListUpgradeCandidates(checkedBefore, t.cfg.Upgrade.BatchSize)
+	if err != nil {
+		return fmt.Errorf("failed to list upgrade candidates: %w", err)
+	}
+
+	enqueued := 0
+	for _, job := range jobs {
+		if !t.needsUpgrade(job) {
+			if err := t.repo.MarkUpgradeChecked(job.ID); err != nil {
+				t.logger.Warn("failed to mark upgrade checked", zap.String("job_id", job.ID), zap.Error(err))
+			}
+			continue
+		}
+
+		if err := tasks.EnqueueUpgrade(ctx, t.client, t.inspector, job.ID, ""); err != nil {
+			if errors.Is(err, asynq.ErrTaskIDConflict) {
+				continue
+			}
+			return fmt.Errorf("failed to enqueue upgrade task: %w", err)
+		}
+		enqueued++
+	}
+
+	t.logger.Info("upgrade sweep completed",
+		zap.Int("candidates", len(jobs)),
+		zap.Int("enqueued", enqueued))
+	return nil
+}
+
+// ProcessTask 处理单个任务的音质升级
+func (t *UpgradeTask) ProcessTask(ctx context.Context, task *asynq.Task) (err error) {
+	var payload tasks.UpgradePayload
+	if err := json.Unmarshal(task.Payload(), &payload); err != nil {
+		return fmt.Errorf("unmarshal payload failed: %w", err)
+	}
+
+	ctx, span := tracing.Start(tracing.Extract(ctx, payload.TraceContext), "job.upgrade",
+		attribute.String("job.id", payload.JobID),
+		attribute.String("request.id", payload.RequestID))
+	defer func() { tracing.End(span, err) }()
+
+	job, err := t.repo.FindByID(payload.JobID)
+	if err != nil {
+		return fmt.Errorf("failed to find job: %w", err)
+	}
+	if job.Status != model.JobStatusDone || job.FilePath == "" {
+		t.logger.Info("job not eligible for upgrade",
+			zap.String("job_id", job.ID),
+			zap.String("request_id", payload.RequestID),
+			zap.String("status", job.Status))
+		return nil
+	}
+
+	defer func() {
+		if err := t.repo.MarkUpgradeChecked(job.ID); err != nil {
+			t.logger.Warn("failed to mark upgrade checked", zap.String("job_id", job.ID), zap.Error(err))
+		}
+	}()
+
+	// 曲库文件被删除或移走时重试没有意义
+	if _, err := os.Stat(job.FilePath); err != nil {
+		return fmt.Errorf("library file not accessible: %v: %w", err, asynq.SkipRetry)
+	}
+
+	urlResult := t.resolveBetter(ctx, job)
+	if urlResult == nil {
+		t.logger.Info("no better version available",
+			zap.String("job_id", job.ID),
+			zap.Int("bitrate", job.Bitrate))
+		return nil
+	}
+
+	workDir := filepath.Join(t.cfg.Storage.WorkDir, job.ID+upgradeWorkSuffix)
+	if err := os.MkdirAll(workDir, 0755); err != nil {
+		return fmt.Errorf("failed to create work dir: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	newPath := filepath.Join(workDir, "audio."+urlResult.Extension)
+	if err := t.downloadFile(ctx, urlResult.URL, newPath, ""); err != nil {
+		return fmt.Errorf("failed to download upgrade: %w", err)
+	}
+
+	// 保留曲库文件中的标签（可能被用户编辑过），而不是重新从源站写入。
+	if err := t.tagger.CopyTags(job.FilePath, newPath); err != nil {
+		return err
+	}
+
+	oldPath := job.FilePath
+	targetPath, err := t.replaceLibraryFile(newPath, oldPath)
+	if err != nil {
+		return err
+	}
+
+	if err := t.library.IndexFile(targetPath, job.ID); err != nil {
+		t.logger.Warn("failed to index library file", zap.String("path", targetPath), zap.Error(err))
+	}
+
+	oldBitrate := job.Bitrate
+	job.FilePath = targetPath
+	job.Bitrate = urlResult.Bitrate
+	if info, err := os.Stat(targetPath); err == nil {
+		job.FileSize = info.Size()
+	}
+	job.Message = fmt.Sprintf("upgraded from %d kbps to %d kbps", oldBitrate, urlResult.Bitrate)
+	if err := t.repo.Update(job); err != nil {
+		return fmt.Errorf("failed to update job: %w", err)
+	}
+
+	t.logger.Info("library track upgraded",
+		zap.String("job_id", job.ID),
+		zap.String("request_id", payload.RequestID),
+		zap.String("path", targetPath),
+		zap.Int("old_bitrate", oldBitrate),
+		zap.Int("new_bitrate", urlResult.Bitrate))
+
+	if err := t.naviClient.StartScan(ctx); err != nil {
+		t.logger.Warn("failed to start scan", zap.Error(err))
+	}
+	return nil
+}
+
+// needsUpgrade 当前文件音质是否低于任务期望的音质
+func (t *UpgradeTask) needsUpgrade(job *model.Job) bool {
+	return currentQualityScore(job) < bitrateScore(t.getBitrateFromQuality(job.Quality))
+}
+
+// resolveBetter 从期望码率往下尝试，返回第一个音质高于当前文件的解析结果。
+func (t *UpgradeTask) resolveBetter(ctx context.Context, job *model.Job) *gdstudio.URLResult {
+	current := currentQualityScore(job)
+	for _, bitrate := range t.getBitrateCandidates(job.Quality) {
+		if bitrateScore(bitrate) <= current {
+			break
+		}
+
+		result, err := t.gdClient.ResolveURL(ctx, job.Source, job.TrackID, bitrate)
+		if err != nil {
+			t.logger.Debug("upgrade resolve failed",
+				zap.String("job_id", job.ID),
+				zap.Int("bitrate", bitrate),
+				zap.Error(err))
+			continue
+		}
+		if library.QualityScore(result.Extension, result.Bitrate) > current {
+			return result
+		}
+	}
+	return nil
+}
+
+// replaceLibraryFile 将新文件原子替换到曲库中（路径不变，仅扩展名可能变化），返回最终路径。
+func (t *UpgradeTask) replaceLibraryFile(newPath, oldPath string) (string, error) {
+	targetPath := strings.TrimSuffix(oldPath, filepath.Ext(oldPath)) + filepath.Ext(newPath)
+
+	// 经目标目录的临时文件复制校验后 rename 覆盖，保证曲库中始终是完整文件。
+	if err := copyFileAtomic(newPath, targetPath, t.logger); err != nil {
+		return "", fmt.Errorf("failed to replace library file: %w", err)
+	}
+	if err := t.perms.ApplyFile(targetPath); err != nil {
+		t.logger.Warn("failed to apply file permissions", zap.String("path", targetPath), zap.Error(err))
+	}
+
+	if targetPath != oldPath {
+		if err := os.Remove(oldPath); err != nil && !os.IsNotExist(err) {
+			t.logger.Warn("failed to remove replaced file", zap.String("path", oldPath), zap.Error(err))
+		}
+		if err := t.library.Remove(oldPath); err != nil {
+			t.logger.Warn("failed to remove replaced file from index", zap.Error(err))
+		}
+	}
+
+	return targetPath, nil
+}
+
+func currentQualityScore(job *model.Job) int {
+	return library.QualityScore(filepath.Ext(job.FilePath), job.Bitrate)
+}
+
+// bitrateScore 将请求码率换算为音质评分（999 表示无损）
+func bitrateScore(bitrate int) int {
+	if bitrate >= 999 {
+		return library.QualityScore("flac", bitrate)
+	}
+	return bitrate
+}
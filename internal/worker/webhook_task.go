@@ -0,0 +1,31 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/azin/gdstudio-embed-service/internal/service/webhook"
+	"github.com/hibiken/asynq"
+)
+
+// WebhookTask Webhook 投递任务处理器
+type WebhookTask struct {
+	deliverer *webhook.Deliverer
+}
+
+// NewWebhookTask 创建 Webhook 投递任务处理器
+func NewWebhookTask(deliverer *webhook.Deliverer) *WebhookTask {
+	return &WebhookTask{deliverer: deliverer}
+}
+
+// ProcessDelivery 投递一次 webhook，失败时返回错误交由 asynq 指数退避重试
+func (t *WebhookTask) ProcessDelivery(ctx context.Context, task *asynq.Task) error {
+	var payload webhook.DeliveryPayload
+	if err := json.Unmarshal(task.Payload(), &payload); err != nil {
+		return fmt.Errorf("unmarshal payload failed: %w: %w", err, asynq.SkipRetry)
+	}
+
+	retried, _ := asynq.GetRetryCount(ctx)
+	return t.deliverer.Deliver(ctx, &payload, retried+1)
+}